@@ -2,53 +2,116 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
-	"math/big"
+	"os"
 
-	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ydh2333/dapp_stu/pkg/block"
+	"github.com/ydh2333/dapp_stu/pkg/config"
+	"github.com/ydh2333/dapp_stu/pkg/indexer"
+)
+
+/*
+	区块查询与区块头检查：
+	  默认查询示例区块 5671744：go run ./01_search_block
+	  查询最新 / safe / finalized 区块：go run ./01_search_block -block finalized
+	  只取区块头（HeaderByNumber），以 JSON 输出：go run ./01_search_block -block latest -header-only -json
+*/
+
+var (
+	blockFlag  = flag.String("block", "5671744", "block number (decimal or 0x hex) or tag latest/safe/finalized/pending/earliest")
+	headerOnly = flag.Bool("header-only", false, "inspect with HeaderByNumber only (no transactions or withdrawals)")
+	jsonOut    = flag.Bool("json", false, "print only the block summary as JSON")
 )
 
 func main() {
-	client, err := ethclient.Dial("https://ethereum-sepolia-rpc.publicnode.com")
+	ctx := context.Background()
+	cfg, err := config.FromCommandLine()
 	if err != nil {
 		log.Fatal(err)
 	}
+	node, err := cfg.Dial(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// 配置了 --index / INDEX_DIR 时，区块、交易和回执先查本地索引（由 21_index_chain 写入），索引中没有再请求节点
+	idx, err := indexer.FromConfig(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if idx != nil {
+		defer idx.Close()
+	}
+	client := indexer.NewReader(idx, node)
 
-	blockNumber := big.NewInt(5671744)
+	blockNumber, err := block.ParseNumber(*blockFlag)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	header, err := client.HeaderByNumber(context.Background(), blockNumber)
+	// JSON 输出：只打印区块汇总，方便交给 jq 等工具处理
+	if *jsonOut {
+		summary, err := block.Inspect(ctx, client, blockNumber, !*headerOnly)
+		if err != nil {
+			log.Fatal(err)
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(summary); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	header, err := block.QueryHeader(ctx, client, blockNumber)
+	if err != nil {
+		log.Fatal(err)
+	}
 	// 区块号
 	fmt.Println(header.Number.Uint64()) // 5671744
 	// 区块时间戳
 	fmt.Println(header.Time) // 1712798400
-	// 区块难度
+	// 区块难度（合并之后由信标链出块，恒为 0）
 	fmt.Println(header.Difficulty.Uint64()) // 0
 	// 区块hash
 	fmt.Println(header.Hash().Hex()) // 0xae713dea1419ac72b928ebe6ba9915cd4fc1ef125a606f90f5e783c47cb1a4b5
 
-	if err != nil {
+	// 区块头的全部字段：基础费用与 gas 利用率（伦敦）、提款根（上海）、blob gas 与信标根（坎昆）、请求哈希（布拉格），
+	// 所在分叉还没有的字段不会列出
+	if err := block.Summarize(header).Write(os.Stdout); err != nil {
 		log.Fatal(err)
 	}
-	block, err := client.BlockByNumber(context.Background(), blockNumber)
+	if *headerOnly {
+		return
+	}
+	fmt.Println("-------------------------------------------------")
+
+	b, err := block.QueryBlock(ctx, client, blockNumber)
 	if err != nil {
 		log.Fatal(err)
 	}
 
 	// 区块号
-	fmt.Println(block.Number().Uint64()) // 5671744
+	fmt.Println(b.Number().Uint64()) // 5671744
 	// 区块时间戳
-	fmt.Println(block.Time()) // 1712798400
+	fmt.Println(b.Time()) // 1712798400
 	// 区块难度
-	fmt.Println(block.Difficulty().Uint64()) // 0
+	fmt.Println(b.Difficulty().Uint64()) // 0
 	// 区块hash
-	fmt.Println(block.Hash().Hex()) // 0xae713dea1419ac72b928ebe6ba9915cd4fc1ef125a606f90f5e783c47cb1a4b5
+	fmt.Println(b.Hash().Hex()) // 0xae713dea1419ac72b928ebe6ba9915cd4fc1ef125a606f90f5e783c47cb1a4b5
 	// 交易数量
-	fmt.Println(len(block.Transactions())) // 70
-	count, err := client.TransactionCount(context.Background(), block.Hash())
+	fmt.Println(len(b.Transactions())) // 70
+	count, err := block.TransactionCount(ctx, client, b.Hash())
 	if err != nil {
 		log.Fatal(err)
 	}
 
 	fmt.Println(count) // 70
+
+	// 完整区块还可以按交易类型统计交易数量，并列出本区块处理的提款
+	if err := block.SummarizeBlock(b).Write(os.Stdout); err != nil {
+		log.Fatal(err)
+	}
 }
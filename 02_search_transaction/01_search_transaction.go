@@ -2,75 +2,106 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"log"
 	"math/big"
+	"os"
 
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ydh2333/dapp_stu/pkg/abireg"
+	"github.com/ydh2333/dapp_stu/pkg/block"
+	"github.com/ydh2333/dapp_stu/pkg/config"
+	"github.com/ydh2333/dapp_stu/pkg/indexer"
+	"github.com/ydh2333/dapp_stu/pkg/receipt"
+	"github.com/ydh2333/dapp_stu/pkg/transaction"
 )
 
+var inspectTx = flag.String("tx", "", "also inspect this transaction hash (any type)")
+
 func main() {
-	client, err := ethclient.Dial("https://sepolia.infura.io/v3/48838061f7a544dfb74776ac7a0680bb")
+	ctx := context.Background()
+	cfg, err := config.FromCommandLine()
 	if err != nil {
 		log.Fatal(err)
 	}
-	// 1、连接以太坊节点，获取链 ID（用于交易签名验证）
-	chainID, err := client.ChainID(context.Background())
-	fmt.Println("chainID:", chainID)
+	node, err := cfg.Dial(ctx)
 	if err != nil {
 		log.Fatal(err)
 	}
+	// 配置了 --index / INDEX_DIR 时，区块、交易和回执先查本地索引（由 21_index_chain 写入），索引中没有再请求节点
+	idx, err := indexer.FromConfig(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if idx != nil {
+		defer idx.Close()
+	}
+	client := indexer.NewReader(idx, node)
+	// 1、从网络配置获取链 ID（用于交易签名验证）
+	chainID := cfg.ChainIDBig()
+	fmt.Println("chainID:", chainID)
 	// 2、指定区块号（5671744），通过 BlockByNumber 获取该区块的完整数据
 	blockNumber := big.NewInt(5671744)
-	block, err := client.BlockByNumber(context.Background(), blockNumber)
+	b, err := block.QueryBlock(ctx, client, blockNumber)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// 调用数据与回执日志解码用的 ABI 注册表（Store、MyERC20）
+	reg, err := abireg.Default()
 	if err != nil {
 		log.Fatal(err)
 	}
 	// 3、遍历区块内的交易（仅取第一条，break 终止循环），打印交易核心字段
-	for _, tx := range block.Transactions() {
+	for _, tx := range b.Transactions() {
 		// 交易hash
 		fmt.Println(tx.Hash().Hex()) // 0x20294a03e8766e9aeab58327fc4112756017c6c28f6f99c7722f4a29075601c5
 		// 转账金额
 		fmt.Println(tx.Value().String()) // 100000000000000000
 		// gas限制
 		fmt.Println(tx.Gas()) // 21000
-		// gas价格
-		fmt.Println(tx.GasPrice().Uint64()) // 100000000000
+		// 交易类型：0 传统、1 EIP-2930、2 EIP-1559、3 blob、4 set-code
+		// 注意 tx.GasPrice() 对 EIP-1559 及之后的类型返回的是 maxFeePerGas，实际成交价格要看回执
+		fmt.Println(transaction.TypeName(tx.Type())) // legacy
 		// 交易随机数
 		fmt.Println(tx.Nonce()) // 245132
-		// 交易数据，空表示普通转账，非空为合约交互数据
-		fmt.Println(tx.Data()) // []
-		// 交易接收地址
-		fmt.Println(tx.To().Hex()) // 0x8F9aFd209339088Ced7Bc0f57Fe08566ADda3587
-		// 交易发送地址，通过 types.Sender 结合链 ID 解析签名获取
-		if sender, err := types.Sender(types.NewEIP155Signer(chainID), tx); err == nil {
-			fmt.Println("sender", sender.Hex()) // 0x2CdA41645F2dBffB852a605E92B185501801FC28
-		} else {
-			log.Fatal(err)
+		// 交易数据，空表示普通转账，非空为合约交互数据：按 4 字节选择器匹配仓库 ABI 与离线签名库，
+		// 解码为方法名和参数（如 transfer(to=0x..., value=...)）；合约创建交易则拆分字节码与构造函数参数
+		fmt.Println(reg.DecodeTx(tx)) // (no calldata)
+		// 按交易类型解析详情：发送方用支持全部类型的最新签名器恢复（0x2CdA41645F2dBffB852a605E92B185501801FC28），
+		// 并列出该类型特有的字段（gasPrice / 小费与费用上限 / 访问列表 / blob 哈希 / 授权列表）
+		details, err := transaction.Describe(tx, chainID)
+		if err != nil {
+			log.Println(err)
+			continue
 		}
-		// 交易收据（签名），包含交易状态（receipt.Status，1 表示成功）、日志（receipt.Logs）
-		receipt, err := client.TransactionReceipt(context.Background(), tx.Hash())
+		// 交易收据（签名），包含交易状态（receipt.Status，1 表示成功）、实际 gas 价格、日志（receipt.Logs）
+		r, err := receipt.QueryReceipt(ctx, client, tx.Hash())
 		if err != nil {
 			log.Fatal(err)
 		}
-
-		fmt.Println(receipt.Status) // 1
-		fmt.Println(receipt.Logs)   // []
+		// 由回执计算实际支付的手续费：effectiveGasPrice × gasUsed，区块基础费用部分被销毁，其余为小费
+		details.AttachReceipt(r, b.BaseFee())
+		if err := details.Write(os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		// 日志按 topic0 匹配仓库自带 ABI 解码为具名事件，未知事件输出原始十六进制（普通转账没有日志）
+		if err := receipt.WriteLogs(os.Stdout, receipt.DecodeLogs(reg, r)); err != nil {
+			log.Fatal(err)
+		}
 		break
 	}
 	fmt.Println("-------------------------------------------------")
 	// 1、指定区块哈希，通过 TransactionCount 获取该区块内的交易总数
 	blockHash := common.HexToHash("0xae713dea1419ac72b928ebe6ba9915cd4fc1ef125a606f90f5e783c47cb1a4b5")
-	count, err := client.TransactionCount(context.Background(), blockHash)
+	count, err := block.TransactionCount(ctx, client, blockHash)
 	if err != nil {
 		log.Fatal(err)
 	}
 	fmt.Println("count:", count)
 	// 2、按交易索引遍历（仅取第 1 条），通过 TransactionInBlock 获取指定索引的交易，打印交易哈希
 	for idx := uint(0); idx < count; idx++ {
-		tx, err := client.TransactionInBlock(context.Background(), blockHash, idx)
+		tx, err := transaction.TransactionInBlock(ctx, client, blockHash, idx)
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -81,12 +112,30 @@ func main() {
 	fmt.Println("-------------------------------------------------")
 	// 1、指定交易哈希，通过 TransactionByHash 查询交易
 	txHash := common.HexToHash("0x20294a03e8766e9aeab58327fc4112756017c6c28f6f99c7722f4a29075601c5")
-	tx, isPending, err := client.TransactionByHash(context.Background(), txHash)
+	tx, isPending, err := transaction.QueryTransaction(ctx, client, txHash)
 	if err != nil {
 		log.Fatal(err)
 	}
 	// isPending：标识交易是否处于 “待确认” 状态（false，表示已上链）
-	fmt.Println(isPending)
+	fmt.Println(isPending) // false
 	// 交易哈希（验证查询结果的准确性）
-	fmt.Println(tx.Hash().Hex()) // 0x20294a03e8766e9aeab58327fc4112756017c6c28f6f99c7722f4a29075601c5.Println(isPending)       // false
+	fmt.Println(tx.Hash().Hex()) // 0x20294a03e8766e9aeab58327fc4112756017c6c28f6f99c7722f4a29075601c5
+
+	// 查看任意一笔交易（如 EIP-1559、blob 或 set-code 交易）的完整详情：go run ./02_search_transaction -tx 0x...
+	if *inspectTx != "" {
+		fmt.Println("-------------------------------------------------")
+		details, err := transaction.Inspect(ctx, client, common.HexToHash(*inspectTx), chainID)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := details.Write(os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println("call:", reg.DecodeTx(details.Tx))
+		if details.Receipt != nil {
+			if err := receipt.WriteLogs(os.Stdout, receipt.DecodeLogs(reg, details.Receipt)); err != nil {
+				log.Fatal(err)
+			}
+		}
+	}
 }
@@ -2,54 +2,87 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"log"
 	"math/big"
+	"os"
 
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ydh2333/dapp_stu/pkg/abireg"
+	"github.com/ydh2333/dapp_stu/pkg/config"
+	"github.com/ydh2333/dapp_stu/pkg/indexer"
+	"github.com/ydh2333/dapp_stu/pkg/receipt"
 )
 
+var txFlag = flag.String("tx", "0x20294a03e8766e9aeab58327fc4112756017c6c28f6f99c7722f4a29075601c5", "transaction whose receipt logs are decoded")
+
 func main() {
+	ctx := context.Background()
 
-	// 连接以太坊 Sepolia 测试网节点
-	client, err := ethclient.Dial("https://ethereum-sepolia-rpc.publicnode.com")
+	cfg, err := config.FromCommandLine()
 	if err != nil {
 		log.Fatal(err)
 	}
+	// 连接配置中的以太坊节点（默认 Sepolia 测试网）
+	node, err := cfg.Dial(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// 配置了 --index / INDEX_DIR 时，区块、交易和回执先查本地索引（由 21_index_chain 写入），索引中没有再请求节点
+	idx, err := indexer.FromConfig(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if idx != nil {
+		defer idx.Close()
+	}
+	client := indexer.NewReader(idx, node)
 
 	blockNumber := big.NewInt(5671744)
 	blockHash := common.HexToHash("0xae713dea1419ac72b928ebe6ba9915cd4fc1ef125a606f90f5e783c47cb1a4b5")
-	// 通过区块号获取区块的交易回执
-	receiptByHash, err := client.BlockReceipts(context.Background(), rpc.BlockNumberOrHashWithHash(blockHash, false))
+	// 通过区块哈希获取区块的交易回执
+	receiptByHash, err := receipt.QueryBlockReceipts(ctx, client, rpc.BlockNumberOrHashWithHash(blockHash, false))
 	if err != nil {
 		log.Fatal(err)
 	}
-	// 通过区块哈希获取区块的交易回执
-	receiptsByNum, err := client.BlockReceipts(context.Background(), rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(blockNumber.Int64())))
+	// 通过区块号获取区块的交易回执
+	receiptsByNum, err := receipt.QueryBlockReceipts(ctx, client, rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(blockNumber.Int64())))
 	if err != nil {
 		log.Fatal(err)
 	}
 	fmt.Println("receiptByHash[0] == receiptsByNum[0] ", receiptByHash[0] == receiptsByNum[0]) // true
 
-	for _, receipt := range receiptByHash {
-		fmt.Println(receipt.Status)                // 1
-		fmt.Println(receipt.Logs)                  // []
-		fmt.Println(receipt.TxHash.Hex())          // 0x20294a03e8766e9aeab58327fc4112756017c6c28f6f99c7722f4a29075601c5
-		fmt.Println(receipt.TransactionIndex)      // 0
-		fmt.Println(receipt.ContractAddress.Hex()) // 0x0000000000000000000000000000000000000000
+	// 日志解码注册表：按 topic0 匹配仓库自带 ABI 中的事件（Store.ItemSet、ERC20 Transfer/Approval/OwnershipTransferred）
+	reg, err := abireg.Default()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, r := range receiptByHash {
+		fmt.Println(r.Status) // 1
+		// 解码后的日志（该交易是普通转账，没有日志）
+		if err := receipt.WriteLogs(os.Stdout, receipt.DecodeLogs(reg, r)); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(r.TxHash.Hex())          // 0x20294a03e8766e9aeab58327fc4112756017c6c28f6f99c7722f4a29075601c5
+		fmt.Println(r.TransactionIndex)      // 0
+		fmt.Println(r.ContractAddress.Hex()) // 0x0000000000000000000000000000000000000000
 		break
 	}
 
-	txHash := common.HexToHash("0x20294a03e8766e9aeab58327fc4112756017c6c28f6f99c7722f4a29075601c5")
-	receipt, err := client.TransactionReceipt(context.Background(), txHash)
+	txHash := common.HexToHash(*txFlag)
+	r, err := receipt.QueryReceipt(ctx, client, txHash)
 	if err != nil {
 		log.Fatal(err)
 	}
-	fmt.Println(receipt.Status)                // 1
-	fmt.Println(receipt.Logs)                  // []
-	fmt.Println(receipt.TxHash.Hex())          // 0x20294a03e8766e9aeab58327fc4112756017c6c28f6f99c7722f4a29075601c5
-	fmt.Println(receipt.TransactionIndex)      // 0
-	fmt.Println(receipt.ContractAddress.Hex()) // 0x0000000000000000000000000000000000000000
+	fmt.Println(r.Status) // 1
+	// 每条日志按 topic0 解码为事件名和具名参数，indexed 参数从 topics 还原，未知事件输出原始十六进制
+	if err := receipt.WriteLogs(os.Stdout, receipt.DecodeLogs(reg, r)); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(r.TxHash.Hex())          // 0x20294a03e8766e9aeab58327fc4112756017c6c28f6f99c7722f4a29075601c5
+	fmt.Println(r.TransactionIndex)      // 0
+	fmt.Println(r.ContractAddress.Hex()) // 0x0000000000000000000000000000000000000000
 }
@@ -7,6 +7,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ydh2333/dapp_stu/pkg/keys"
 	"golang.org/x/crypto/sha3"
 )
 
@@ -51,4 +52,7 @@ func main() {
 	hash.Write(publicKeyBytes[1:]) // 写入去掉 04 前缀的公钥字节数组（64 字节）
 	fmt.Println("full:", hexutil.Encode(hash.Sum(nil)[:]))
 	fmt.Println(hexutil.Encode(hash.Sum(nil)[12:])) // 原长32位，截去12位，保留后20位
+	fmt.Println("--------------------------------------------------------")
+	// 以上两种方式已封装在 pkg/keys 中（keys.Address / keys.KeccakAddress），HD 钱包等功能复用同一套推导
+	fmt.Println("same address:", keys.KeccakAddress(publicKeyECDSA) == keys.Address(publicKeyECDSA))
 }
@@ -2,72 +2,64 @@ package main
 
 import (
 	"context"
-	"crypto/ecdsa"
+	"flag"
 	"fmt"
 	"log"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ydh2333/dapp_stu/pkg/config"
+	"github.com/ydh2333/dapp_stu/pkg/guard"
+	"github.com/ydh2333/dapp_stu/pkg/nonce"
+	"github.com/ydh2333/dapp_stu/pkg/signer"
+	"github.com/ydh2333/dapp_stu/pkg/transfer"
 )
 
+var count = flag.Int("n", 1, "number of transfers to send back to back")
+
 func main() {
-	// 步骤1：连接以太坊节点（Infura提供的Sepolia测试网节点）
-	client, err := ethclient.Dial("https://sepolia.infura.io/v3/48838061f7a544dfb74776ac7a0680bb")
-	if err != nil {
-		log.Fatal(err)
-	}
-	// 步骤2：加载发送方私钥（需替换为实际有效私钥）
-	privateKey, err := crypto.HexToECDSA("d71a701e75b49c9a337ac20bacf15ccf62b92b86fee94cb6f5bc0240453f4f64")
+	ctx := context.Background()
+	cfg, err := config.FromCommandLine()
 	if err != nil {
 		log.Fatal(err)
 	}
-
-	// 步骤3：从私钥推导发送方地址
-	publicKey := privateKey.Public()
-	publicKeyECDSA, ok := publicKey.(*ecdsa.PublicKey)
-	if !ok {
-		log.Fatal("cannot assert type: publicKey is not of type *ecdsa.PublicKey")
+	if *count < 1 {
+		log.Fatal("-n must be at least 1")
 	}
-	fromAddress := crypto.PubkeyToAddress(*publicKeyECDSA)
-	// 步骤4：获取发送方账户的下一个nonce（防止交易重放）
-	nonce, err := client.PendingNonceAt(context.Background(), fromAddress)
+	// 步骤1：连接配置中的以太坊节点（默认 Sepolia 测试网）
+	client, err := cfg.Dial(ctx)
 	if err != nil {
 		log.Fatal(err)
 	}
-	// 步骤5：定义交易参数
-	value := big.NewInt(1000000000000000000) // 转账金额：1 ETH（以wei为单位，1 ETH = 10^18 wei）
-	gasLimit := uint64(21000)                // 燃气上限：普通ETH转账固定21000 gas
-	// 根据'x'个先前块来获得平均燃气价格，燃气价格总是根据市场需求和用户愿意支付的价格而波动
-	// 因此对燃气价格进行硬编码并不理想（gasPrice := big.NewInt(30000000000)）
-	// 建议燃气价格（动态获取，适配市场）
-	gasPrice, err := client.SuggestGasPrice(context.Background())
+	// 签名守卫：确认节点的 eth_chainId 与配置的链 ID 一致，主网需显式开启 --allow-mainnet
+	g, err := guard.FromConfig(ctx, client, cfg)
 	if err != nil {
 		log.Fatal(err)
 	}
-	toAddress := common.HexToAddress("0xF9B6FF30D67e802690C94edD7B4CFFCfdF6A4deF") // 接收方地址
-	var data []byte                                                                // 转账数据：普通ETH转账无需附加数据，设为空
-
-	// 步骤6：构造未签名交易
-	tx := types.NewTransaction(nonce, toAddress, value, gasLimit, gasPrice, data)
-
-	// 步骤7：获取链ID并签名交易（EIP155标准，防止跨链重放）
-	chainID, err := client.NetworkID(context.Background())
-	if err != nil {
-		log.Fatal(err)
-	}
-	signedTx, err := types.SignTx(tx, types.NewEIP155Signer(chainID), privateKey)
+	// nonce 管理器：本地分配 nonce 并持久化到 --nonce-file，连续或并发发送也不会冲突
+	backend := nonce.New(client, cfg.NonceFile).Wrap(client)
+	// 步骤2：加载发送方账户（keystore 文件 + 口令解锁；明文私钥需显式开启 --insecure-private-key）
+	account, err := signer.FromConfig(cfg)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	// 步骤8：发送签名后的交易到区块链
-	err = client.SendTransaction(context.Background(), signedTx)
-	if err != nil {
-		log.Fatal(err)
+	// 步骤3：定义交易参数
+	value := big.NewInt(1000000000000000000)                                       // 转账金额：1 ETH（以wei为单位，1 ETH = 10^18 wei）
+	toAddress := common.HexToAddress("0xF9B6FF30D67e802690C94edD7B4CFFCfdF6A4deF") // 接收方地址
+
+	// 步骤4：构造、签名并发送交易，-n 大于 1 时不等待确认、连续发送多笔
+	// SendETH 通过 txbuilder 依次完成：获取nonce → 获取建议燃气价格 → 构造传统交易 → 按当前分叉选择签名器签名 → 发送
+	for i := 0; i < *count; i++ {
+		// nonce 被其他进程抢先使用时，管理器已重新同步，重试即可拿到新的 nonce
+		signedTx, err := nonce.Retry(ctx, 3, func() (*types.Transaction, error) {
+			return transfer.SendETH(ctx, backend, g, account, toAddress, value)
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		// 步骤5：输出交易哈希（可在区块链浏览器查询交易状态）
+		fmt.Printf("tx sent: %s (nonce %d)\n", signedTx.Hash().Hex(), signedTx.Nonce())
 	}
-	// 步骤9：输出交易哈希（可在区块链浏览器查询交易状态）
-	fmt.Printf("tx sent: %s\n", signedTx.Hash().Hex())
 }
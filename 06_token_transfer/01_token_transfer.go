@@ -2,45 +2,41 @@ package main
 
 import (
 	"context"
-	"crypto/ecdsa"
 	"fmt"
 	"log"
 	"math/big"
 
 	"golang.org/x/crypto/sha3"
 
-	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
-	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ydh2333/dapp_stu/pkg/config"
+	"github.com/ydh2333/dapp_stu/pkg/guard"
+	"github.com/ydh2333/dapp_stu/pkg/nonce"
+	"github.com/ydh2333/dapp_stu/pkg/signer"
+	"github.com/ydh2333/dapp_stu/pkg/transfer"
 )
 
 func main1() {
-	client, err := ethclient.Dial("https://ethereum-sepolia-rpc.publicnode.com")
+	ctx := context.Background()
+	cfg, err := config.FromCommandLine()
 	if err != nil {
 		log.Fatal(err)
 	}
-
-	privateKey, err := crypto.HexToECDSA("d71a701e75b49c9a337ac20bacf15ccf62b92b86fee94cb6f5bc0240453f4f64")
+	client, err := cfg.Dial(ctx)
 	if err != nil {
 		log.Fatal(err)
 	}
-
-	publicKey := privateKey.Public()
-	publicKeyECDSA, ok := publicKey.(*ecdsa.PublicKey)
-	if !ok {
-		log.Fatal("cannot assert type: publicKey is not of type *ecdsa.PublicKey")
-	}
-
-	fromAddress := crypto.PubkeyToAddress(*publicKeyECDSA)
-	nonce, err := client.PendingNonceAt(context.Background(), fromAddress)
+	// 签名守卫：确认节点的 eth_chainId 与配置的链 ID 一致，主网需显式开启 --allow-mainnet
+	g, err := guard.FromConfig(ctx, client, cfg)
 	if err != nil {
 		log.Fatal(err)
 	}
-	value := big.NewInt(0) // in wei (0 eth)
-	gasPrice, err := client.SuggestGasPrice(context.Background())
+	// nonce 管理器：本地分配 nonce 并持久化到 --nonce-file，连续或并发发送也不会冲突
+	backend := nonce.New(client, cfg.NonceFile).Wrap(client)
+
+	// 加载签名账户（keystore 文件 + 口令解锁）
+	account, err := signer.FromConfig(cfg)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -48,8 +44,12 @@ func main1() {
 	// 接收方地址
 	toAddress := common.HexToAddress("0xF9B6FF30D67e802690C94edD7B4CFFCfdF6A4deF")
 	// erc20代币合约地址
-	tokenAddress := common.HexToAddress("0xf8112b83f4ABA089Acf7E8fb77c480D6778d029d")
+	tokenAddress, err := cfg.Contract("token")
+	if err != nil {
+		log.Fatal(err)
+	}
 
+	// 手动演示 transfer 调用数据的组成（transfer.EncodeTransfer 用 ABI 自动完成同样的编码）
 	// 1. 计算 transfer 函数的方法 ID（前4字节 Keccak256 哈希）
 	transferFnSignature := []byte("transfer(address,uint256)")
 	hash := sha3.NewLegacyKeccak256()
@@ -63,38 +63,9 @@ func main1() {
 	amount.SetString("10000000000000000000", 10) // 10 tokens
 	paddedAmount := common.LeftPadBytes(amount.Bytes(), 32)
 	fmt.Println(hexutil.Encode(paddedAmount)) // 0x00000000000000000000000000000000000000000000003635c9adc5dea00000
-	var data []byte
-	// 3. 拼接最终的交易数据
-	data = append(data, methodID...)
-	data = append(data, paddedAddress...)
-	data = append(data, paddedAmount...)
-
-	// 估算 Gas 限额
-	gasLimit, err := client.EstimateGas(context.Background(), ethereum.CallMsg{
-		From: fromAddress,
-		To:   &tokenAddress,
-		Data: data,
-	})
-	if err != nil {
-		fmt.Println(err)
-		log.Fatal(err)
-	}
-	fmt.Println(gasLimit) // 23256
-
-	//构建未签名交易
-	tx := types.NewTransaction(nonce, tokenAddress, value, gasLimit, gasPrice, data)
-	// 获取链 ID（Sepolia 测试网）
-	chainID, err := client.NetworkID(context.Background())
-	if err != nil {
-		log.Fatal(err)
-	}
-	// 用私钥签名交易（EIP155 签名规则，防止跨链重放）
-	signedTx, err := types.SignTx(tx, types.NewEIP155Signer(chainID), privateKey)
-	if err != nil {
-		log.Fatal(err)
-	}
 
-	err = client.SendTransaction(context.Background(), signedTx)
+	// 3. 由 txbuilder 估算 Gas、构建未签名交易、签名并发送
+	signedTx, err := transfer.SendToken(ctx, backend, g, account, tokenAddress, toAddress, amount)
 	if err != nil {
 		log.Fatal(err)
 	}
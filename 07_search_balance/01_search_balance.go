@@ -4,15 +4,20 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"math"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ydh2333/dapp_stu/pkg/balance"
+	"github.com/ydh2333/dapp_stu/pkg/config"
 )
 
 func main() {
-	client, err := ethclient.Dial("https://ethereum-sepolia-rpc.publicnode.com")
+	ctx := context.Background()
+	cfg, err := config.FromCommandLine()
+	if err != nil {
+		log.Fatal(err)
+	}
+	client, err := cfg.Dial(ctx)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -20,26 +25,26 @@ func main() {
 	account := common.HexToAddress("0x51ccc58AE0a621b78196CcE2e01920dd6E5be38b")
 
 	// 1. 查询最新区块的账户余额
-	balance, err := client.BalanceAt(context.Background(), account, nil) // nil：表示查询最新区块的余额
+	bal, err := balance.QueryBalance(ctx, client, account, nil) // nil：表示查询最新区块的余额
 	if err != nil {
 		log.Fatal(err)
 	}
-	fmt.Println(balance)
+	fmt.Println(bal)
 	// ----------------------------------------------------------------------------
 	// 2. 查询指定历史区块的余额
 	blockNumber := big.NewInt(9996975)
-	balanceAt, err := client.BalanceAt(context.Background(), account, blockNumber)
+	balanceAt, err := balance.QueryBalance(ctx, client, account, blockNumber)
 	if err != nil {
 		log.Fatal(err)
 	}
 	fmt.Println(balanceAt)
 	// 将 wei 转换为 ETH 单位
-	fbalance := new(big.Float)
-	fbalance.SetString(balanceAt.String())
-	ethValue := new(big.Float).Quo(fbalance, big.NewFloat(math.Pow10(18)))
-	fmt.Println(ethValue)
+	fmt.Println(balance.WeiToEther(balanceAt))
 
 	// 3. 查询待处理交易的余额（Pending 余额）
-	pendingBalance, err := client.PendingBalanceAt(context.Background(), account)
+	pendingBalance, err := balance.QueryPendingBalance(ctx, client, account)
+	if err != nil {
+		log.Fatal(err)
+	}
 	fmt.Println(pendingBalance)
 }
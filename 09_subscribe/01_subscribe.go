@@ -2,48 +2,74 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"log"
 
-	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ydh2333/dapp_stu/pkg/block"
+	"github.com/ydh2333/dapp_stu/pkg/config"
+	"github.com/ydh2333/dapp_stu/pkg/headtracker"
+	"github.com/ydh2333/dapp_stu/pkg/stream"
+)
+
+var (
+	window = flag.Int("window", headtracker.DefaultWindow, "number of recent canonical headers kept for reorg detection")
+	start  = flag.Uint64("start", 0, "replay blocks from this height before following the head (0 = start at the head)")
 )
 
 func main() {
-	// 连接以太坊 Sepolia 测试网的 WebSocket 节点（WSS 协议）
-	client, err := ethclient.Dial("wss://ethereum-sepolia-rpc.publicnode.com")
+	ctx := context.Background()
+	cfg, err := config.FromCommandLine()
 	if err != nil {
 		log.Fatal(err)
 	}
-
-	// 创建一个新的通道，用于接收最新的区块头
-	headers := make(chan *types.Header)
-	// SubscribeNewHead 方法，接收刚创建的区块头通道，该方法将返回一个订阅对象
-	sub, err := client.SubscribeNewHead(context.Background(), headers)
+	// 查询区块详情用的 HTTP 连接
+	client, err := cfg.Dial(ctx)
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	// 区块流：连接配置中的 WebSocket 节点（WSS 协议，订阅需要长连接）并用 SubscribeNewHead 订阅新区块头，
+	// 断线或长时间没有新区块时自动重连并补齐错过的区块；只有 HTTP 地址时退回 eth_getFilterChanges 轮询。
+	// 内部由 headtracker 校验父哈希链接，已回报过的区块被孤立时先发出 Removed 事件
+	s := stream.New(cfg.DialWS, stream.Options{
+		Start:     *start,
+		Window:    *window,
+		OnConnect: func(m stream.Mode) { log.Println("connected via", m) },
+		OnError:   func(err error) { log.Println("connection lost, reconnecting:", err) },
+	})
+	events := make(chan *stream.Event)
+	errc := make(chan error, 1)
+	go func() { errc <- s.Run(ctx, events) }()
+
 	for {
 		select {
-		case err := <-sub.Err():
+		case err := <-errc:
 			log.Fatal(err)
-		case header := <-headers:
+		case ev := <-events:
+			header := ev.Header
+			if ev.Removed {
+				fmt.Printf("!!! reorg, dropped: %d %s\n", header.Number.Uint64(), header.Hash().Hex())
+				fmt.Println("-----------------------------------------------------------")
+				continue
+			}
 			fmt.Println("header:", header.Hash().Hex())
 			fmt.Println("header:", header.Number.Uint64())
 			fmt.Println("header:", header.Time)
 			fmt.Println("header:", header.Nonce)
 
-			block, err := client.BlockByHash(context.Background(), header.Hash())
+			b, err := block.QueryBlockByHash(ctx, client, header.Hash())
 			if err != nil {
-				log.Fatal(err)
+				// 查询失败（如节点短暂不可用）只跳过区块详情，不中断订阅
+				log.Println(err)
+				continue
 			}
 
-			fmt.Println("block:", block.Hash().Hex())
-			fmt.Println("block:", block.Number().Uint64())
-			fmt.Println("block:", block.Time())
-			fmt.Println("block:", block.Nonce())
-			fmt.Println("block:", len(block.Transactions()))
+			fmt.Println("block:", b.Hash().Hex())
+			fmt.Println("block:", b.Number().Uint64())
+			fmt.Println("block:", b.Time())
+			fmt.Println("block:", b.Nonce())
+			fmt.Println("block:", len(b.Transactions()))
 
 			fmt.Println("-----------------------------------------------------------")
 		}
@@ -2,17 +2,14 @@ package main
 
 import (
 	"context"
-	"crypto/ecdsa"
 	"fmt"
 	"log"
-	"math/big"
-	"os"
 
-	"github.com/ethereum/go-ethereum/accounts/abi/bind"
-	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/ethclient"
-	"github.com/joho/godotenv"
-	store "github.com/ydh2333/dapp_stu/10_contract_deploy/store"
+	"github.com/ydh2333/dapp_stu/pkg/config"
+	"github.com/ydh2333/dapp_stu/pkg/deploy"
+	"github.com/ydh2333/dapp_stu/pkg/feeoracle"
+	"github.com/ydh2333/dapp_stu/pkg/guard"
+	"github.com/ydh2333/dapp_stu/pkg/signer"
 )
 
 /*
@@ -21,63 +18,39 @@ import (
 
 func main1() {
 
-	err := godotenv.Load()
-	if err != nil {
-		log.Fatal("Error loading .env file")
-	}
-
-	privateKeyStr := os.Getenv("PRIVATE_KEY")
-	if privateKeyStr == "" {
-		log.Fatal("PRIVATE_KEY is not set in .env file")
-	}
-
-	client, err := ethclient.Dial("https://ethereum-sepolia-rpc.publicnode.com")
+	cfg, err := config.FromCommandLine()
 	if err != nil {
 		log.Fatal(err)
 	}
-
-	// privateKey, err := crypto.GenerateKey()
-	// privateKeyBytes := crypto.FromECDSA(privateKey)
-	// privateKeyHex := hex.EncodeToString(privateKeyBytes)
-	// fmt.Println("Private Key:", privateKeyHex)
-	privateKey, err := crypto.HexToECDSA(privateKeyStr)
+	// EIP-1559 手续费策略（--fee-strategy / FEE_STRATEGY，默认 standard），由 feeoracle 按 eth_feeHistory 估算
+	strategy, err := feeoracle.ParseStrategy(cfg.FeeStrategy)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	publicKey := privateKey.Public()
-	publicKeyECDSA, ok := publicKey.(*ecdsa.PublicKey)
-	if !ok {
-		log.Fatal("cannot assert type: publicKey is not of type *ecdsa.PublicKey")
-	}
-
-	fromAddress := crypto.PubkeyToAddress(*publicKeyECDSA)
-	nonce, err := client.PendingNonceAt(context.Background(), fromAddress)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	gasPrice, err := client.SuggestGasPrice(context.Background())
+	ctx := context.Background()
+	client, err := cfg.Dial(ctx)
 	if err != nil {
 		log.Fatal(err)
 	}
-
-	chainId, err := client.NetworkID(context.Background())
+	// 签名守卫：确认节点的 eth_chainId 与配置的链 ID 一致，主网需显式开启 --allow-mainnet
+	g, err := guard.FromConfig(ctx, client, cfg)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	auth, err := bind.NewKeyedTransactorWithChainID(privateKey, chainId)
+	// privateKey, err := crypto.GenerateKey()
+	// privateKeyBytes := crypto.FromECDSA(privateKey)
+	// privateKeyHex := hex.EncodeToString(privateKeyBytes)
+	// fmt.Println("Private Key:", privateKeyHex)
+	account, err := signer.FromConfig(cfg)
 	if err != nil {
 		log.Fatal(err)
 	}
-	auth.Nonce = big.NewInt(int64(nonce))
-	auth.Value = big.NewInt(0)     // in wei
-	auth.GasLimit = uint64(500000) // in units
-	auth.GasPrice = gasPrice
 
+	// DeployStore 内部用绑定中的 ABI 编码构造参数，经 txbuilder 发送部署交易（EIP-1559 手续费、gas上限 500000）
 	input := "1.0"
-	address, tx, instance, err := store.DeployStore(auth, client, input)
+	address, tx, instance, err := deploy.DeployStore(ctx, client, g, account, input, strategy)
 	if err != nil {
 		log.Fatal(err)
 	}
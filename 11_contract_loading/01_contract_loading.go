@@ -1,23 +1,29 @@
 package main
 
 import (
+	"context"
 	"log"
 
-	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/ethclient"
-	"github.com/ydh2333/dapp_stu/10_contract_deploy/store"
-)
-
-const (
-	contractAddr = "0x9F49FF297E88AD77120f0e261a76fa3A835c24Aa"
+	"github.com/ydh2333/dapp_stu/pkg/config"
+	"github.com/ydh2333/dapp_stu/pkg/contract"
 )
 
 func main() {
-	client, err := ethclient.Dial("https://ethereum-sepolia-rpc.publicnode.com")
+	ctx := context.Background()
+	cfg, err := config.FromCommandLine()
+	if err != nil {
+		log.Fatal(err)
+	}
+	client, err := cfg.Dial(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// Store 合约地址来自网络配置（contracts.store）
+	contractAddr, err := cfg.Contract("store")
 	if err != nil {
 		log.Fatal(err)
 	}
-	storeContract, err := store.NewStore(common.HexToAddress(contractAddr), client)
+	storeContract, err := contract.LoadStore(client, contractAddr)
 	if err != nil {
 		log.Fatal(err)
 	}
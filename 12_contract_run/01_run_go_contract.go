@@ -4,68 +4,54 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"math/big"
-	"os"
-	"time"
 
-	"github.com/ethereum/go-ethereum"
-	"github.com/ethereum/go-ethereum/accounts/abi/bind"
-	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/ethclient"
-	"github.com/joho/godotenv"
-	"github.com/ydh2333/dapp_stu/10_contract_deploy/store"
-)
-
-const (
-	contractAddr = "0x9F49FF297E88AD77120f0e261a76fa3A835c24Aa"
+	"github.com/ydh2333/dapp_stu/pkg/config"
+	"github.com/ydh2333/dapp_stu/pkg/contract"
+	"github.com/ydh2333/dapp_stu/pkg/guard"
+	"github.com/ydh2333/dapp_stu/pkg/nonce"
+	"github.com/ydh2333/dapp_stu/pkg/signer"
+	"github.com/ydh2333/dapp_stu/pkg/waiter"
 )
 
 func main1() {
+	ctx := context.Background()
 	// 步骤 1：连接以太坊节点
-	client, err := ethclient.Dial("https://ethereum-sepolia-rpc.publicnode.com")
+	cfg, err := config.FromCommandLine()
 	if err != nil {
 		log.Fatal(err)
 	}
-	// 步骤 2：实例化合约, 通过 Store 合约的 Go 绑定代码，结合合约地址和客户端实例，
-	// 创建合约交互对象 storeContract，后续可通过该对象调用合约方法
-	storeContract, err := store.NewStore(common.HexToAddress(contractAddr), client)
+	client, err := cfg.Dial(ctx)
 	if err != nil {
 		log.Fatal(err)
 	}
-
-	// 步骤 3：加载私钥（交易签名用）
-	err = godotenv.Load()
+	// 签名守卫：确认节点的 eth_chainId 与配置的链 ID 一致，主网需显式开启 --allow-mainnet
+	g, err := guard.FromConfig(ctx, client, cfg)
 	if err != nil {
-		log.Fatal("Error loading .env file")
-	}
-	privateKeyStr := os.Getenv("PRIVATE_KEY")
-	if privateKeyStr == "" {
-		log.Fatal("PRIVATE_KEY is not set in .env file")
+		log.Fatal(err)
 	}
-	privateKey, err := crypto.HexToECDSA(privateKeyStr)
+	// nonce 管理器：本地分配 nonce 并持久化到 --nonce-file，连续或并发发送也不会冲突
+	backend := nonce.New(client, cfg.NonceFile).Wrap(client)
+
+	// 步骤 2：读取合约地址并加载签名账户（keystore）
+	// Store 合约地址来自网络配置（contracts.store）
+	contractAddr, err := cfg.Contract("store")
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	// 步骤 4：准备合约调用参数
-	// 将字符串复制到 32 字节数组中，适配合约参数类型
-	var key [32]byte
-	var value [32]byte
-	copy(key[:], []byte("demo_save_key5"))
-	copy(value[:], []byte("demo_save_value555"))
-
-	// 步骤 5：初始化交易选项（签名 + 链 ID）
-	// bind.NewKeyedTransactorWithChainID：创建交易签名器 opt（*bind.TransactOpts）；
-	// 入参：私钥 + 链 ID（Sepolia 测试网链 ID 为 11155111），确保交易仅在目标链有效。
-	opt, err := bind.NewKeyedTransactorWithChainID(privateKey, big.NewInt(11155111))
+	account, err := signer.FromConfig(cfg)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	// 步骤 6：调用合约写入方法（发送交易）
-	tx, err := storeContract.SetItem(opt, key, value)
+	// 步骤 3：准备合约调用参数
+	// 将字符串复制到 32 字节数组中，适配合约参数类型
+	key := contract.Key32("demo_save_key5")
+	value := contract.Key32("demo_save_value555")
+
+	// 步骤 4：调用合约写入方法（发送交易）
+	// SetItem 内部用 Store 合约绑定中的 ABI 编码调用数据，经 txbuilder 构造交易并由签名守卫签名
+	tx, err := contract.SetItem(ctx, backend, g, account, contractAddr, key, value)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -73,38 +59,23 @@ func main1() {
 
 	// 等待交易被挖矿
 	// 注意：只有在执行合约部署交易的情况下，合约地址（receipt）才会有值，否则为空（0x00000...）
-	receipt, err := waitForReceipt(client, tx.Hash())
-	if err != nil {
-		log.Fatal(err)
-	}
-	if receipt.Status == types.ReceiptStatusSuccessful {
-		fmt.Printf("交易成功！区块号：%d，消耗Gas：%d\n", receipt.BlockNumber, receipt.GasUsed)
-	} else {
+	// 等待交易达到 -confirmations 个确认：指数退避轮询，超过 -wait-timeout 放弃，被替换或丢弃时立即返回
+	status, err := waiter.WaitTx(ctx, client, tx, waiter.Options{Confirmations: *confirmations, Timeout: *waitTimeout})
+	switch {
+	case err == nil:
+		fmt.Printf("交易成功！区块号：%d，消耗Gas：%d，确认数：%d\n", status.Receipt.BlockNumber, status.Receipt.GasUsed, status.Confirmations)
+	case status.State == waiter.Failed:
 		fmt.Println("交易失败！")
+	default:
+		log.Fatalf("%v (%s)", err, status)
 	}
 
-	// 步骤 7：查询合约数据（验证写入结果）
-	// 构建只读调用选项（CallOpts）：不发送交易，仅查询链上数据，无 Gas 消耗
-	callOpt := &bind.CallOpts{Context: context.Background()}
-	// 调用合约 Items 方法（读取指定 key 的 value）
-	valueInContract, err := storeContract.Items(callOpt, key)
+	// 步骤 5：查询合约数据（验证写入结果）
+	// 只读调用（CallOpts）：不发送交易，仅查询链上数据，无 Gas 消耗
+	valueInContract, err := contract.GetItem(ctx, client, contractAddr, key)
 	if err != nil {
 		log.Fatal(err)
 	}
 	fmt.Println("valueInContract:", valueInContract)
 	fmt.Println("is value saving in contract equals to origin value:", valueInContract == value)
 }
-
-func waitForReceipt(client *ethclient.Client, txHash common.Hash) (*types.Receipt, error) {
-	for {
-		receipt, err := client.TransactionReceipt(context.Background(), txHash)
-		if err == nil {
-			return receipt, nil
-		}
-		if err != ethereum.NotFound {
-			return nil, err
-		}
-		// 等待一段时间后再次查询
-		time.Sleep(1 * time.Second)
-	}
-}
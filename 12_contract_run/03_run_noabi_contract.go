@@ -2,139 +2,89 @@ package main
 
 import (
 	"context"
-	"crypto/ecdsa"
+	"flag"
 	"fmt"
 	"log"
-	"math/big"
-	"os"
-	"strings"
 	"time"
 
-	"github.com/ethereum/go-ethereum"
-	"github.com/ethereum/go-ethereum/accounts/abi"
-	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/ethclient"
-	"github.com/joho/godotenv"
+	"github.com/ydh2333/dapp_stu/pkg/config"
+	"github.com/ydh2333/dapp_stu/pkg/contract"
+	"github.com/ydh2333/dapp_stu/pkg/guard"
+	"github.com/ydh2333/dapp_stu/pkg/nonce"
+	"github.com/ydh2333/dapp_stu/pkg/signer"
+	"github.com/ydh2333/dapp_stu/pkg/waiter"
 )
 
-const (
-	contractAddr3 = "0x9F49FF297E88AD77120f0e261a76fa3A835c24Aa"
+var (
+	count         = flag.Int("n", 1, "number of setItem transactions to send back to back")
+	confirmations = flag.Uint64("confirmations", 1, "number of confirmations to wait for")
+	waitTimeout   = flag.Duration("wait-timeout", 5*time.Minute, "give up waiting for the receipt after this long")
 )
 
 func main() {
-	client, err := ethclient.Dial("https://ethereum-sepolia-rpc.publicnode.com")
+	ctx := context.Background()
+	cfg, err := config.FromCommandLine()
 	if err != nil {
 		log.Fatal(err)
 	}
-
-	err = godotenv.Load()
-	if err != nil {
-		log.Fatal("Error loading .env file")
-	}
-	privateKeyStr := os.Getenv("PRIVATE_KEY")
-	if privateKeyStr == "" {
-		log.Fatal("PRIVATE_KEY is not set in .env file")
+	if *count < 1 {
+		log.Fatal("-n must be at least 1")
 	}
-	privateKey, err := crypto.HexToECDSA(privateKeyStr)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	// 获取公钥地址
-	publicKey := privateKey.Public()
-	publicKeyECDSA, ok := publicKey.(*ecdsa.PublicKey)
-	if !ok {
-		log.Fatal("error casting public key to ECDSA")
-	}
-	fromAddress := crypto.PubkeyToAddress(*publicKeyECDSA)
-
-	// 获取 nonce
-	nonce, err := client.PendingNonceAt(context.Background(), fromAddress)
+	client, err := cfg.Dial(ctx)
 	if err != nil {
 		log.Fatal(err)
 	}
-
-	// 估算 gas 价格
-	gasPrice, err := client.SuggestGasPrice(context.Background())
+	// 签名守卫：确认节点的 eth_chainId 与配置的链 ID 一致，主网需显式开启 --allow-mainnet
+	g, err := guard.FromConfig(ctx, client, cfg)
 	if err != nil {
 		log.Fatal(err)
 	}
+	// nonce 管理器：本地分配 nonce 并持久化到 --nonce-file，连续或并发发送也不会冲突
+	backend := nonce.New(client, cfg.NonceFile).Wrap(client)
 
-	// 准备交易数据
-	contractABI, err := abi.JSON(strings.NewReader(`[{"inputs":[{"internalType":"string","name":"_version","type":"string"}],"stateMutability":"nonpayable","type":"constructor"},{"anonymous":false,"inputs":[{"indexed":false,"internalType":"bytes32","name":"key","type":"bytes32"},{"indexed":false,"internalType":"bytes32","name":"value","type":"bytes32"}],"name":"ItemSet","type":"event"},{"inputs":[{"internalType":"bytes32","name":"","type":"bytes32"}],"name":"items","outputs":[{"internalType":"bytes32","name":"","type":"bytes32"}],"stateMutability":"view","type":"function"},{"inputs":[{"internalType":"bytes32","name":"key","type":"bytes32"},{"internalType":"bytes32","name":"value","type":"bytes32"}],"name":"setItem","outputs":[],"stateMutability":"nonpayable","type":"function"},{"inputs":[],"name":"version","outputs":[{"internalType":"string","name":"","type":"string"}],"stateMutability":"view","type":"function"}]`))
+	// Store 合约地址来自网络配置（contracts.store）
+	contractAddr, err := cfg.Contract("store")
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	methodName := "setItem"
-	var key [32]byte
-	var value [32]byte
-
-	copy(key[:], []byte("demo_save_key_use_abi6"))
-	copy(value[:], []byte("demo_save_value_use_abi_666"))
-	input, err := contractABI.Pack(methodName, key, value)
-
-	// 创建交易并签名交易
-	chainID := big.NewInt(int64(11155111))
-	tx := types.NewTransaction(nonce, common.HexToAddress(contractAddr3), big.NewInt(0), 300000, gasPrice, input)
-	signedTx, err := types.SignTx(tx, types.NewEIP155Signer(chainID), privateKey)
+	// 加载签名账户（keystore 文件 + 口令解锁）
+	account, err := signer.FromConfig(cfg)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	// 发送交易
-	err = client.SendTransaction(context.Background(), signedTx)
-	if err != nil {
-		log.Fatal(err)
-	}
-	fmt.Printf("Transaction sent: %s\n", signedTx.Hash().Hex())
-	receipt, err := waitForReceipt3(client, signedTx.Hash())
-	if err != nil {
-		log.Fatal(err)
-	}
-	if receipt.Status == types.ReceiptStatusSuccessful {
-		fmt.Printf("交易成功！区块号：%d，消耗Gas：%d\n", receipt.BlockNumber, receipt.GasUsed)
-	} else {
+	// 准备交易数据
+	key := contract.Key32("demo_save_key_use_abi6")
+	value := contract.Key32("demo_save_value_use_abi_666")
+
+	// 用 ABI 编码 setItem 调用数据，创建交易并签名、发送；-n 大于 1 时连续发送，只等待最后一笔
+	var signedTx *types.Transaction
+	for i := 0; i < *count; i++ {
+		signedTx, err = nonce.Retry(ctx, 3, func() (*types.Transaction, error) {
+			return contract.SetItemABI(ctx, backend, g, account, contractAddr, key, value)
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Transaction sent: %s (nonce %d)\n", signedTx.Hash().Hex(), signedTx.Nonce())
+	}
+	// 等待交易达到 -confirmations 个确认：指数退避轮询，超过 -wait-timeout 放弃，被替换或丢弃时立即返回
+	status, err := waiter.WaitTx(ctx, client, signedTx, waiter.Options{Confirmations: *confirmations, Timeout: *waitTimeout})
+	switch {
+	case err == nil:
+		fmt.Printf("交易成功！区块号：%d，消耗Gas：%d，确认数：%d\n", status.Receipt.BlockNumber, status.Receipt.GasUsed, status.Confirmations)
+	case status.State == waiter.Failed:
 		fmt.Println("交易失败！")
+	default:
+		log.Fatalf("%v (%s)", err, status)
 	}
 
-	// 查询刚刚设置的值
-	callInput, err := contractABI.Pack("items", key)
-	if err != nil {
-		log.Fatal(err)
-	}
-	to := common.HexToAddress(contractAddr3)
-	callMsg := ethereum.CallMsg{
-		To:   &to,
-		Data: callInput,
-	}
-
-	// 解析返回值
-	result, err := client.CallContract(context.Background(), callMsg, nil)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	var unpacked [32]byte
-	err = contractABI.UnpackIntoInterface(&unpacked, "items", result)
+	// 查询刚刚设置的值，并解析返回值
+	unpacked, err := contract.GetItemABI(ctx, client, contractAddr, key)
 	if err != nil {
 		log.Fatal(err)
 	}
 	fmt.Println("is value saving in contract equals to origin value:", unpacked == value)
 }
-
-func waitForReceipt3(client *ethclient.Client, txHash common.Hash) (*types.Receipt, error) {
-	for {
-		receipt, err := client.TransactionReceipt(context.Background(), txHash)
-		if err == nil {
-			return receipt, nil
-		}
-		if err != ethereum.NotFound {
-			return nil, err
-		}
-		// 等待一段时间后再次查询
-		time.Sleep(1 * time.Second)
-	}
-}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/console/prompt"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ydh2333/dapp_stu/pkg/signer"
+)
+
+/*
+	把私钥加密保存为 Web3 Secret Storage 格式的 keystore 文件，
+	之后转账、部署、合约调用都通过 --keystore 指定该文件，不再把明文私钥写进 .env
+*/
+
+var (
+	dir        = flag.String("dir", "keystore", "directory to write the keystore file to")
+	importFlag = flag.Bool("import", false, "import an existing hex private key instead of generating a new one")
+)
+
+func main() {
+	flag.Parse()
+
+	// 1. 准备私钥：导入已有的十六进制私钥，或生成新的随机私钥
+	var (
+		key *ecdsa.PrivateKey
+		err error
+	)
+	if *importFlag {
+		// 使用密码输入方式读取，避免私钥回显在终端或留在 shell 历史里
+		hexKey, err := prompt.Stdin.PromptPassword("Hex private key: ")
+		if err != nil {
+			log.Fatal(err)
+		}
+		key, err = crypto.HexToECDSA(strings.TrimPrefix(strings.TrimSpace(hexKey), "0x"))
+		if err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		key, err = crypto.GenerateKey()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// 2. 输入两次口令，口令用于 scrypt 派生加密密钥
+	passphrase, err := prompt.Stdin.PromptPassword("Passphrase: ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	confirm, err := prompt.Stdin.PromptPassword("Repeat passphrase: ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if passphrase != confirm {
+		log.Fatal("passphrases do not match")
+	}
+
+	// 3. 加密私钥并写入 keystore 文件
+	path, err := signer.ImportKey(*dir, key, passphrase)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// 4. 用同一口令解锁一次，确认文件可用
+	loaded, err := signer.LoadKeystore(path, passphrase)
+	if err != nil {
+		log.Fatal(err)
+	}
+	address := crypto.PubkeyToAddress(key.PublicKey)
+	fmt.Println("address: ", address.Hex())
+	fmt.Println("keystore:", path)
+	fmt.Println("unlock ok:", loaded.Address() == address)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/console/prompt"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ydh2333/dapp_stu/pkg/hdwallet"
+)
+
+/*
+	BIP-39 助记词 + BIP-32/BIP-44 分层确定性钱包：
+	  生成新助记词：go run ./14_hd_wallet -new
+	  恢复并列出前 N 个地址：MNEMONIC="..." go run ./14_hd_wallet -n 5
+	  派生任意路径：go run ./14_hd_wallet -path "m/44'/60'/1'/0/0"
+*/
+
+var (
+	newFlag  = flag.Bool("new", false, "generate a new mnemonic instead of restoring one")
+	bits     = flag.Int("bits", 128, "entropy bits for -new: 128 (12 words) to 256 (24 words)")
+	count    = flag.Int("n", 5, "number of accounts to list along m/44'/60'/0'/0/i")
+	pathFlag = flag.String("path", "", "derive a single custom derivation path instead of listing")
+	showKeys = flag.Bool("show-keys", false, "also print the derived private keys (test wallets only)")
+)
+
+func main() {
+	flag.Parse()
+
+	// 1. 获取助记词：新生成，或从 MNEMONIC 环境变量 / 终端输入恢复
+	var (
+		mnemonic string
+		err      error
+	)
+	if *newFlag {
+		mnemonic, err = hdwallet.NewMnemonic(*bits)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println("mnemonic:", mnemonic)
+		fmt.Println("--------------------------------------------------------")
+	} else if mnemonic = os.Getenv("MNEMONIC"); mnemonic == "" {
+		mnemonic, err = prompt.Stdin.PromptPassword("Mnemonic: ")
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// 2. 助记词 + 可选口令（BIP39_PASSPHRASE，"第 25 个词"）→ 种子 → 主私钥
+	wallet, err := hdwallet.FromMnemonic(strings.TrimSpace(mnemonic), os.Getenv("BIP39_PASSPHRASE"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// 3. 派生账户，每个地址都用 crypto.PubkeyToAddress 与手动 Keccak 两种方式计算并交叉校验
+	var list []*hdwallet.Account
+	if *pathFlag != "" {
+		path, err := accounts.ParseDerivationPath(*pathFlag)
+		if err != nil {
+			log.Fatal(err)
+		}
+		account, err := wallet.DeriveAccount(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		list = append(list, account)
+	} else {
+		list, err = wallet.Accounts(*count)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	for _, account := range list {
+		fmt.Printf("%-20s %s\n", account.Path, account.Address.Hex())
+		if *showKeys {
+			fmt.Printf("%-20s %s\n", "", hexutil.Encode(crypto.FromECDSA(account.PrivateKey)))
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"regexp"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/console/prompt"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ydh2333/dapp_stu/pkg/signer"
+	"github.com/ydh2333/dapp_stu/pkg/vanity"
+)
+
+/*
+	多核靓号地址生成：
+	  前缀：go run ./15_vanity_address -prefix dead
+	  前后缀 + 校验和大小写：go run ./15_vanity_address -prefix Ab -suffix 00 -case
+	  正则：go run ./15_vanity_address -regex '^(00)+'
+	每多一个十六进制字符难度乘 16，区分大小写时每个字母再乘 2；Ctrl+C 可随时取消
+*/
+
+var (
+	prefix   = flag.String("prefix", "", "address prefix (hex, without 0x)")
+	suffix   = flag.String("suffix", "", "address suffix (hex)")
+	regex    = flag.String("regex", "", "regular expression the address (without 0x) must match")
+	caseFlag = flag.Bool("case", false, "match the EIP-55 checksum case of -prefix/-suffix/-regex")
+	workers  = flag.Int("workers", 0, "number of worker goroutines (default: all CPU cores)")
+	timeout  = flag.Duration("timeout", 0, "give up after this long (0 = no limit)")
+	dir      = flag.String("dir", "", "encrypt the found key into a keystore file in this directory")
+	showKey  = flag.Bool("show-key", false, "print the raw private key (test wallets only)")
+)
+
+func main() {
+	flag.Parse()
+
+	pattern := vanity.Pattern{Prefix: *prefix, Suffix: *suffix, CaseSensitive: *caseFlag}
+	if *regex != "" {
+		re, err := regexp.Compile(*regex)
+		if err != nil {
+			log.Fatal(err)
+		}
+		pattern.Regex = re
+	}
+	if err := pattern.Validate(); err != nil {
+		log.Fatal(err)
+	}
+
+	// 1. Ctrl+C 或超时都会取消搜索
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+
+	// 2. 多个 worker 并行搜索，进度输出到 stderr
+	fmt.Fprintf(os.Stderr, "difficulty: %.0f\n", pattern.Difficulty())
+	result, err := vanity.Search(ctx, pattern, vanity.Options{
+		Workers: *workers,
+		Progress: func(s vanity.Stats) {
+			fmt.Fprintf(os.Stderr, "\r%d keys, %.0f keys/s, %.1f%% probability, 50%% ETA %s   ",
+				s.Attempts, s.Rate, s.Probability()*100, s.ETA50().Round(time.Second))
+		},
+	})
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("address: ", result.Address.Hex())
+	fmt.Printf("attempts: %d in %s (%.0f keys/s)\n", result.Stats.Attempts, result.Stats.Elapsed.Round(time.Millisecond), result.Stats.Rate)
+	if *showKey {
+		fmt.Println("key:     ", hexutil.Encode(crypto.FromECDSA(result.Key)))
+	}
+
+	// 3. 可选：把找到的私钥加密保存为 keystore 文件
+	if *dir != "" {
+		passphrase, err := prompt.Stdin.PromptPassword("Passphrase: ")
+		if err != nil {
+			log.Fatal(err)
+		}
+		path, err := signer.ImportKey(*dir, result.Key, passphrase)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println("keystore:", path)
+	}
+}
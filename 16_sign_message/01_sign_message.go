@@ -0,0 +1,93 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ydh2333/dapp_stu/pkg/config"
+	"github.com/ydh2333/dapp_stu/pkg/msgsign"
+	"github.com/ydh2333/dapp_stu/pkg/signer"
+)
+
+/*
+	消息签名与验签（证明钱包所有权）：
+	  签名（使用与转账示例相同的 keystore / --insecure-private-key 账户）：
+	    go run ./16_sign_message -message "login nonce 42"
+	  对原始 32 字节哈希签名：
+	    go run ./16_sign_message -hash 0x...
+	  验签 / 恢复签名者（不需要私钥）：
+	    go run ./16_sign_message -message "login nonce 42" -sig 0x... -address 0x...
+*/
+
+var (
+	message = flag.String("message", "", "message to sign with EIP-191 personal_sign")
+	hash    = flag.String("hash", "", "raw 32-byte hash (hex) to sign instead of -message")
+	sigFlag = flag.String("sig", "", "verify this signature instead of signing")
+	address = flag.String("address", "", "expected signer address when verifying")
+)
+
+func main() {
+	cfg, err := config.FromCommandLine()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// 1. 计算待签名的哈希：personal_sign 会先加上 "\x19Ethereum Signed Message:\n" + 长度 前缀
+	var digest []byte
+	if *hash != "" {
+		if digest, err = hexutil.Decode(*hash); err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		digest = msgsign.TextHash([]byte(*message))
+	}
+	fmt.Println("hash:     ", hexutil.Encode(digest))
+
+	// 2. 验签模式：从签名恢复公钥和地址，并与期望地址比较
+	if *sigFlag != "" {
+		sig, err := hexutil.Decode(*sigFlag)
+		if err != nil {
+			log.Fatal(err)
+		}
+		pub, err := msgsign.RecoverPubkey(digest, sig)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println("pubkey:   ", hexutil.Encode(crypto.FromECDSAPub(pub)))
+		fmt.Println("recovered:", crypto.PubkeyToAddress(*pub).Hex())
+		if *address != "" {
+			ok, err := msgsign.VerifyHash(digest, sig, common.HexToAddress(*address))
+			if err != nil {
+				log.Fatal(err)
+			}
+			fmt.Println("valid:    ", ok)
+		}
+		return
+	}
+
+	// 3. 签名模式：加载账户并签名，再恢复一次确认签名者就是自己
+	account, err := signer.FromConfig(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var sig []byte
+	if *hash != "" {
+		sig, err = msgsign.SignHash(account, digest)
+	} else {
+		sig, err = msgsign.SignMessage(account, []byte(*message))
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	recovered, err := msgsign.RecoverAddress(digest, sig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("signer:   ", account.Address().Hex())
+	fmt.Println("signature:", hexutil.Encode(sig))
+	fmt.Println("recovered:", recovered.Hex(), recovered == account.Address())
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ydh2333/dapp_stu/pkg/config"
+	"github.com/ydh2333/dapp_stu/pkg/signer"
+	"github.com/ydh2333/dapp_stu/pkg/typeddata"
+)
+
+/*
+	EIP-712 结构化数据签名（permit、链下订单、登录等的基础）：
+	  签名：go run ./17_typed_data -file 17_typed_data/group_mail.json
+	  验签：go run ./17_typed_data -file 17_typed_data/mail.json -sig 0x... -address 0x...
+	域中声明了 chainId 时，签名前会与当前网络配置的链 ID 比对，防止签出可在其他链使用的签名
+*/
+
+var (
+	file    = flag.String("file", "", "EIP-712 typed data JSON document (eth_signTypedData_v4 format)")
+	sigFlag = flag.String("sig", "", "verify this signature instead of signing")
+	address = flag.String("address", "", "expected signer address when verifying")
+)
+
+func main() {
+	cfg, err := config.FromCommandLine()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *file == "" {
+		log.Fatal("-file is required")
+	}
+
+	// 1. 解析文档并计算 域分隔符 / 消息哈希 / 摘要
+	td, err := typeddata.ReadFile(*file)
+	if err != nil {
+		log.Fatal(err)
+	}
+	hashes, err := typeddata.Hash(td)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("domain separator:", hashes.DomainSeparator.Hex())
+	fmt.Println("message hash:    ", hashes.MessageHash.Hex())
+	fmt.Println("digest:          ", hashes.Digest.Hex())
+
+	// 2. 验签模式：恢复签名者并与期望地址比较
+	if *sigFlag != "" {
+		sig, err := hexutil.Decode(*sigFlag)
+		if err != nil {
+			log.Fatal(err)
+		}
+		result, err := typeddata.Recover(td, sig)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println("recovered:       ", result.Signer.Hex())
+		if *address != "" {
+			ok, err := typeddata.Verify(td, sig, common.HexToAddress(*address))
+			if err != nil {
+				log.Fatal(err)
+			}
+			fmt.Println("valid:           ", ok)
+		}
+		return
+	}
+
+	// 3. 签名模式：确认域的链 ID 与配置一致，再用与交易相同的账户签名
+	if chainID := typeddata.ChainID(td); chainID != nil && chainID.Cmp(cfg.ChainIDBig()) != 0 {
+		log.Fatalf("domain chainId %s does not match the %s network (chain %d)", chainID, cfg.Network, cfg.ChainID)
+	}
+	account, err := signer.FromConfig(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	result, err := typeddata.Sign(account, td)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("signature:       ", hexutil.Encode(result.Signature))
+	fmt.Println("signer:          ", result.Signer.Hex())
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ydh2333/dapp_stu/pkg/config"
+	"github.com/ydh2333/dapp_stu/pkg/feeoracle"
+	"github.com/ydh2333/dapp_stu/pkg/guard"
+	"github.com/ydh2333/dapp_stu/pkg/nonce"
+	"github.com/ydh2333/dapp_stu/pkg/replace"
+	"github.com/ydh2333/dapp_stu/pkg/signer"
+)
+
+/*
+	处理卡住的交易（05_ETH_transfer / 06_token_transfer 打印 tx sent: 之后迟迟不上链）：
+	  扫描账户：go run ./18_replace_tx -scan -address 0x...
+	  加速：    go run ./18_replace_tx -hash 0x... -fee-strategy fast
+	  取消：    go run ./18_replace_tx -hash 0x... -cancel
+	加速 / 取消都使用原交易的 nonce，新手续费至少比原交易高 10%，否则节点会拒绝替换；
+	blob 交易只能由带着原 blob 数据的 blob 交易替换，本工具不支持
+*/
+
+var (
+	hash       = flag.String("hash", "", "hash of the pending transaction to speed up or cancel")
+	cancelFlag = flag.Bool("cancel", false, "cancel the transaction with a 0-value self-transfer instead of speeding it up")
+	scan       = flag.Bool("scan", false, "list the nonces blocking an account instead of replacing a transaction")
+	address    = flag.String("address", "", "account to scan (default: the signing account)")
+)
+
+func main() {
+	ctx := context.Background()
+	cfg, err := config.FromCommandLine()
+	if err != nil {
+		log.Fatal(err)
+	}
+	client, err := cfg.Dial(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// 1. 扫描：比较 latest / pending nonce，并尝试通过 txpool_contentFrom 列出交易池中的交易
+	if *scan {
+		var account common.Address
+		if *address != "" {
+			account = common.HexToAddress(*address)
+		} else {
+			s, err := signer.FromConfig(cfg)
+			if err != nil {
+				log.Fatal(err)
+			}
+			account = s.Address()
+		}
+		report, err := replace.Scan(ctx, client, client.Client(), account)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println("account:      ", report.Account.Hex())
+		fmt.Println("latest nonce: ", report.LatestNonce)
+		fmt.Println("pending nonce:", report.PendingNonce)
+		if report.PoolErr != nil {
+			fmt.Println("txpool:       ", report.PoolErr)
+		}
+		for _, tx := range report.Pending {
+			if tx.Type() == types.LegacyTxType || tx.Type() == types.AccessListTxType {
+				fmt.Printf("pending nonce %d: %s (gas price %s)\n", tx.Nonce(), tx.Hash().Hex(), tx.GasPrice())
+			} else {
+				fmt.Printf("pending nonce %d: %s (max fee %s, tip %s)\n", tx.Nonce(), tx.Hash().Hex(), tx.GasFeeCap(), tx.GasTipCap())
+			}
+		}
+		for _, tx := range report.Queued {
+			fmt.Printf("queued  nonce %d: %s\n", tx.Nonce(), tx.Hash().Hex())
+		}
+		if !report.Stuck() {
+			fmt.Println("nothing is waiting to be mined")
+			return
+		}
+		fmt.Println("blocking nonces:", report.Blocking())
+		return
+	}
+
+	// 2. 加速 / 取消：经签名守卫签名，nonce 管理器记录替换后的交易哈希
+	if *hash == "" {
+		log.Fatal("-hash or -scan is required")
+	}
+	g, err := guard.FromConfig(ctx, client, cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	backend := nonce.New(client, cfg.NonceFile).Wrap(client)
+	account, err := signer.FromConfig(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	strategy, err := feeoracle.ParseStrategy(cfg.FeeStrategy)
+	if err != nil {
+		log.Fatal(err)
+	}
+	replaceTx := replace.SpeedUp
+	if *cancelFlag {
+		replaceTx = replace.Cancel
+	}
+	signedTx, err := replaceTx(ctx, backend, g, account, common.HexToHash(*hash), strategy)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if signedTx.Type() == types.LegacyTxType || signedTx.Type() == types.AccessListTxType {
+		fmt.Printf("replacement sent: %s (nonce %d, gas price %s)\n", signedTx.Hash().Hex(), signedTx.Nonce(), signedTx.GasPrice())
+	} else {
+		fmt.Printf("replacement sent: %s (nonce %d, max fee %s, tip %s)\n", signedTx.Hash().Hex(), signedTx.Nonce(), signedTx.GasFeeCap(), signedTx.GasTipCap())
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ydh2333/dapp_stu/pkg/abireg"
+	"github.com/ydh2333/dapp_stu/pkg/config"
+	"github.com/ydh2333/dapp_stu/pkg/logwatch"
+)
+
+/*
+	通用日志查询与监听：
+	  实时监听 Store 合约的 ItemSet 事件（需要 WebSocket）：
+	    go run ./19_watch_logs -address store -event ItemSet
+	  查询历史上转入某地址的代币 Transfer，查完后继续监听：
+	    go run ./19_watch_logs -address token -event Transfer -arg to=0x... -from 5000000 -follow
+	  按事件签名或 topic0 过滤，不在仓库 ABI 中的日志按原始 topics / data 打印：
+	    go run ./19_watch_logs -event "Approval(address,address,uint256)" -from 5000000 -to 5001000
+	  额外的 ABI 文件用 -abi 加入解码：
+	    go run ./19_watch_logs -abi path/to/Other.abi -event Other.Deposit
+*/
+
+// listFlag 支持多次传入同一个参数，也接受逗号分隔的多个值。
+type listFlag []string
+
+func (l *listFlag) String() string { return strings.Join(*l, ",") }
+
+func (l *listFlag) Set(v string) error {
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			*l = append(*l, s)
+		}
+	}
+	return nil
+}
+
+var (
+	addresses, events, args, topics, abiFiles listFlag
+
+	fromBlock = flag.Int64("from", -1, "query history starting at this block (-1 = live only)")
+	toBlock   = flag.Int64("to", -1, "last block of the history query (-1 = latest)")
+	follow    = flag.Bool("follow", false, "keep watching live logs after the history query")
+	chunk     = flag.Uint64("chunk", logwatch.DefaultChunk, "blocks per eth_getLogs request when querying history")
+)
+
+func init() {
+	flag.Var(&addresses, "address", "contract address or configured contract name (repeatable)")
+	flag.Var(&events, "event", "event name, signature or topic0 (repeatable)")
+	flag.Var(&args, "arg", "indexed argument filter name=value (repeatable)")
+	flag.Var(&topics, "topic", "raw topic filter position=0x... for positions 1-3 (repeatable)")
+	flag.Var(&abiFiles, "abi", "extra ABI file used for decoding (repeatable)")
+}
+
+func main() {
+	ctx := context.Background()
+	cfg, err := config.FromCommandLine()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// 1. 解码用的 ABI 注册表：仓库自带 Store_sol_Store.abi、MyERC20.abi，再加上 -abi 指定的文件
+	reg, err := abireg.Default()
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, path := range abiFiles {
+		if err := reg.LoadFile(path); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// 2. 组装过滤条件：地址可以是 0x 地址，也可以是网络配置里的合约名（store、token）
+	q := logwatch.Query{Events: events, Args: map[string][]string{}}
+	for _, a := range addresses {
+		if common.IsHexAddress(a) {
+			q.Addresses = append(q.Addresses, common.HexToAddress(a))
+			continue
+		}
+		addr, err := cfg.Contract(a)
+		if err != nil {
+			log.Fatal(err)
+		}
+		q.Addresses = append(q.Addresses, addr)
+	}
+	for _, a := range args {
+		name, value, ok := strings.Cut(a, "=")
+		if !ok {
+			log.Fatalf("-arg expects name=value, got %q", a)
+		}
+		q.Args[name] = append(q.Args[name], value)
+	}
+	for _, t := range topics {
+		pos, value, ok := strings.Cut(t, "=")
+		n, err := strconv.Atoi(pos)
+		topic, herr := hexutil.Decode(value)
+		if !ok || err != nil || n < 1 || n > 3 || herr != nil || len(topic) != common.HashLength {
+			log.Fatalf("-topic expects position=0x<32 bytes> with position 1-3, got %q", t)
+		}
+		q.Topics[n-1] = append(q.Topics[n-1], common.BytesToHash(topic))
+	}
+	if *fromBlock >= 0 {
+		q.FromBlock = big.NewInt(*fromBlock)
+	}
+	if *toBlock >= 0 {
+		q.ToBlock = big.NewInt(*toBlock)
+	}
+	filter, err := q.Resolve(reg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// 3. 历史查询用 HTTP 连接调用 FilterLogs；实时监听用 WebSocket 连接调用 SubscribeFilterLogs
+	show := func(l types.Log) {
+		d := reg.Decode(l)
+		removed := ""
+		if l.Removed {
+			removed = " (removed by reorg)"
+		}
+		fmt.Printf("block %d tx %s log %d %s%s\n  %s\n", l.BlockNumber, l.TxHash.Hex(), l.Index, l.Address.Hex(), removed, d)
+	}
+	if q.FromBlock != nil && !*follow {
+		client, err := cfg.Dial(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		logs, err := logwatch.History(ctx, client, filter, *chunk)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, l := range logs {
+			show(l)
+		}
+		fmt.Println("total:", len(logs))
+		return
+	}
+
+	client, err := cfg.DialWS(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	logs := make(chan types.Log)
+	errc := make(chan error, 1)
+	go func() {
+		if q.FromBlock != nil {
+			// 先补历史再接实时，两段之间不遗漏也不重复
+			errc <- logwatch.Follow(ctx, client, filter, *chunk, logs)
+		} else {
+			errc <- logwatch.Watch(ctx, client, filter, logs)
+		}
+	}()
+	for {
+		select {
+		case err := <-errc:
+			log.Fatal(err)
+		case l := <-logs:
+			show(l)
+		}
+	}
+}
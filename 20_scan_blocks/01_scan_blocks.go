@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ydh2333/dapp_stu/pkg/block"
+	"github.com/ydh2333/dapp_stu/pkg/config"
+	"github.com/ydh2333/dapp_stu/pkg/scanner"
+)
+
+/*
+	并发扫描一段区块范围（01–03 只查询单个区块）：
+	  扫描最近 1000 个区块的区块头：go run ./20_scan_blocks -last 1000 -mode headers
+	  扫描指定范围的区块及回执，每秒最多 20 个请求：go run ./20_scan_blocks -from 5671744 -to 5672743 -mode receipts -rate 20
+	进度写入 -checkpoint 文件，中途退出（Ctrl+C 或出错）后用同样的参数重新运行即从断点继续。
+*/
+
+var (
+	fromFlag   = flag.Int64("from", -1, "first block of the range (-1 = use -last, or resume the checkpoint's range)")
+	toFlag     = flag.String("to", "latest", "last block of the range: number or latest/safe/finalized")
+	last       = flag.Uint64("last", 100, "scan this many blocks ending at -to when -from is not set")
+	modeFlag   = flag.String("mode", "blocks", "what to fetch per block: headers, blocks or receipts")
+	workers    = flag.Int("workers", scanner.DefaultWorkers, "number of concurrent fetch workers")
+	rate       = flag.Float64("rate", 0, "maximum RPC requests per second (0 = unlimited)")
+	checkpoint = flag.String("checkpoint", "scan_checkpoint.json", "progress file used to resume an interrupted scan (empty = disabled)")
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	cfg, err := config.FromCommandLine()
+	if err != nil {
+		log.Fatal(err)
+	}
+	client, err := cfg.Dial(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// 1. 确定扫描范围：-to 支持区块号与 latest/safe/finalized 标签；未指定 -from 时优先沿用断点文件的起点，
+	//    否则取 -to 之前的 -last 个区块
+	toNumber, err := block.ParseNumber(*toFlag)
+	if err != nil {
+		log.Fatal(err)
+	}
+	toHeader, err := block.QueryHeader(ctx, client, toNumber)
+	if err != nil {
+		log.Fatal(err)
+	}
+	to := toHeader.Number.Uint64()
+	var from uint64
+	switch {
+	case *fromFlag >= 0:
+		from = uint64(*fromFlag)
+	case *checkpoint != "":
+		cp, err := scanner.LoadCheckpoint(*checkpoint)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if cp != nil && cp.Next <= cp.To {
+			from, to = cp.From, cp.To
+			fmt.Printf("resuming scan of %d..%d at block %d\n", cp.From, cp.To, cp.Next)
+			break
+		}
+		fallthrough
+	default:
+		if *last > 0 && *last <= to+1 {
+			from = to + 1 - *last
+		}
+	}
+
+	var mode scanner.Mode
+	switch *modeFlag {
+	case "headers":
+		mode = scanner.Headers
+	case "blocks":
+		mode = scanner.Blocks
+	case "receipts":
+		mode = scanner.Receipts
+	default:
+		log.Fatalf("unknown -mode %q (headers, blocks or receipts)", *modeFlag)
+	}
+
+	// 2. 启动扫描：worker 池并发拉取，结果按区块号顺序送出；回执优先用 eth_getBlockReceipts，
+	//    节点不支持时自动改为逐笔 TransactionReceipt
+	s := scanner.New(client, scanner.Options{
+		Mode:       mode,
+		Workers:    *workers,
+		Rate:       *rate,
+		Checkpoint: *checkpoint,
+	})
+	results := make(chan *scanner.Result)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- s.Run(ctx, from, to, results)
+		close(results)
+	}()
+
+	// 3. 逐块输出摘要并累计统计
+	start := time.Now()
+	var blocks, txs, logs, failed, gasUsed uint64
+	for r := range results {
+		blocks++
+		gasUsed += r.Header.GasUsed
+		line := fmt.Sprintf("block %d %s gas %d/%d", r.Number, r.Header.Hash().Hex(), r.Header.GasUsed, r.Header.GasLimit)
+		if r.Block != nil {
+			txs += uint64(len(r.Block.Transactions()))
+			line += fmt.Sprintf(" txs %d", len(r.Block.Transactions()))
+		}
+		if r.Receipts != nil {
+			var blockLogs, blockFailed int
+			for _, rc := range r.Receipts {
+				blockLogs += len(rc.Logs)
+				if rc.Status != types.ReceiptStatusSuccessful {
+					blockFailed++
+				}
+			}
+			logs += uint64(blockLogs)
+			failed += uint64(blockFailed)
+			line += fmt.Sprintf(" logs %d failed %d", blockLogs, blockFailed)
+		}
+		fmt.Println(line)
+	}
+	err = <-errc
+
+	elapsed := time.Since(start)
+	fmt.Println("-------------------------------------------------")
+	fmt.Printf("range %d..%d: %d blocks in %s (%.1f blocks/s), %d requests\n",
+		from, to, blocks, elapsed.Round(time.Millisecond), float64(blocks)/elapsed.Seconds(), s.Requests())
+	fmt.Printf("gas used %d, transactions %d", gasUsed, txs)
+	if mode == scanner.Receipts {
+		fmt.Printf(", logs %d, failed transactions %d, eth_getBlockReceipts %v", logs, failed, s.BlockReceiptsSupported())
+	}
+	fmt.Println()
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ydh2333/dapp_stu/pkg/abireg"
+	"github.com/ydh2333/dapp_stu/pkg/block"
+	"github.com/ydh2333/dapp_stu/pkg/config"
+	"github.com/ydh2333/dapp_stu/pkg/indexer"
+	"github.com/ydh2333/dapp_stu/pkg/scanner"
+	"github.com/ydh2333/dapp_stu/pkg/transaction"
+)
+
+/*
+	本地链数据索引（LevelDB，默认目录 chainindex，可用 --index / INDEX_DIR 指定）：
+	  索引最近 1000 个区块：go run ./21_index_chain -last 1000
+	  持续跟随最新区块，遇到重组自动回滚：go run ./21_index_chain -follow
+	  只查询、不同步：go run ./21_index_chain -sync=false -address 0x... -topic 0xddf252ad...
+	01–03 加上 --index chainindex 即可先从索引回答区块、交易和回执查询。
+*/
+
+var (
+	syncFlag = flag.Bool("sync", true, "sync the index with the node before querying")
+	fromFlag = flag.Int64("from", -1, "first block to index when the index is empty (-1 = -last blocks before -to)")
+	toFlag   = flag.String("to", "latest", "last block to index: number or latest/safe/finalized")
+	last     = flag.Uint64("last", 100, "index this many blocks ending at -to when the index is empty and -from is not set")
+	follow   = flag.Duration("follow", 0, "keep syncing at this interval (e.g. 12s) until interrupted")
+	workers  = flag.Int("workers", scanner.DefaultWorkers, "number of concurrent fetch workers")
+	rate     = flag.Float64("rate", 0, "maximum RPC requests per second (0 = unlimited)")
+
+	addressQuery = flag.String("address", "", "list indexed transactions touching this address")
+	txQuery      = flag.String("tx", "", "show an indexed transaction and its receipt")
+	blockQuery   = flag.Int64("block", -1, "show an indexed block")
+	topicQuery   = flag.String("topic", "", "list indexed logs containing this topic in any position")
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	cfg, err := config.FromCommandLine()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if cfg.IndexDir == "" {
+		cfg.IndexDir = "chainindex"
+	}
+	idx, err := indexer.FromConfig(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer idx.Close()
+
+	// 1. 同步：索引为空时从 -from（或 -to 之前的 -last 个区块）开始，否则从索引头继续；
+	//    区块和回执由 scanner 并发拉取、按序写入，父哈希对不上时与节点比对并回滚到共同祖先
+	if *syncFlag {
+		client, err := cfg.Dial(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		opts := indexer.SyncOptions{
+			Options: scanner.Options{Workers: *workers, Rate: *rate},
+			OnBlock: func(b *types.Block, receipts []*types.Receipt) {
+				var logs int
+				for _, r := range receipts {
+					logs += len(r.Logs)
+				}
+				fmt.Printf("indexed block %d %s txs %d logs %d\n", b.NumberU64(), b.Hash().Hex(), len(b.Transactions()), logs)
+			},
+			OnRewind: func(from, to uint64) {
+				fmt.Printf("reorg: removed blocks %d..%d from the index\n", from, to)
+			},
+		}
+		toNumber, err := block.ParseNumber(*toFlag)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for {
+			head, err := block.QueryHeader(ctx, client, toNumber)
+			if err != nil {
+				log.Fatal(err)
+			}
+			to := head.Number.Uint64()
+			from := uint64(0)
+			if *fromFlag >= 0 {
+				from = uint64(*fromFlag)
+			} else if *last > 0 && *last <= to+1 {
+				from = to + 1 - *last
+			}
+			if err := idx.Sync(ctx, client, from, to, opts); err != nil && ctx.Err() == nil {
+				log.Fatal(err)
+			}
+			if *follow <= 0 {
+				break
+			}
+			select {
+			case <-time.After(*follow):
+			case <-ctx.Done():
+			}
+			if ctx.Err() != nil {
+				break
+			}
+		}
+	}
+	start, _ := idx.Start()
+	if number, hash, ok := idx.Head(); ok {
+		fmt.Printf("index covers blocks %d..%d (head %s)\n", start, number, hash.Hex())
+	} else {
+		fmt.Println("index is empty")
+	}
+
+	// 2. 查询：全部由本地索引回答，不访问节点
+	if *blockQuery >= 0 {
+		b, err := idx.BlockByNumber(uint64(*blockQuery))
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println("-------------------------------------------------")
+		if err := block.SummarizeBlock(b).Write(os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *txQuery != "" {
+		e, err := idx.Transaction(common.HexToHash(*txQuery))
+		if err != nil {
+			log.Fatal(err)
+		}
+		r, err := idx.Receipt(e.Tx.Hash())
+		if err != nil {
+			log.Fatal(err)
+		}
+		b, err := idx.BlockByHash(e.BlockHash)
+		if err != nil {
+			log.Fatal(err)
+		}
+		details, err := transaction.Describe(e.Tx, cfg.ChainIDBig())
+		if err != nil {
+			log.Fatal(err)
+		}
+		details.AttachReceipt(r, b.BaseFee())
+		fmt.Println("-------------------------------------------------")
+		if err := details.Write(os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *addressQuery != "" {
+		if !common.IsHexAddress(*addressQuery) {
+			log.Fatalf("-address %q is not a hex address", *addressQuery)
+		}
+		entries, err := idx.TransactionsByAddress(common.HexToAddress(*addressQuery), 0, math.MaxUint64)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println("-------------------------------------------------")
+		for _, e := range entries {
+			to := "(contract creation)"
+			if e.Tx.To() != nil {
+				to = e.Tx.To().Hex()
+			}
+			fmt.Printf("block %d index %d %s %s → %s value %s\n", e.BlockNumber, e.Index, e.Tx.Hash().Hex(), e.From.Hex(), to, e.Tx.Value())
+		}
+		fmt.Println("transactions:", len(entries))
+	}
+	if *topicQuery != "" {
+		logs, err := idx.LogsByTopic(common.HexToHash(*topicQuery), 0, math.MaxUint64)
+		if err != nil {
+			log.Fatal(err)
+		}
+		// 日志按仓库自带 ABI 解码，未知事件输出原始 topics / data
+		reg, err := abireg.Default()
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println("-------------------------------------------------")
+		for _, l := range logs {
+			fmt.Printf("block %d tx %s log %d %s\n  %s\n", l.BlockNumber, l.TxHash.Hex(), l.Index, l.Address.Hex(), reg.Decode(*l))
+		}
+		fmt.Println("logs:", len(logs))
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"os/signal"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	token "github.com/ydh2333/dapp_stu/08_search_token_balance/erc20"
+	"github.com/ydh2333/dapp_stu/pkg/balance"
+	"github.com/ydh2333/dapp_stu/pkg/config"
+	"github.com/ydh2333/dapp_stu/pkg/tokenhistory"
+)
+
+/*
+	ERC20 转账历史（LevelDB，默认目录 tokenhistory）：
+	  同步网络配置中 token 合约最近 10000 个区块的 Transfer / Approval：go run ./22_token_history -last 10000
+	  再次运行从上次索引到的区块继续；查询某地址的全部转账及逐笔余额：
+	    go run ./22_token_history -address 0x51ccc58AE0a621b78196CcE2e01920dd6E5be38b
+	  其他代币：go run ./22_token_history -token 0x... -from 5000000
+	节点报告结果过多时每次查询的区块数自动减半，成功后逐步恢复到 -chunk。
+*/
+
+var (
+	tokenFlag     = flag.String("token", "token", "token contract address or configured contract name")
+	dbDir         = flag.String("db", "tokenhistory", "history database directory")
+	syncFlag      = flag.Bool("sync", true, "sync the history with the node before querying")
+	fromFlag      = flag.Int64("from", -1, "first block to index when the token is not indexed yet (-1 = -last blocks before the head)")
+	last          = flag.Uint64("last", 10000, "index this many blocks when the token is not indexed yet and -from is not set")
+	confirmations = flag.Uint64("confirmations", 12, "only index blocks this far below the latest block")
+	chunk         = flag.Uint64("chunk", tokenhistory.DefaultChunk, "maximum blocks per log query")
+	addressQuery  = flag.String("address", "", "list transfers and approvals of this address with running balances")
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	cfg, err := config.FromCommandLine()
+	if err != nil {
+		log.Fatal(err)
+	}
+	client, err := cfg.Dial(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	tokenAddress := common.HexToAddress(*tokenFlag)
+	if !common.IsHexAddress(*tokenFlag) {
+		if tokenAddress, err = cfg.Contract(*tokenFlag); err != nil {
+			log.Fatal(err)
+		}
+	}
+	store, err := tokenhistory.Open(*dbDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer store.Close()
+
+	// 1. 同步：只索引到最新区块减去确认数，首次从 -from（或最近 -last 个区块）开始，之后从上次的位置继续
+	if *syncFlag {
+		head, err := client.HeaderByNumber(ctx, nil)
+		if err != nil {
+			log.Fatal(err)
+		}
+		var to uint64
+		if n := head.Number.Uint64(); n > *confirmations {
+			to = n - *confirmations
+		}
+		from := uint64(0)
+		if *fromFlag >= 0 {
+			from = uint64(*fromFlag)
+		} else if *last > 0 && *last <= to+1 {
+			from = to + 1 - *last
+		}
+		opts := tokenhistory.Options{
+			Chunk: *chunk,
+			OnChunk: func(from, to uint64, events int) {
+				fmt.Printf("indexed blocks %d..%d events %d\n", from, to, events)
+			},
+			OnShrink: func(from, to, next uint64) {
+				fmt.Printf("blocks %d..%d returned too many results, retrying with %d blocks\n", from, to, next)
+			},
+			OnRewind: func(from, to uint64) {
+				fmt.Printf("reorg: removed blocks %d..%d from the history\n", from, to)
+			},
+		}
+		if err := store.Sync(ctx, client, tokenAddress, from, to, opts); err != nil {
+			if ctx.Err() == nil {
+				log.Fatal(err)
+			}
+			fmt.Println("interrupted, run again to resume")
+		}
+	}
+	start, _ := store.Start(tokenAddress)
+	head, headHash, ok := store.Head(tokenAddress)
+	if !ok {
+		fmt.Println("token", tokenAddress.Hex(), "is not indexed yet")
+		return
+	}
+	fmt.Printf("token %s indexed blocks %d..%d (head %s)\n", tokenAddress.Hex(), start, head, headHash.Hex())
+	if *addressQuery == "" {
+		return
+	}
+	if !common.IsHexAddress(*addressQuery) {
+		log.Fatalf("-address %q is not a hex address", *addressQuery)
+	}
+	addr := common.HexToAddress(*addressQuery)
+
+	// 2. 起始余额：起始区块之前的 BalanceOf（需要归档节点），查询失败时从 0 开始累计
+	caller, err := token.NewErc20Caller(tokenAddress, client)
+	if err != nil {
+		log.Fatal(err)
+	}
+	decimals, err := caller.Decimals(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		log.Fatal(err)
+	}
+	opening := new(big.Int)
+	if start > 0 {
+		bal, err := caller.BalanceOf(&bind.CallOpts{Context: ctx, BlockNumber: new(big.Int).SetUint64(start - 1)}, addr)
+		if err != nil {
+			fmt.Printf("opening balance at block %d unavailable (%v), counting from 0\n", start-1, err)
+		} else {
+			opening = bal
+		}
+	}
+
+	// 3. 逐笔转账及转账后的余额，全部由本地历史库回答
+	entries, err := store.History(tokenAddress, addr, opening)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("-------------------------------------------------")
+	fmt.Printf("opening balance %s\n", balance.ToUnits(opening, int(decimals)).Text('f', int(decimals)))
+	for _, e := range entries {
+		fmt.Printf("block %d tx %s log %d %s → %s %s balance %s\n", e.BlockNumber, e.TxHash.Hex(), e.LogIndex,
+			e.From.Hex(), e.To.Hex(), balance.ToUnits(e.Delta, int(decimals)).Text('f', int(decimals)),
+			balance.ToUnits(e.Balance, int(decimals)).Text('f', int(decimals)))
+	}
+	fmt.Println("transfers:", len(entries))
+
+	// 与节点在索引头处的 BalanceOf 对照，起始余额可用时两者应当一致
+	closing := opening
+	if len(entries) > 0 {
+		closing = entries[len(entries)-1].Balance
+	}
+	if bal, err := caller.BalanceOf(&bind.CallOpts{Context: ctx, BlockNumber: new(big.Int).SetUint64(head)}, addr); err == nil {
+		fmt.Printf("balance at block %d: history %s, balanceOf %s, match %v\n", head, closing, bal, closing.Cmp(bal) == 0)
+	}
+
+	approvals, err := store.Approvals(tokenAddress, addr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, e := range approvals {
+		fmt.Printf("block %d tx %s approval owner %s spender %s value %s\n", e.BlockNumber, e.TxHash.Hex(), e.From.Hex(), e.To.Hex(), e.Value)
+	}
+	fmt.Println("approvals:", len(approvals))
+}
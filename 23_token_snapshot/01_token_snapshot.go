@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	token "github.com/ydh2333/dapp_stu/08_search_token_balance/erc20"
+	"github.com/ydh2333/dapp_stu/pkg/balance"
+	"github.com/ydh2333/dapp_stu/pkg/block"
+	"github.com/ydh2333/dapp_stu/pkg/config"
+	"github.com/ydh2333/dapp_stu/pkg/snapshot"
+	"github.com/ydh2333/dapp_stu/pkg/tokenhistory"
+)
+
+/*
+	代币持仓快照（空投、治理投票权）：
+	  从部署区块开始重放 Transfer，得到区块 5671744 的全部持有人并导出：
+	    go run ./23_token_snapshot -from 5000000 -block 5671744 -csv holders.csv -json holders.json
+	  已用 22_token_history 索引过该代币时，直接用本地历史重建（历史需要从部署区块开始）：
+	    go run ./23_token_snapshot -db tokenhistory -block 5671744
+	抽样持有人的余额会在快照区块上用 BalanceOf 核对，持仓之和与 TotalSupply 核对（需要归档节点）。
+*/
+
+var (
+	tokenFlag = flag.String("token", "token", "token contract address or configured contract name")
+	blockFlag = flag.String("block", "latest", "snapshot block: number or latest/safe/finalized")
+	fromFlag  = flag.Uint64("from", 0, "first block to replay, no later than the token deployment")
+	chunk     = flag.Uint64("chunk", tokenhistory.DefaultChunk, "maximum blocks per log query")
+	dbDir     = flag.String("db", "", "rebuild from this 22_token_history database instead of querying the node")
+	sample    = flag.Int("sample", 20, "number of holders to check against balanceOf (0 = skip verification)")
+	top       = flag.Int("top", 10, "print this many top holders")
+	csvOut    = flag.String("csv", "", "write the ranked holders to this CSV file")
+	jsonOut   = flag.String("json", "", "write the snapshot to this JSON file")
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	cfg, err := config.FromCommandLine()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *top < 0 {
+		log.Fatal("-top must not be negative")
+	}
+	client, err := cfg.Dial(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	tokenAddress := common.HexToAddress(*tokenFlag)
+	if !common.IsHexAddress(*tokenFlag) {
+		if tokenAddress, err = cfg.Contract(*tokenFlag); err != nil {
+			log.Fatal(err)
+		}
+	}
+	number, err := block.ParseNumber(*blockFlag)
+	if err != nil {
+		log.Fatal(err)
+	}
+	header, err := block.QueryHeader(ctx, client, number)
+	if err != nil {
+		log.Fatal(err)
+	}
+	at := header.Number.Uint64()
+
+	// 1. 重建：优先使用本地历史库，否则用 FilterTransfer 分段拉取 [from, block] 内的 Transfer 并重放
+	var snap *snapshot.Snapshot
+	if *dbDir != "" {
+		store, err := tokenhistory.Open(*dbDir)
+		if err != nil {
+			log.Fatal(err)
+		}
+		snap, err = snapshot.FromStore(store, tokenAddress, at)
+		store.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		opts := tokenhistory.Options{
+			Chunk: *chunk,
+			OnShrink: func(from, to, next uint64) {
+				fmt.Printf("blocks %d..%d returned too many results, retrying with %d blocks\n", from, to, next)
+			},
+		}
+		if snap, err = snapshot.Build(ctx, client, tokenAddress, *fromFlag, at, opts); err != nil {
+			log.Fatal(err)
+		}
+	}
+	caller, err := token.NewErc20Caller(tokenAddress, client)
+	if err != nil {
+		log.Fatal(err)
+	}
+	dec, err := caller.Decimals(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("token %s block %d (replayed from %d): transfers %d holders %d total %s\n",
+		tokenAddress.Hex(), snap.Block, snap.From, snap.Transfers, len(snap.Holders), balance.ToUnits(snap.Total, int(dec)).Text('f', int(dec)))
+	for _, addr := range snap.Negative {
+		fmt.Println("negative balance (history starts too late?):", addr.Hex())
+	}
+	for _, h := range snap.Holders[:min(*top, len(snap.Holders))] {
+		fmt.Printf("%4d %s %s %.4f%%\n", h.Rank, h.Address.Hex(), balance.ToUnits(h.Balance, int(dec)).Text('f', int(dec)), h.Share*100)
+	}
+
+	// 2. 核对：抽样 BalanceOf 与 TotalSupply 都在快照区块上查询
+	if *sample > 0 {
+		v, err := snap.Verify(ctx, client, *sample)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println("-------------------------------------------------")
+		fmt.Printf("balanceOf checked %d, mismatches %d\n", v.Checked, len(v.Mismatches))
+		for _, m := range v.Mismatches {
+			fmt.Printf("  %s snapshot %s on-chain %s\n", m.Address.Hex(), m.Snapshot, m.OnChain)
+		}
+		fmt.Printf("totalSupply %s, sum of holders %s, match %v\n", v.TotalSupply, snap.Total, v.SupplyMatches)
+	}
+
+	// 3. 导出
+	if *csvOut != "" {
+		f, err := os.Create(*csvOut)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := snap.WriteCSV(f, dec); err != nil {
+			log.Fatal(err)
+		}
+		if err := f.Close(); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *jsonOut != "" {
+		f, err := os.Create(*jsonOut)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := snap.WriteJSON(f); err != nil {
+			log.Fatal(err)
+		}
+		if err := f.Close(); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,320 @@
+// Package abireg 汇总若干合约 ABI，按 topic0 查找事件并解码日志（包括 indexed 参数），
+// 按 4 字节选择器解码调用数据（另有内置的离线函数签名库），并拆分合约创建数据中的构造函数参数。
+// 无法识别的日志和调用保留原始数据。默认注册仓库自带的 Store 与 MyERC20 ABI。
+package abireg
+
+import (
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	token "github.com/ydh2333/dapp_stu/08_search_token_balance/erc20"
+	"github.com/ydh2333/dapp_stu/10_contract_deploy/store"
+	"github.com/ydh2333/dapp_stu/pkg/errs"
+)
+
+// Event 是注册表中的一个事件定义。
+type Event struct {
+	Contract string // 定义该事件的 ABI 名称，如 "Store"、"MyERC20"
+	abi.Event
+}
+
+// Registry 按 topic0 索引事件。不同合约可能定义签名相同的事件（如 ERC20 与 ERC721 的 Transfer），
+// 它们的 indexed 参数个数不同，解码时按日志的 topics 数量挑选匹配的定义。
+type Registry struct {
+	names  []string
+	abis   map[string]*abi.ABI
+	bins   map[string][]byte // 创建字节码，用于识别合约创建交易
+	events map[common.Hash][]*Event
+}
+
+// New 创建空的注册表。
+func New() *Registry {
+	return &Registry{abis: make(map[string]*abi.ABI), bins: make(map[string][]byte), events: make(map[common.Hash][]*Event)}
+}
+
+// Default 返回注册了仓库自带 ABI（Store_sol_Store.abi、MyERC20.abi，取自 abigen 绑定）的注册表。
+func Default() (*Registry, error) {
+	const op = "abireg.Default"
+
+	r := New()
+	for _, c := range []struct {
+		name string
+		meta *bind.MetaData
+	}{
+		{"Store", store.StoreMetaData},
+		{"MyERC20", token.Erc20MetaData},
+	} {
+		parsed, err := c.meta.GetAbi()
+		if err != nil {
+			return nil, errs.Wrap(op, err)
+		}
+		r.Add(c.name, parsed)
+		if c.meta.Bin != "" {
+			bin, err := hexutil.Decode(c.meta.Bin)
+			if err != nil {
+				return nil, errs.Wrap(op, err)
+			}
+			r.AddBytecode(c.name, bin)
+		}
+	}
+	return r, nil
+}
+
+// Add 以 name 注册一个 ABI，同名 ABI 会被替换。
+func (r *Registry) Add(name string, parsed *abi.ABI) {
+	if _, ok := r.abis[name]; ok {
+		r.remove(name)
+	} else {
+		r.names = append(r.names, name)
+	}
+	r.abis[name] = parsed
+	for _, ev := range parsed.Events {
+		if ev.Anonymous {
+			continue
+		}
+		r.events[ev.ID] = append(r.events[ev.ID], &Event{Contract: name, Event: ev})
+	}
+}
+
+// AddBytecode 为已注册的 name 记录创建字节码（solc 输出的 .bin），用于精确拆分合约创建交易。
+func (r *Registry) AddBytecode(name string, bin []byte) {
+	r.bins[name] = bin
+}
+
+// remove 删除 name 注册的事件和创建字节码。
+func (r *Registry) remove(name string) {
+	for id, list := range r.events {
+		kept := list[:0]
+		for _, ev := range list {
+			if ev.Contract != name {
+				kept = append(kept, ev)
+			}
+		}
+		r.events[id] = kept
+	}
+	delete(r.bins, name)
+}
+
+// LoadFile 读取 solc 输出的 .abi 文件并以文件名（去掉扩展名）注册。
+func (r *Registry) LoadFile(path string) error {
+	const op = "abireg.LoadFile"
+
+	f, err := os.Open(path)
+	if err != nil {
+		return errs.Wrap(op, err)
+	}
+	defer f.Close()
+	parsed, err := abi.JSON(f)
+	if err != nil {
+		return errs.Wrap(op, fmt.Errorf("%s: %w", path, err))
+	}
+	r.Add(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)), &parsed)
+	return nil
+}
+
+// Names 返回已注册的 ABI 名称，按注册顺序排列。
+func (r *Registry) Names() []string {
+	return append([]string(nil), r.names...)
+}
+
+// ABI 返回以 name 注册的 ABI。
+func (r *Registry) ABI(name string) (*abi.ABI, bool) {
+	parsed, ok := r.abis[name]
+	return parsed, ok
+}
+
+// EventsByTopic 返回 topic0 为 topic 的全部事件定义。
+func (r *Registry) EventsByTopic(topic common.Hash) []*Event {
+	return r.events[topic]
+}
+
+// EventsByName 按事件名查找，name 可以是 "Transfer" 或带 ABI 名的 "MyERC20.Transfer"。
+func (r *Registry) EventsByName(name string) []*Event {
+	contract, event, qualified := strings.Cut(name, ".")
+	if !qualified {
+		contract, event = "", name
+	}
+	var found []*Event
+	for _, n := range r.names {
+		if contract != "" && n != contract {
+			continue
+		}
+		if ev, ok := r.abis[n].Events[event]; ok && !ev.Anonymous {
+			found = append(found, &Event{Contract: n, Event: ev})
+		}
+	}
+	return found
+}
+
+// Arg 是解码后的一个事件参数。
+type Arg struct {
+	Name    string
+	Type    abi.Type
+	Indexed bool
+	Value   any // string / bytes / 数组类型的 indexed 参数只能得到其 keccak256 哈希（common.Hash）
+}
+
+// DecodedLog 是解码后的日志。Event 为 nil 表示注册表中没有匹配的事件或解码失败，此时只有原始数据。
+type DecodedLog struct {
+	Log   types.Log
+	Event *Event
+	Args  []Arg
+	Err   error // 找到了事件定义但解码失败的原因
+}
+
+// Decode 用注册表解码日志，总是返回结果：找不到事件定义或解码失败时退回原始 topics 和 data。
+func (r *Registry) Decode(l types.Log) *DecodedLog {
+	d := &DecodedLog{Log: l}
+	if len(l.Topics) == 0 {
+		return d
+	}
+	for _, ev := range r.events[l.Topics[0]] {
+		if countIndexed(ev.Inputs) != len(l.Topics)-1 {
+			continue
+		}
+		args, err := decodeArgs(&ev.Event, l)
+		if err != nil {
+			d.Err = err
+			continue
+		}
+		d.Event, d.Args, d.Err = ev, args, nil
+		return d
+	}
+	return d
+}
+
+// Arg 按名称返回参数值。
+func (d *DecodedLog) Arg(name string) (any, bool) {
+	for _, a := range d.Args {
+		if a.Name == name {
+			return a.Value, true
+		}
+	}
+	return nil, false
+}
+
+// Name 返回事件名，未解码时返回 topic0（没有 topic 的匿名日志返回 "anonymous"）。
+func (d *DecodedLog) Name() string {
+	switch {
+	case d.Event != nil:
+		return d.Event.Name
+	case len(d.Log.Topics) > 0:
+		return d.Log.Topics[0].Hex()
+	default:
+		return "anonymous"
+	}
+}
+
+// String 返回形如 Transfer(from=0x..., to=0x..., value=100) 的可读形式，
+// 未解码时列出原始 topics 和 data。
+func (d *DecodedLog) String() string {
+	if d.Event == nil {
+		topics := make([]string, len(d.Log.Topics))
+		for i, t := range d.Log.Topics {
+			topics[i] = t.Hex()
+		}
+		return fmt.Sprintf("raw(topics=[%s], data=%s)", strings.Join(topics, ", "), hexutil.Encode(d.Log.Data))
+	}
+	return d.Event.Name + "(" + formatArgs(d.Args) + ")"
+}
+
+// countIndexed 统计 indexed 参数个数。
+func countIndexed(args abi.Arguments) int {
+	n := 0
+	for _, a := range args {
+		if a.Indexed {
+			n++
+		}
+	}
+	return n
+}
+
+// decodeArgs 从 topics 解出 indexed 参数、从 data 解出其余参数，按 ABI 中的参数顺序返回。
+// 解码前把参数按位置改名（Solidity 标识符不会是纯数字），未命名或重名的参数不会互相覆盖。
+func decodeArgs(ev *abi.Event, l types.Log) ([]Arg, error) {
+	inputs := make(abi.Arguments, len(ev.Inputs))
+	var indexed abi.Arguments
+	for i, a := range ev.Inputs {
+		a.Name = strconv.Itoa(i)
+		inputs[i] = a
+		if a.Indexed {
+			indexed = append(indexed, a)
+		}
+	}
+	values := make(map[string]any, len(inputs))
+	if err := abi.ParseTopicsIntoMap(values, indexed, l.Topics[1:]); err != nil {
+		return nil, err
+	}
+	if err := inputs.UnpackIntoMap(values, l.Data); err != nil {
+		return nil, err
+	}
+	args := make([]Arg, len(ev.Inputs))
+	for i, a := range ev.Inputs {
+		name := a.Name
+		if name == "" {
+			name = fmt.Sprintf("arg%d", i)
+		}
+		args[i] = Arg{Name: name, Type: a.Type, Indexed: a.Indexed, Value: values[strconv.Itoa(i)]}
+	}
+	return args, nil
+}
+
+// FormatValue 把解码出的参数值格式化为字符串：地址和哈希用十六进制，
+// bytes32 若是可打印的短字符串（如 Store 合约的 key/value）同时给出文本形式。
+func FormatValue(v any) string {
+	switch v := v.(type) {
+	case common.Address:
+		return v.Hex()
+	case common.Hash:
+		return v.Hex()
+	case *big.Int:
+		return v.String()
+	case string:
+		return strconv.Quote(v)
+	case []byte:
+		return hexutil.Encode(v)
+	case []common.Address:
+		parts := make([]string, len(v))
+		for i, a := range v {
+			parts[i] = a.Hex()
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	case [][]byte:
+		parts := make([]string, len(v))
+		for i, b := range v {
+			parts[i] = hexutil.Encode(b)
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	case [32]byte:
+		if s, ok := printable(v[:]); ok {
+			return fmt.Sprintf("%s(%q)", hexutil.Encode(v[:]), s)
+		}
+		return hexutil.Encode(v[:])
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// printable 把右侧补零的字节串还原为字符串，含不可打印字符时返回 false。
+func printable(b []byte) (string, bool) {
+	s := strings.TrimRight(string(b), "\x00")
+	if s == "" {
+		return "", false
+	}
+	for _, r := range s {
+		if r == unicode.ReplacementChar || !unicode.IsPrint(r) {
+			return "", false
+		}
+	}
+	return s, true
+}
@@ -0,0 +1,105 @@
+package abireg
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// movedEvent 在代码中构造事件（不经过 abi.JSON，未命名的参数保持空名）：两个未命名的 indexed 参数，
+// data 中一个具名参数和两个未命名参数。
+func movedEvent(t *testing.T) abi.Event {
+	t.Helper()
+	arg := func(typ string, indexed bool) abi.Argument {
+		ty, err := abi.NewType(typ, "", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return abi.Argument{Type: ty, Indexed: indexed}
+	}
+	inputs := abi.Arguments{arg("address", true), arg("address", true), arg("uint256", false), arg("uint256", false), arg("bytes32", false)}
+	inputs[2].Name = "value"
+	return abi.Event{
+		Name:    "Moved",
+		RawName: "Moved",
+		Inputs:  inputs,
+		ID:      crypto.Keccak256Hash([]byte("Moved(address,address,uint256,uint256,bytes32)")),
+	}
+}
+
+func TestDecodeUnnamedArgs(t *testing.T) {
+	ev := movedEvent(t)
+	r := New()
+	r.Add("moved", &abi.ABI{Events: map[string]abi.Event{ev.Name: ev}})
+
+	from := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	to := common.HexToAddress("0x2222222222222222222222222222222222222222")
+	value, fee := big.NewInt(1000), big.NewInt(7)
+	memo := [32]byte{'m', 'e', 'm', 'o'}
+	data, err := ev.Inputs.NonIndexed().Pack(value, fee, memo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := r.Decode(types.Log{
+		Topics: []common.Hash{ev.ID, common.BytesToHash(from.Bytes()), common.BytesToHash(to.Bytes())},
+		Data:   data,
+	})
+	if d.Err != nil || d.Event == nil {
+		t.Fatalf("decode failed: %v", d.Err)
+	}
+
+	want := []struct {
+		name  string
+		value any
+	}{
+		{"arg0", from},
+		{"arg1", to},
+		{"value", value},
+		{"arg3", fee},
+		{"arg4", memo},
+	}
+	if len(d.Args) != len(want) {
+		t.Fatalf("got %d args, want %d", len(d.Args), len(want))
+	}
+	for i, w := range want {
+		got := d.Args[i]
+		if got.Name != w.name {
+			t.Errorf("arg %d: name %q, want %q", i, got.Name, w.name)
+		}
+		if FormatValue(got.Value) != FormatValue(w.value) {
+			t.Errorf("arg %d (%s): value %s, want %s", i, got.Name, FormatValue(got.Value), FormatValue(w.value))
+		}
+	}
+}
+
+// abi.JSON 把未命名参数命名为 argN，可能与真正叫 argN 的参数重名。
+func TestDecodeGeneratedNameCollision(t *testing.T) {
+	parsed, err := abi.JSON(strings.NewReader(`[{"type":"event","name":"Tagged","anonymous":false,"inputs":[
+		{"name":"","type":"uint256","indexed":true},
+		{"name":"arg0","type":"uint256","indexed":false}]}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := New()
+	r.Add("tagged", &parsed)
+
+	ev := parsed.Events["Tagged"]
+	data, err := ev.Inputs.NonIndexed().Pack(big.NewInt(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := r.Decode(types.Log{Topics: []common.Hash{ev.ID, common.BigToHash(big.NewInt(1))}, Data: data})
+	if d.Err != nil || d.Event == nil {
+		t.Fatalf("decode failed: %v", d.Err)
+	}
+	for i, want := range []int64{1, 2} {
+		if v, ok := d.Args[i].Value.(*big.Int); !ok || v.Int64() != want {
+			t.Errorf("arg %d: value %v, want %d", i, d.Args[i].Value, want)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package abireg
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// SignatureDB 是离线函数签名库中方法的 Contract 名称。
+const SignatureDB = "sigdb"
+
+//go:embed signatures.txt
+var signaturesFile string
+
+// signatureDB 按选择器索引离线签名库，首次使用时解析。
+var signatureDB = sync.OnceValue(func() map[[4]byte][]*Method {
+	db := make(map[[4]byte][]*Method)
+	for _, line := range strings.Split(signaturesFile, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		m, err := ParseSignature(line)
+		if err != nil {
+			continue
+		}
+		db[[4]byte(m.ID)] = append(db[[4]byte(m.ID)], m)
+	}
+	return db
+})
+
+// Method 是注册表中的一个函数定义。
+type Method struct {
+	Contract string // 定义该函数的 ABI 名称，来自离线签名库时为 SignatureDB
+	abi.Method
+}
+
+// ParseSignature 把 "transfer(address,uint256)" 形式的规范签名解析为函数定义，参数名为空。
+// 不支持元组参数。
+func ParseSignature(sig string) (*Method, error) {
+	name, rest, ok := strings.Cut(strings.ReplaceAll(sig, " ", ""), "(")
+	if !ok || name == "" || !strings.HasSuffix(rest, ")") {
+		return nil, fmt.Errorf("invalid function signature %q", sig)
+	}
+	rest = strings.TrimSuffix(rest, ")")
+	var inputs abi.Arguments
+	if rest != "" {
+		for _, t := range strings.Split(rest, ",") {
+			typ, err := abi.NewType(t, "", nil)
+			if err != nil {
+				return nil, fmt.Errorf("invalid function signature %q: %w", sig, err)
+			}
+			inputs = append(inputs, abi.Argument{Type: typ})
+		}
+	}
+	return &Method{
+		Contract: SignatureDB,
+		Method:   abi.NewMethod(name, name, abi.Function, "nonpayable", false, false, inputs, nil),
+	}, nil
+}
+
+// MethodsBySelector 返回选择器对应的全部函数定义：先是已注册 ABI 中的函数（按注册顺序），再是离线签名库中的签名。
+func (r *Registry) MethodsBySelector(selector [4]byte) []*Method {
+	var found []*Method
+	for _, name := range r.names {
+		if m, err := r.abis[name].MethodById(selector[:]); err == nil {
+			found = append(found, &Method{Contract: name, Method: *m})
+		}
+	}
+	return append(found, signatureDB()[selector]...)
+}
+
+// DecodedCall 是解码后的调用数据。Method 为 nil 表示选择器未知或参数无法按任何候选定义解码。
+type DecodedCall struct {
+	Data   []byte
+	Method *Method
+	Args   []Arg
+	Err    error // 找到了候选定义但都解码失败时，最后一个失败原因
+}
+
+// DecodeCall 按前 4 字节选择器查找函数定义并解码参数。同一选择器有多个候选（签名碰撞）时，
+// 优先选择重新编码后与原数据完全一致的定义。
+func (r *Registry) DecodeCall(data []byte) *DecodedCall {
+	c := &DecodedCall{Data: data}
+	if len(data) < 4 {
+		return c
+	}
+	var lenient *DecodedCall
+	for _, m := range r.MethodsBySelector([4]byte(data[:4])) {
+		args, exact, err := unpackArgs(m.Inputs, data[4:])
+		if err != nil {
+			c.Err = err
+			continue
+		}
+		if exact {
+			c.Method, c.Args, c.Err = m, args, nil
+			return c
+		}
+		if lenient == nil {
+			lenient = &DecodedCall{Data: data, Method: m, Args: args}
+		}
+	}
+	if lenient != nil {
+		return lenient
+	}
+	return c
+}
+
+// Selector 返回十六进制的 4 字节选择器，数据不足 4 字节时返回空字符串。
+func (c *DecodedCall) Selector() string {
+	if len(c.Data) < 4 {
+		return ""
+	}
+	return hexutil.Encode(c.Data[:4])
+}
+
+// String 返回形如 transfer(to=0x..., value=100) 的可读形式。
+func (c *DecodedCall) String() string {
+	switch {
+	case len(c.Data) == 0:
+		return "(no calldata)"
+	case c.Method == nil && len(c.Data) < 4:
+		return "raw(" + hexutil.Encode(c.Data) + ")"
+	case c.Method == nil:
+		return fmt.Sprintf("unknown %s(%d bytes of arguments)", c.Selector(), len(c.Data)-4)
+	}
+	return c.Method.Name + "(" + formatArgs(c.Args) + ")"
+}
+
+// DecodedCreation 是解码后的合约创建数据：创建字节码之后紧跟 ABI 编码的构造函数参数。
+type DecodedCreation struct {
+	Code            []byte // 创建字节码（initcode）
+	ConstructorArgs []byte // 构造函数参数的 ABI 编码
+	Contract        string // 识别出的 ABI 名称，未识别时为空
+	// Exact 表示字节码与注册的创建字节码完全一致；否则是按 solc 元数据尾部切分，
+	// 再用各 ABI 的构造函数试探解码参数得到的结果。
+	Exact bool
+	Args  []Arg
+	Err   error
+}
+
+// DecodeCreation 拆分合约创建交易的数据。先匹配注册的创建字节码；
+// 匹配不到时以 solc 追加在字节码末尾的 CBOR 元数据为界，之后的部分视为构造函数参数。
+func (r *Registry) DecodeCreation(data []byte) *DecodedCreation {
+	d := &DecodedCreation{Code: data}
+	for _, name := range r.names {
+		bin := r.bins[name]
+		if len(bin) == 0 || !bytes.HasPrefix(data, bin) {
+			continue
+		}
+		d.Code, d.ConstructorArgs, d.Contract, d.Exact = bin, data[len(bin):], name, true
+		d.Args, _, d.Err = unpackArgs(r.abis[name].Constructor.Inputs, d.ConstructorArgs)
+		return d
+	}
+
+	end := metadataEnd(data)
+	if end < 0 {
+		return d
+	}
+	d.Code, d.ConstructorArgs = data[:end], data[end:]
+	if len(d.ConstructorArgs) == 0 {
+		return d
+	}
+	for _, name := range r.names {
+		inputs := r.abis[name].Constructor.Inputs
+		if len(inputs) == 0 {
+			continue
+		}
+		if args, exact, err := unpackArgs(inputs, d.ConstructorArgs); err == nil && exact {
+			d.Contract, d.Args = name, args
+			return d
+		}
+	}
+	return d
+}
+
+// String 返回创建数据的摘要。
+func (d *DecodedCreation) String() string {
+	s := fmt.Sprintf("create code=%d bytes, constructor args=%d bytes", len(d.Code), len(d.ConstructorArgs))
+	if d.Contract != "" {
+		match := "probable"
+		if d.Exact {
+			match = "exact bytecode"
+		}
+		s += fmt.Sprintf(", %s(%s) [%s]", d.Contract, formatArgs(d.Args), match)
+	}
+	return s
+}
+
+// DecodeTx 解码交易数据：合约创建交易返回 *DecodedCreation，其余返回 *DecodedCall。
+func (r *Registry) DecodeTx(tx *types.Transaction) fmt.Stringer {
+	if tx.To() == nil {
+		return r.DecodeCreation(tx.Data())
+	}
+	return r.DecodeCall(tx.Data())
+}
+
+// metadataEnd 定位 solc 元数据尾部的结束位置：末尾两个字节是 CBOR 长度，
+// CBOR 以 map 开头并包含 "solc" 键。找不到时返回 -1。取最后一处，因为创建字节码内嵌的运行时代码也带有元数据。
+func metadataEnd(data []byte) int {
+	marker := []byte{0x64, 's', 'o', 'l', 'c', 0x43} // "solc": 3 字节版本号
+	for search := data; ; {
+		i := bytes.LastIndex(search, marker)
+		if i < 0 {
+			return -1
+		}
+		end := i + len(marker) + 3 + 2
+		if end <= len(data) {
+			n := int(data[end-2])<<8 | int(data[end-1])
+			if start := end - 2 - n; start >= 0 && data[start]&0xf0 == 0xa0 {
+				return end
+			}
+		}
+		search = data[:i]
+	}
+}
+
+// unpackArgs 解码参数，exact 表示重新编码后与原数据完全一致。
+func unpackArgs(inputs abi.Arguments, data []byte) (args []Arg, exact bool, err error) {
+	values, err := inputs.Unpack(data)
+	if err != nil {
+		return nil, false, err
+	}
+	repacked, err := inputs.Pack(values...)
+	exact = err == nil && bytes.Equal(repacked, data)
+	args = make([]Arg, len(inputs))
+	for i, in := range inputs {
+		name := in.Name
+		if name == "" {
+			name = fmt.Sprintf("arg%d", i)
+		}
+		args[i] = Arg{Name: name, Type: in.Type, Value: values[i]}
+	}
+	return args, exact, nil
+}
+
+// formatArgs 把参数格式化为 name=value 列表。
+func formatArgs(args []Arg) string {
+	parts := make([]string, len(args))
+	for i, a := range args {
+		parts[i] = a.Name + "=" + FormatValue(a.Value)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,84 @@
+// Package balance 封装 ETH 余额与 ERC20 代币余额的查询（对应 07_search_balance、08_search_token_balance）。
+package balance
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	token "github.com/ydh2333/dapp_stu/08_search_token_balance/erc20"
+	"github.com/ydh2333/dapp_stu/pkg/errs"
+)
+
+// QueryBalance 查询账户在指定区块的 ETH 余额（单位 wei），blockNumber 为 nil 表示最新区块。
+func QueryBalance(ctx context.Context, client ethereum.ChainStateReader, account common.Address, blockNumber *big.Int) (*big.Int, error) {
+	balance, err := client.BalanceAt(ctx, account, blockNumber)
+	if err != nil {
+		return nil, errs.Wrap("balance.QueryBalance", err)
+	}
+	return balance, nil
+}
+
+// QueryPendingBalance 查询账户计入待处理交易后的余额（Pending 余额）。
+func QueryPendingBalance(ctx context.Context, client ethereum.PendingStateReader, account common.Address) (*big.Int, error) {
+	balance, err := client.PendingBalanceAt(ctx, account)
+	if err != nil {
+		return nil, errs.Wrap("balance.QueryPendingBalance", err)
+	}
+	return balance, nil
+}
+
+// TokenBalance 是代币余额及代币基础信息。
+type TokenBalance struct {
+	Name     string
+	Symbol   string
+	Decimals uint8
+	Balance  *big.Int // 原始余额（最小单位）
+}
+
+// Value 把原始余额换算成可读的代币单位（除以 10^Decimals）。
+func (b *TokenBalance) Value() *big.Float {
+	return ToUnits(b.Balance, int(b.Decimals))
+}
+
+// QueryTokenBalance 查询 account 持有的 ERC20 代币余额，同时返回名称、符号和小数位数。
+func QueryTokenBalance(ctx context.Context, caller bind.ContractCaller, tokenAddress, account common.Address) (*TokenBalance, error) {
+	const op = "balance.QueryTokenBalance"
+
+	instance, err := token.NewErc20Caller(tokenAddress, caller)
+	if err != nil {
+		return nil, errs.Wrap(op, err)
+	}
+	opts := &bind.CallOpts{Context: ctx}
+	bal, err := instance.BalanceOf(opts, account)
+	if err != nil {
+		return nil, errs.Wrap(op, err)
+	}
+	name, err := instance.Name(opts)
+	if err != nil {
+		return nil, errs.Wrap(op, err)
+	}
+	symbol, err := instance.Symbol(opts)
+	if err != nil {
+		return nil, errs.Wrap(op, err)
+	}
+	decimals, err := instance.Decimals(opts)
+	if err != nil {
+		return nil, errs.Wrap(op, err)
+	}
+	return &TokenBalance{Name: name, Symbol: symbol, Decimals: decimals, Balance: bal}, nil
+}
+
+// ToUnits 把最小单位的数量换算成 10^decimals 进制的可读数量，如 wei → ETH。
+func ToUnits(amount *big.Int, decimals int) *big.Float {
+	f := new(big.Float).SetInt(amount)
+	unit := new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil))
+	return f.Quo(f, unit)
+}
+
+// WeiToEther 把 wei 换算成 ETH（1 ETH = 10^18 wei）。
+func WeiToEther(wei *big.Int) *big.Float {
+	return ToUnits(wei, 18)
+}
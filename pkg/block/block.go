@@ -0,0 +1,48 @@
+// Package block 封装区块及区块头的查询，并按分叉整理区块头的全部字段（对应 01_search_block）。
+package block
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ydh2333/dapp_stu/pkg/errs"
+)
+
+// QueryHeader 查询指定高度的区块头，number 为 nil 表示最新区块。
+func QueryHeader(ctx context.Context, client ethereum.ChainReader, number *big.Int) (*types.Header, error) {
+	header, err := client.HeaderByNumber(ctx, number)
+	if err != nil {
+		return nil, errs.Wrap("block.QueryHeader", err)
+	}
+	return header, nil
+}
+
+// QueryBlock 查询指定高度的完整区块（包含交易列表），number 为 nil 表示最新区块。
+func QueryBlock(ctx context.Context, client ethereum.ChainReader, number *big.Int) (*types.Block, error) {
+	block, err := client.BlockByNumber(ctx, number)
+	if err != nil {
+		return nil, errs.Wrap("block.QueryBlock", err)
+	}
+	return block, nil
+}
+
+// QueryBlockByHash 通过区块哈希查询完整区块。
+func QueryBlockByHash(ctx context.Context, client ethereum.ChainReader, hash common.Hash) (*types.Block, error) {
+	block, err := client.BlockByHash(ctx, hash)
+	if err != nil {
+		return nil, errs.Wrap("block.QueryBlockByHash", err)
+	}
+	return block, nil
+}
+
+// TransactionCount 查询指定区块内的交易数量。
+func TransactionCount(ctx context.Context, client ethereum.ChainReader, hash common.Hash) (uint, error) {
+	count, err := client.TransactionCount(ctx, hash)
+	if err != nil {
+		return 0, errs.Wrap("block.TransactionCount", err)
+	}
+	return count, nil
+}
@@ -0,0 +1,196 @@
+package block
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ydh2333/dapp_stu/pkg/errs"
+	"github.com/ydh2333/dapp_stu/pkg/transaction"
+)
+
+// ParseNumber 解析区块号参数：十进制或 0x 十六进制的高度，或者标签 latest / safe / finalized / pending / earliest。
+// 返回值可以直接传给 HeaderByNumber / BlockByNumber，latest 返回 nil。
+func ParseNumber(s string) (*big.Int, error) {
+	const op = "block.ParseNumber"
+
+	s = strings.TrimSpace(s)
+	switch strings.ToLower(s) {
+	case "", "latest":
+		return nil, nil
+	case "safe":
+		return big.NewInt(int64(rpc.SafeBlockNumber)), nil
+	case "finalized":
+		return big.NewInt(int64(rpc.FinalizedBlockNumber)), nil
+	case "pending":
+		return big.NewInt(int64(rpc.PendingBlockNumber)), nil
+	case "earliest":
+		return big.NewInt(0), nil
+	}
+	// 只认 0x 前缀的十六进制，其余按十进制解析：补零的 "010" 是 10 而不是八进制的 8
+	digits, base := s, 10
+	if len(s) > 2 && (s[:2] == "0x" || s[:2] == "0X") {
+		digits, base = s[2:], 16
+	}
+	n, err := strconv.ParseUint(digits, base, 64)
+	if err != nil {
+		return nil, errs.Invalid(op, fmt.Sprintf("block %q is not a number or one of latest/safe/finalized/pending/earliest", s))
+	}
+	return new(big.Int).SetUint64(n), nil
+}
+
+// TxTypeCount 是区块内某一交易类型的交易数量。
+type TxTypeCount struct {
+	Type  uint8  `json:"type"`
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// Summary 汇总区块头的全部字段（含伦敦、上海、坎昆、布拉格升级新增的字段）。
+// 指针字段为 nil 表示该区块所在的分叉还没有这个字段；Withdrawals、TxTypes 只有完整区块才有。
+type Summary struct {
+	Number      uint64         `json:"number"`
+	Hash        common.Hash    `json:"hash"`
+	ParentHash  common.Hash    `json:"parentHash"`
+	Time        uint64         `json:"timestamp"`
+	Miner       common.Address `json:"miner"`
+	Difficulty  *big.Int       `json:"difficulty"` // 合并（The Merge）之后恒为 0
+	StateRoot   common.Hash    `json:"stateRoot"`
+	TxRoot      common.Hash    `json:"transactionsRoot"`
+	ReceiptRoot common.Hash    `json:"receiptsRoot"`
+	GasLimit    uint64         `json:"gasLimit"`
+	GasUsed     uint64         `json:"gasUsed"`
+	Utilization float64        `json:"utilization"` // GasUsed / GasLimit，百分比
+
+	BaseFee          *big.Int     `json:"baseFeePerGas,omitempty"`         // 伦敦（EIP-1559）
+	WithdrawalsRoot  *common.Hash `json:"withdrawalsRoot,omitempty"`       // 上海（EIP-4895）
+	BlobGasUsed      *uint64      `json:"blobGasUsed,omitempty"`           // 坎昆（EIP-4844）
+	ExcessBlobGas    *uint64      `json:"excessBlobGas,omitempty"`         // 坎昆（EIP-4844）
+	ParentBeaconRoot *common.Hash `json:"parentBeaconBlockRoot,omitempty"` // 坎昆（EIP-4788）
+	RequestsHash     *common.Hash `json:"requestsHash,omitempty"`          // 布拉格（EIP-7685）
+
+	Full             bool                `json:"-"`
+	TxCount          int                 `json:"transactionCount,omitempty"`
+	TxTypes          []TxTypeCount       `json:"transactionTypes,omitempty"`
+	Withdrawals      []*types.Withdrawal `json:"withdrawals,omitempty"`
+	WithdrawalsTotal uint64              `json:"withdrawalsTotalGwei,omitempty"`
+}
+
+// Summarize 整理区块头的字段。
+func Summarize(h *types.Header) *Summary {
+	s := &Summary{
+		Number:           h.Number.Uint64(),
+		Hash:             h.Hash(),
+		ParentHash:       h.ParentHash,
+		Time:             h.Time,
+		Miner:            h.Coinbase,
+		Difficulty:       h.Difficulty,
+		StateRoot:        h.Root,
+		TxRoot:           h.TxHash,
+		ReceiptRoot:      h.ReceiptHash,
+		GasLimit:         h.GasLimit,
+		GasUsed:          h.GasUsed,
+		BaseFee:          h.BaseFee,
+		WithdrawalsRoot:  h.WithdrawalsHash,
+		BlobGasUsed:      h.BlobGasUsed,
+		ExcessBlobGas:    h.ExcessBlobGas,
+		ParentBeaconRoot: h.ParentBeaconRoot,
+		RequestsHash:     h.RequestsHash,
+	}
+	if h.GasLimit > 0 {
+		s.Utilization = float64(h.GasUsed) * 100 / float64(h.GasLimit)
+	}
+	return s
+}
+
+// SummarizeBlock 在区块头字段之外，统计各交易类型的数量并列出提款（withdrawals）。
+func SummarizeBlock(b *types.Block) *Summary {
+	s := Summarize(b.Header())
+	s.Full = true
+	s.TxCount = len(b.Transactions())
+	counts := make(map[uint8]int)
+	for _, tx := range b.Transactions() {
+		counts[tx.Type()]++
+	}
+	for t, n := range counts {
+		s.TxTypes = append(s.TxTypes, TxTypeCount{Type: t, Name: transaction.TypeName(t), Count: n})
+	}
+	sort.Slice(s.TxTypes, func(i, j int) bool { return s.TxTypes[i].Type < s.TxTypes[j].Type })
+	s.Withdrawals = b.Withdrawals()
+	for _, w := range s.Withdrawals {
+		s.WithdrawalsTotal += w.Amount
+	}
+	return s
+}
+
+// Inspect 查询并汇总区块。full 为 false 时只调用 HeaderByNumber，否则调用 BlockByNumber 获取交易和提款。
+// number 可以是 ParseNumber 返回的标签。
+func Inspect(ctx context.Context, client ethereum.ChainReader, number *big.Int, full bool) (*Summary, error) {
+	if !full {
+		h, err := QueryHeader(ctx, client, number)
+		if err != nil {
+			return nil, err
+		}
+		return Summarize(h), nil
+	}
+	b, err := QueryBlock(ctx, client, number)
+	if err != nil {
+		return nil, err
+	}
+	return SummarizeBlock(b), nil
+}
+
+// Write 以可读形式输出区块汇总，只列出该区块具有的字段。
+func (s *Summary) Write(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "number:\t%d\n", s.Number)
+	fmt.Fprintf(tw, "hash:\t%s\n", s.Hash.Hex())
+	fmt.Fprintf(tw, "parent hash:\t%s\n", s.ParentHash.Hex())
+	fmt.Fprintf(tw, "timestamp:\t%d\n", s.Time)
+	fmt.Fprintf(tw, "miner:\t%s\n", s.Miner.Hex())
+	fmt.Fprintf(tw, "difficulty:\t%s\n", s.Difficulty)
+	fmt.Fprintf(tw, "state root:\t%s\n", s.StateRoot.Hex())
+	fmt.Fprintf(tw, "transactions root:\t%s\n", s.TxRoot.Hex())
+	fmt.Fprintf(tw, "receipts root:\t%s\n", s.ReceiptRoot.Hex())
+	fmt.Fprintf(tw, "gas used:\t%d / %d (%.2f%%)\n", s.GasUsed, s.GasLimit, s.Utilization)
+	if s.BaseFee != nil {
+		fmt.Fprintf(tw, "base fee:\t%s wei\n", s.BaseFee)
+	}
+	if s.WithdrawalsRoot != nil {
+		fmt.Fprintf(tw, "withdrawals root:\t%s\n", s.WithdrawalsRoot.Hex())
+	}
+	if s.BlobGasUsed != nil {
+		fmt.Fprintf(tw, "blob gas used:\t%d\n", *s.BlobGasUsed)
+	}
+	if s.ExcessBlobGas != nil {
+		fmt.Fprintf(tw, "excess blob gas:\t%d\n", *s.ExcessBlobGas)
+	}
+	if s.ParentBeaconRoot != nil {
+		fmt.Fprintf(tw, "parent beacon root:\t%s\n", s.ParentBeaconRoot.Hex())
+	}
+	if s.RequestsHash != nil {
+		fmt.Fprintf(tw, "requests hash:\t%s\n", s.RequestsHash.Hex())
+	}
+	if s.Full {
+		fmt.Fprintf(tw, "transactions:\t%d\n", s.TxCount)
+		for _, t := range s.TxTypes {
+			fmt.Fprintf(tw, "  %s\t%d\n", t.Name, t.Count)
+		}
+		if s.WithdrawalsRoot != nil {
+			fmt.Fprintf(tw, "withdrawals:\t%d (%d gwei)\n", len(s.Withdrawals), s.WithdrawalsTotal)
+			for _, wd := range s.Withdrawals {
+				fmt.Fprintf(tw, "  #%d\tvalidator %d → %s, %d gwei\n", wd.Index, wd.Validator, wd.Address.Hex(), wd.Amount)
+			}
+		}
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,382 @@
+// Package config 统一管理网络配置：内置 mainnet / sepolia / dev 三个网络配置（Profile），
+// 依次叠加配置文件、环境变量（含 .env）和命令行参数，后者覆盖前者。
+// 各章节通过它获取节点客户端、链 ID 和合约地址，不再在代码里硬编码。
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/joho/godotenv"
+	"github.com/ydh2333/dapp_stu/pkg/errs"
+)
+
+// 内置网络名称。
+const (
+	Mainnet = "mainnet"
+	Sepolia = "sepolia"
+	Dev     = "dev"
+)
+
+// DefaultFile 是未指定 --config / CONFIG_FILE 时尝试读取的配置文件。
+const DefaultFile = "networks.json"
+
+// DefaultNonceFile 是未指定 --nonce-file / NONCE_FILE 时使用的 nonce 状态文件。
+const DefaultNonceFile = "nonces.json"
+
+// Profile 描述一个网络：节点地址、链 ID 以及该网络上已部署的合约地址。
+type Profile struct {
+	RPCURL    string            `json:"rpcUrl"`
+	WSURL     string            `json:"wsUrl,omitempty"`
+	ChainID   uint64            `json:"chainId"`
+	Contracts map[string]string `json:"contracts,omitempty"` // 合约名 → 地址，如 "store"、"token"
+}
+
+// File 是配置文件的结构，Networks 中的字段会覆盖同名内置网络的对应字段。
+type File struct {
+	Network  string              `json:"network,omitempty"` // 默认使用的网络
+	Networks map[string]*Profile `json:"networks"`
+}
+
+// Config 是解析完成后的最终配置。
+type Config struct {
+	Network      string // 当前使用的网络名称
+	AllowMainnet bool   // 是否允许在主网签名交易，见 guard 包
+	FeeStrategy  string // EIP-1559 手续费策略 slow / standard / fast，见 feeoracle 包
+	NonceFile    string // 本地 nonce 分配状态文件，见 nonce 包
+	IndexDir     string // 本地链数据索引目录，为空表示不使用索引，见 indexer 包
+
+	// 签名账户配置，见 signer 包
+	Keystore           string // keystore 文件路径
+	PassphraseFile     string // 保存 keystore 口令的文件
+	InsecurePrivateKey bool   // 允许使用 PRIVATE_KEY 明文私钥
+
+	Profile
+}
+
+// Flags 保存命令行覆盖项，由 RegisterFlags 注册到 FlagSet 上。
+type Flags struct {
+	ConfigFile string
+	Network    string
+	RPCURL     string
+	WSURL      string
+	ChainID    uint64
+	Contracts  contractFlags
+	// AllowMainnet 显式允许在主网签名交易
+	AllowMainnet bool
+	FeeStrategy  string
+	NonceFile    string
+	IndexDir     string
+
+	Keystore           string
+	PassphraseFile     string
+	InsecurePrivateKey bool
+}
+
+// contractFlags 支持多次传入 --contract name=0x...。
+type contractFlags map[string]string
+
+func (c contractFlags) String() string {
+	pairs := make([]string, 0, len(c))
+	for name, addr := range c {
+		pairs = append(pairs, name+"="+addr)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (c contractFlags) Set(v string) error {
+	name, addr, ok := strings.Cut(v, "=")
+	if !ok || name == "" {
+		return fmt.Errorf("expected name=address, got %q", v)
+	}
+	c[strings.ToLower(name)] = addr
+	return nil
+}
+
+// builtin 返回内置网络配置，每次调用都返回新副本。
+func builtin() map[string]*Profile {
+	return map[string]*Profile{
+		Mainnet: {
+			RPCURL:  "https://ethereum-rpc.publicnode.com",
+			WSURL:   "wss://ethereum-rpc.publicnode.com",
+			ChainID: 1,
+		},
+		Sepolia: {
+			RPCURL:  "https://ethereum-sepolia-rpc.publicnode.com",
+			WSURL:   "wss://ethereum-sepolia-rpc.publicnode.com",
+			ChainID: 11155111,
+			Contracts: map[string]string{
+				"store": "0x9F49FF297E88AD77120f0e261a76fa3A835c24Aa",
+				"token": "0xf8112b83f4ABA089Acf7E8fb77c480D6778d029d",
+			},
+		},
+		Dev: {
+			RPCURL:  "http://127.0.0.1:8545",
+			WSURL:   "ws://127.0.0.1:8546",
+			ChainID: 1337,
+		},
+	}
+}
+
+// RegisterFlags 在 fs 上注册配置相关的命令行参数。
+func RegisterFlags(fs *flag.FlagSet) *Flags {
+	f := &Flags{Contracts: contractFlags{}}
+	fs.StringVar(&f.ConfigFile, "config", "", "network config file (default "+DefaultFile+" if present, or $CONFIG_FILE)")
+	fs.StringVar(&f.Network, "network", "", "network profile: mainnet, sepolia, dev or one defined in the config file (default sepolia, or $NETWORK)")
+	fs.StringVar(&f.RPCURL, "rpc", "", "override the profile RPC endpoint ($RPC_URL)")
+	fs.StringVar(&f.WSURL, "ws", "", "override the profile WebSocket endpoint ($WS_URL)")
+	fs.Uint64Var(&f.ChainID, "chain-id", 0, "override the profile chain ID ($CHAIN_ID)")
+	fs.BoolVar(&f.AllowMainnet, "allow-mainnet", false, "allow signing transactions on mainnet ($ALLOW_MAINNET)")
+	fs.StringVar(&f.FeeStrategy, "fee-strategy", "", "EIP-1559 fee strategy: slow, standard or fast (default standard, or $FEE_STRATEGY)")
+	fs.StringVar(&f.NonceFile, "nonce-file", "", "file persisting locally assigned nonces (default "+DefaultNonceFile+", or $NONCE_FILE)")
+	fs.StringVar(&f.IndexDir, "index", "", "local chain index directory answered before the node ($INDEX_DIR)")
+	fs.StringVar(&f.Keystore, "keystore", "", "keystore file of the signing account ($KEYSTORE)")
+	fs.StringVar(&f.PassphraseFile, "passphrase-file", "", "file containing the keystore passphrase ($KEYSTORE_PASSPHRASE_FILE; default $KEYSTORE_PASSPHRASE or prompt)")
+	fs.BoolVar(&f.InsecurePrivateKey, "insecure-private-key", false, "sign with the plaintext hex key in $PRIVATE_KEY instead of a keystore")
+	fs.Var(f.Contracts, "contract", "override a contract address as name=0x... (repeatable, $<NAME>_CONTRACT_ADDRESS)")
+	return f
+}
+
+// FromCommandLine 在 flag.CommandLine 上注册配置参数、解析命令行并加载配置。
+// 章节自己的参数需在调用前（通常是包级变量）注册到 flag.CommandLine。
+func FromCommandLine() (*Config, error) {
+	f := RegisterFlags(flag.CommandLine)
+	flag.Parse()
+	return Load(f)
+}
+
+// Load 按 内置配置 → 配置文件 → 环境变量（含 .env）→ 命令行参数 的顺序加载配置。
+// f 为 nil 时忽略命令行参数。
+func Load(f *Flags) (*Config, error) {
+	const op = "config.Load"
+	if f == nil {
+		f = &Flags{}
+	}
+
+	// .env 文件是可选的，不存在时只使用进程环境变量
+	if err := godotenv.Load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, errs.Wrap(op, err)
+	}
+
+	profiles := builtin()
+	network := Sepolia
+
+	// 1. 配置文件
+	path, explicit := f.ConfigFile, f.ConfigFile != ""
+	if !explicit {
+		path, explicit = os.Getenv("CONFIG_FILE"), os.Getenv("CONFIG_FILE") != ""
+	}
+	if !explicit {
+		path = DefaultFile
+	}
+	file, err := readFile(path)
+	switch {
+	case err == nil:
+		if file.Network != "" {
+			network = file.Network
+		}
+		for name, p := range file.Networks {
+			merge(profiles, name, p)
+		}
+	case errors.Is(err, os.ErrNotExist) && !explicit:
+		// 默认配置文件不存在时直接使用内置配置
+	default:
+		return nil, errs.Wrap(op, err)
+	}
+
+	// 2. 环境变量
+	if v := os.Getenv("NETWORK"); v != "" {
+		network = v
+	}
+	// 3. 命令行参数
+	if f.Network != "" {
+		network = f.Network
+	}
+
+	base, ok := profiles[network]
+	if !ok {
+		return nil, errs.Invalid(op, "unknown network "+strconv.Quote(network))
+	}
+	cfg := &Config{Network: network, NonceFile: DefaultNonceFile, Profile: *base}
+	cfg.Contracts = make(map[string]string, len(base.Contracts))
+	for name, addr := range base.Contracts {
+		cfg.Contracts[name] = addr
+	}
+
+	// 环境变量覆盖所选网络的字段
+	if v := os.Getenv("RPC_URL"); v != "" {
+		cfg.RPCURL = v
+	}
+	if v := os.Getenv("WS_URL"); v != "" {
+		cfg.WSURL = v
+	}
+	if v := os.Getenv("CHAIN_ID"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return nil, errs.Invalid(op, "invalid CHAIN_ID "+strconv.Quote(v))
+		}
+		cfg.ChainID = id
+	}
+	if v := os.Getenv("ALLOW_MAINNET"); v != "" {
+		allow, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, errs.Invalid(op, "invalid ALLOW_MAINNET "+strconv.Quote(v))
+		}
+		cfg.AllowMainnet = allow
+	}
+	if v := os.Getenv("FEE_STRATEGY"); v != "" {
+		cfg.FeeStrategy = v
+	}
+	if v := os.Getenv("NONCE_FILE"); v != "" {
+		cfg.NonceFile = v
+	}
+	if v := os.Getenv("INDEX_DIR"); v != "" {
+		cfg.IndexDir = v
+	}
+	if v := os.Getenv("KEYSTORE"); v != "" {
+		cfg.Keystore = v
+	}
+	if v := os.Getenv("KEYSTORE_PASSPHRASE_FILE"); v != "" {
+		cfg.PassphraseFile = v
+	}
+	for _, kv := range os.Environ() {
+		key, val, _ := strings.Cut(kv, "=")
+		if name, ok := strings.CutSuffix(key, "_CONTRACT_ADDRESS"); ok && name != "" && val != "" {
+			cfg.Contracts[strings.ToLower(name)] = val
+		}
+	}
+
+	// 命令行参数覆盖所选网络的字段
+	if f.RPCURL != "" {
+		cfg.RPCURL = f.RPCURL
+	}
+	if f.WSURL != "" {
+		cfg.WSURL = f.WSURL
+	}
+	if f.ChainID != 0 {
+		cfg.ChainID = f.ChainID
+	}
+	for name, addr := range f.Contracts {
+		cfg.Contracts[name] = addr
+	}
+	if f.AllowMainnet {
+		cfg.AllowMainnet = true
+	}
+	if f.FeeStrategy != "" {
+		cfg.FeeStrategy = f.FeeStrategy
+	}
+	if f.NonceFile != "" {
+		cfg.NonceFile = f.NonceFile
+	}
+	if f.IndexDir != "" {
+		cfg.IndexDir = f.IndexDir
+	}
+	if f.Keystore != "" {
+		cfg.Keystore = f.Keystore
+	}
+	if f.PassphraseFile != "" {
+		cfg.PassphraseFile = f.PassphraseFile
+	}
+	// 明文私钥只能通过命令行显式开启
+	cfg.InsecurePrivateKey = f.InsecurePrivateKey
+
+	if cfg.RPCURL == "" {
+		return nil, errs.Invalid(op, "network "+strconv.Quote(network)+" has no RPC endpoint")
+	}
+	if cfg.ChainID == 0 {
+		return nil, errs.Invalid(op, "network "+strconv.Quote(network)+" has no chain ID")
+	}
+	return cfg, nil
+}
+
+// readFile 读取并解析 JSON 配置文件。
+func readFile(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file File
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return &file, nil
+}
+
+// merge 把配置文件中的网络配置合并进 profiles，非空字段覆盖内置值。
+func merge(profiles map[string]*Profile, name string, p *Profile) {
+	if p == nil {
+		return
+	}
+	dst, ok := profiles[name]
+	if !ok {
+		dst = &Profile{}
+		profiles[name] = dst
+	}
+	if p.RPCURL != "" {
+		dst.RPCURL = p.RPCURL
+	}
+	if p.WSURL != "" {
+		dst.WSURL = p.WSURL
+	}
+	if p.ChainID != 0 {
+		dst.ChainID = p.ChainID
+	}
+	if len(p.Contracts) > 0 && dst.Contracts == nil {
+		dst.Contracts = make(map[string]string, len(p.Contracts))
+	}
+	for k, v := range p.Contracts {
+		dst.Contracts[strings.ToLower(k)] = v
+	}
+}
+
+// ChainIDBig 以 *big.Int 形式返回配置的链 ID。
+func (c *Config) ChainIDBig() *big.Int {
+	return new(big.Int).SetUint64(c.ChainID)
+}
+
+// Contract 返回名为 name 的合约地址，未配置或地址格式错误时返回 errs.ErrInvalidArgument。
+func (c *Config) Contract(name string) (common.Address, error) {
+	const op = "config.Contract"
+
+	addr, ok := c.Contracts[strings.ToLower(name)]
+	if !ok {
+		return common.Address{}, errs.Invalid(op, "no "+strconv.Quote(name)+" contract configured for network "+strconv.Quote(c.Network))
+	}
+	if !common.IsHexAddress(addr) {
+		return common.Address{}, errs.Invalid(op, "invalid "+strconv.Quote(name)+" contract address "+strconv.Quote(addr))
+	}
+	return common.HexToAddress(addr), nil
+}
+
+// Dial 连接配置的 HTTP(S) RPC 节点。
+func (c *Config) Dial(ctx context.Context) (*ethclient.Client, error) {
+	client, err := ethclient.DialContext(ctx, c.RPCURL)
+	if err != nil {
+		return nil, errs.Wrap("config.Dial", err)
+	}
+	return client, nil
+}
+
+// DialWS 连接配置的 WebSocket 节点（订阅需要），未配置时退回 RPC 地址。
+func (c *Config) DialWS(ctx context.Context) (*ethclient.Client, error) {
+	url := c.WSURL
+	if url == "" {
+		url = c.RPCURL
+	}
+	client, err := ethclient.DialContext(ctx, url)
+	if err != nil {
+		return nil, errs.Wrap("config.DialWS", err)
+	}
+	return client, nil
+}
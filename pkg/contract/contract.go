@@ -0,0 +1,102 @@
+// Package contract 封装对已部署 Store 合约的读写调用（对应 11_contract_loading、12_contract_run）。
+// 读取提供两种实现：基于 abigen 绑定（GetItem）和直接使用 ABI 编码（GetItemABI）；
+// 写入（SetItem/SetItemABI）都用绑定中的 ABI 编码调用数据，经 txbuilder 发送，区别只在交易类型和 gas。
+package contract
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ydh2333/dapp_stu/10_contract_deploy/store"
+	"github.com/ydh2333/dapp_stu/pkg/errs"
+	"github.com/ydh2333/dapp_stu/pkg/guard"
+	"github.com/ydh2333/dapp_stu/pkg/signer"
+	"github.com/ydh2333/dapp_stu/pkg/txbuilder"
+)
+
+// setItemGasLimit 是直接发送 setItem 交易时使用的 gas 上限。
+const setItemGasLimit = 300000
+
+// Key32 把字符串复制到 32 字节数组中，适配合约 bytes32 参数类型，超出部分被截断。
+func Key32(s string) [32]byte {
+	var b [32]byte
+	copy(b[:], s)
+	return b
+}
+
+// LoadStore 加载已部署在 address 上的 Store 合约。
+func LoadStore(backend bind.ContractBackend, address common.Address) (*store.Store, error) {
+	instance, err := store.NewStore(address, backend)
+	if err != nil {
+		return nil, errs.Wrap("contract.LoadStore", err)
+	}
+	return instance, nil
+}
+
+// SetItem 调用 Store.setItem 写入 key/value，返回已发送的交易。调用数据用绑定中的 ABI 编码，
+// 交易经 txbuilder 构造：类型按链是否支持 EIP-1559 自动选择，gas 上限取估算值的 120%。
+func SetItem(ctx context.Context, backend bind.ContractBackend, g *guard.Guard, s signer.Signer, address common.Address, k, v [32]byte) (*types.Transaction, error) {
+	return setItem(ctx, "contract.SetItem", backend, g, s, address, k, v, txbuilder.Spec{Mode: txbuilder.Auto})
+}
+
+// GetItem 通过 abigen 绑定读取 Store.items(key)，只读调用不消耗 gas。
+func GetItem(ctx context.Context, backend bind.ContractBackend, address common.Address, k [32]byte) ([32]byte, error) {
+	instance, err := LoadStore(backend, address)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	v, err := instance.Items(&bind.CallOpts{Context: ctx}, k)
+	if err != nil {
+		return [32]byte{}, errs.Wrap("contract.GetItem", err)
+	}
+	return v, nil
+}
+
+// SetItemABI 与 SetItem 相同，但固定发送传统交易，gas 上限为 setItemGasLimit。
+func SetItemABI(ctx context.Context, backend bind.ContractBackend, g *guard.Guard, s signer.Signer, address common.Address, k, v [32]byte) (*types.Transaction, error) {
+	return setItem(ctx, "contract.SetItemABI", backend, g, s, address, k, v, txbuilder.Spec{Mode: txbuilder.Legacy, Gas: setItemGasLimit})
+}
+
+// setItem 用 ABI 编码 setItem 调用数据，按 spec 的交易类型和 gas 经 txbuilder 发送。
+func setItem(ctx context.Context, op string, backend bind.ContractBackend, g *guard.Guard, s signer.Signer, address common.Address, k, v [32]byte, spec txbuilder.Spec) (*types.Transaction, error) {
+	parsed, err := store.StoreMetaData.GetAbi()
+	if err != nil {
+		return nil, errs.Wrap(op, err)
+	}
+	input, err := parsed.Pack("setItem", k, v)
+	if err != nil {
+		return nil, errs.Wrap(op, err)
+	}
+	spec.To, spec.Data = &address, input
+	signedTx, err := txbuilder.New(backend, g).Send(ctx, s, spec)
+	if err != nil {
+		return nil, errs.Wrap(op, err)
+	}
+	return signedTx, nil
+}
+
+// GetItemABI 用 ABI 编码 items(key) 调用数据，通过 eth_call 读取并解码返回值。
+func GetItemABI(ctx context.Context, backend bind.ContractCaller, address common.Address, k [32]byte) ([32]byte, error) {
+	const op = "contract.GetItemABI"
+
+	parsed, err := store.StoreMetaData.GetAbi()
+	if err != nil {
+		return [32]byte{}, errs.Wrap(op, err)
+	}
+	callInput, err := parsed.Pack("items", k)
+	if err != nil {
+		return [32]byte{}, errs.Wrap(op, err)
+	}
+	result, err := backend.CallContract(ctx, ethereum.CallMsg{To: &address, Data: callInput}, nil)
+	if err != nil {
+		return [32]byte{}, errs.Wrap(op, err)
+	}
+	var unpacked [32]byte
+	if err := parsed.UnpackIntoInterface(&unpacked, "items", result); err != nil {
+		return [32]byte{}, errs.Wrap(op, err)
+	}
+	return unpacked, nil
+}
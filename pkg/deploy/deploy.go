@@ -0,0 +1,89 @@
+// Package deploy 封装 Store 合约的部署（对应 10_contract_deploy）。
+package deploy
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ydh2333/dapp_stu/10_contract_deploy/store"
+	"github.com/ydh2333/dapp_stu/pkg/errs"
+	"github.com/ydh2333/dapp_stu/pkg/feeoracle"
+	"github.com/ydh2333/dapp_stu/pkg/guard"
+	"github.com/ydh2333/dapp_stu/pkg/signer"
+	"github.com/ydh2333/dapp_stu/pkg/txbuilder"
+)
+
+// storeGasLimit 是部署 Store 合约时使用的 gas 上限。
+const storeGasLimit = 500000
+
+// Backend 是部署合约所需的节点能力：abigen 绑定所需的 bind.ContractBackend 加上手续费历史查询。
+type Backend interface {
+	bind.ContractBackend
+	ethereum.FeeHistoryReader
+}
+
+// DeployStore 部署 Store 合约，version 为构造函数参数：用绑定中的 ABI 编码构造参数，
+// 拼在绑定中的字节码之后，经 txbuilder 构造、签名并发送 EIP-1559 交易，手续费由 feeoracle 按 strategy 估算。
+// 返回的合约地址在交易上链前即可确定（由部署者地址和 nonce 计算得出）。
+func DeployStore(ctx context.Context, backend Backend, g *guard.Guard, s signer.Signer, version string, strategy feeoracle.Strategy) (common.Address, *types.Transaction, *store.Store, error) {
+	const op = "deploy.DeployStore"
+
+	parsed, err := store.StoreMetaData.GetAbi()
+	if err != nil {
+		return common.Address{}, nil, nil, errs.Wrap(op, err)
+	}
+	input, err := parsed.Pack("", version)
+	if err != nil {
+		return common.Address{}, nil, nil, errs.Wrap(op, err)
+	}
+	fees, err := feeoracle.New(backend).Suggest(ctx, strategy)
+	if err != nil {
+		return common.Address{}, nil, nil, errs.Wrap(op, err)
+	}
+	signedTx, err := txbuilder.New(backend, g).Send(ctx, s, txbuilder.Spec{
+		Data:      append(common.FromHex(store.StoreMetaData.Bin), input...),
+		Mode:      txbuilder.DynamicFee,
+		Gas:       storeGasLimit,
+		GasTipCap: fees.GasTipCap,
+		GasFeeCap: fees.GasFeeCap,
+	})
+	if err != nil {
+		return common.Address{}, nil, nil, errs.Wrap(op, err)
+	}
+	address := crypto.CreateAddress(s.Address(), signedTx.Nonce())
+	instance, err := store.NewStore(address, backend)
+	if err != nil {
+		return common.Address{}, nil, nil, errs.Wrap(op, err)
+	}
+	return address, signedTx, instance, nil
+}
+
+// DeployBytecode 直接发送带合约字节码（含已编码的构造参数）的 EIP-1559 交易来部署合约。
+// gas 上限取估算值的 120%，手续费由 feeoracle 按 strategy 估算。
+func DeployBytecode(ctx context.Context, backend Backend, g *guard.Guard, s signer.Signer, bytecode []byte, strategy feeoracle.Strategy) (*types.Transaction, error) {
+	const op = "deploy.DeployBytecode"
+
+	if len(bytecode) == 0 {
+		return nil, errs.Invalid(op, "empty bytecode")
+	}
+	fees, err := feeoracle.New(backend).Suggest(ctx, strategy)
+	if err != nil {
+		return nil, errs.Wrap(op, err)
+	}
+
+	// 合约部署本质是向空地址发送带字节码的交易，若字节码无效估算 gas 这一步会直接报错
+	signedTx, err := txbuilder.New(backend, g).Send(ctx, s, txbuilder.Spec{
+		Data:      bytecode,
+		Mode:      txbuilder.DynamicFee,
+		GasTipCap: fees.GasTipCap,
+		GasFeeCap: fees.GasFeeCap,
+	})
+	if err != nil {
+		return nil, errs.Wrap(op, err)
+	}
+	return signedTx, nil
+}
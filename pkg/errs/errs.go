@@ -0,0 +1,58 @@
+// Package errs 定义各个库包共用的错误类型，调用方可以用 errors.Is / errors.As 判断错误原因，
+// 而不必像示例 main 程序那样直接 log.Fatal。
+package errs
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum"
+)
+
+var (
+	// ErrNotFound 表示节点上查不到对应的区块、交易或回执。
+	// 直接复用 ethereum.NotFound，这样 errors.Is(err, ethereum.NotFound) 依然成立。
+	ErrNotFound = ethereum.NotFound
+	// ErrInvalidArgument 表示调用方传入的参数不合法（如金额格式错误、地址为空）。
+	ErrInvalidArgument = errors.New("invalid argument")
+	// ErrTxFailed 表示交易已上链但执行失败（receipt.Status == 0）。
+	ErrTxFailed = errors.New("transaction failed")
+)
+
+// Error 记录出错的操作名以及底层错误。
+type Error struct {
+	Op  string // 出错的操作，如 "block.QueryBlock"
+	Err error  // 底层错误
+}
+
+func (e *Error) Error() string {
+	return e.Op + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Wrap 用操作名包装 err，err 为 nil 时返回 nil。
+func Wrap(op string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Op: op, Err: err}
+}
+
+// Invalid 构造一个 ErrInvalidArgument 错误，msg 说明具体哪个参数不合法。
+func Invalid(op, msg string) error {
+	return &Error{Op: op, Err: &argError{msg: msg}}
+}
+
+type argError struct {
+	msg string
+}
+
+func (e *argError) Error() string {
+	return ErrInvalidArgument.Error() + ": " + e.msg
+}
+
+func (e *argError) Is(target error) bool {
+	return target == ErrInvalidArgument
+}
@@ -0,0 +1,198 @@
+// Package feeoracle 为 EIP-1559 交易估算手续费：优先费取 eth_feeHistory 最近若干区块的
+// 奖励分位数，base fee 用 EIP-1559 公式从最新区块头推算下一个区块的值，
+// 再按 slow / standard / fast 策略留出 base fee 上涨的余量得到 maxFeePerGas。
+package feeoracle
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"slices"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/consensus/misc/eip1559"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ydh2333/dapp_stu/pkg/errs"
+)
+
+// Strategy 是手续费策略。
+type Strategy int
+
+const (
+	Slow Strategy = iota
+	Standard
+	Fast
+)
+
+// strategyParams 是各策略使用的奖励分位数，以及 maxFeePerGas 需要覆盖的 base fee 连续上涨区块数：
+// 每个满块 base fee 最多上涨 12.5%，standard 的 6 个区块约等于常见的 2 倍 base fee。
+var strategyParams = map[Strategy]struct {
+	name       string
+	percentile float64
+	headroom   int
+}{
+	Slow:     {"slow", 10, 2},
+	Standard: {"standard", 50, 6},
+	Fast:     {"fast", 90, 10},
+}
+
+// strategies 按 feeHistory 请求分位数的顺序排列。
+var strategies = []Strategy{Slow, Standard, Fast}
+
+func (s Strategy) String() string {
+	if p, ok := strategyParams[s]; ok {
+		return p.name
+	}
+	return fmt.Sprintf("Strategy(%d)", int(s))
+}
+
+// ParseStrategy 解析 slow / standard / fast，空字符串视为 standard。
+func ParseStrategy(s string) (Strategy, error) {
+	if s == "" {
+		return Standard, nil
+	}
+	for _, strategy := range strategies {
+		if strategy.String() == s {
+			return strategy, nil
+		}
+	}
+	return Standard, errs.Invalid("feeoracle.ParseStrategy", "unknown fee strategy "+s+" (want slow, standard or fast)")
+}
+
+// Backend 是估算手续费所需的节点能力，*ethclient.Client 和模拟链客户端都满足该接口。
+type Backend interface {
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	ethereum.FeeHistoryReader
+	ethereum.GasPricer1559
+}
+
+// defaultBlocks 是默认参考的历史区块数。
+const defaultBlocks = 20
+
+// Oracle 根据最近的区块估算手续费。
+type Oracle struct {
+	backend Backend
+	blocks  uint64
+}
+
+// New 创建参考最近 20 个区块的 Oracle。
+func New(backend Backend) *Oracle {
+	return &Oracle{backend: backend, blocks: defaultBlocks}
+}
+
+// Fees 是某个策略下建议的手续费。
+type Fees struct {
+	Strategy    Strategy
+	BaseFee     *big.Int // 最新区块的 base fee
+	NextBaseFee *big.Int // 按 EIP-1559 公式推算的下一个区块 base fee
+	GasTipCap   *big.Int // maxPriorityFeePerGas
+	GasFeeCap   *big.Int // maxFeePerGas
+}
+
+// Estimate 一次查询得到全部三种策略的建议手续费。
+func (o *Oracle) Estimate(ctx context.Context) (map[Strategy]*Fees, error) {
+	const op = "feeoracle.Estimate"
+
+	head, err := o.backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, errs.Wrap(op, err)
+	}
+	if head.BaseFee == nil {
+		return nil, errs.Wrap(op, fmt.Errorf("%w: block %v has no base fee (EIP-1559 not active)", errs.ErrInvalidArgument, head.Number))
+	}
+	percentiles := make([]float64, len(strategies))
+	for i, s := range strategies {
+		percentiles[i] = strategyParams[s].percentile
+	}
+	history, err := o.backend.FeeHistory(ctx, o.blocks, head.Number, percentiles)
+	if err != nil {
+		return nil, errs.Wrap(op, err)
+	}
+	next := NextBaseFee(head)
+
+	result := make(map[Strategy]*Fees, len(strategies))
+	for i, s := range strategies {
+		tip := medianReward(history, i)
+		if tip == nil {
+			// 最近的区块都是空块，没有可参考的奖励，退回节点建议的优先费
+			if tip, err = o.backend.SuggestGasTipCap(ctx); err != nil {
+				return nil, errs.Wrap(op, err)
+			}
+		}
+		feeCap := MaxBaseFee(next, strategyParams[s].headroom)
+		feeCap.Add(feeCap, tip)
+		result[s] = &Fees{
+			Strategy:    s,
+			BaseFee:     new(big.Int).Set(head.BaseFee),
+			NextBaseFee: next,
+			GasTipCap:   tip,
+			GasFeeCap:   feeCap,
+		}
+	}
+	// 分位数越高优先费越高，但各自取中位数后可能出现倒挂，这里保证 slow <= standard <= fast
+	for i := 1; i < len(strategies); i++ {
+		prev, cur := result[strategies[i-1]], result[strategies[i]]
+		if cur.GasTipCap.Cmp(prev.GasTipCap) < 0 {
+			diff := new(big.Int).Sub(prev.GasTipCap, cur.GasTipCap)
+			cur.GasTipCap = new(big.Int).Set(prev.GasTipCap)
+			cur.GasFeeCap = new(big.Int).Add(cur.GasFeeCap, diff)
+		}
+	}
+	return result, nil
+}
+
+// Suggest 返回策略 s 的建议手续费。
+func (o *Oracle) Suggest(ctx context.Context, s Strategy) (*Fees, error) {
+	if _, ok := strategyParams[s]; !ok {
+		return nil, errs.Invalid("feeoracle.Suggest", "unknown fee strategy "+s.String())
+	}
+	all, err := o.Estimate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return all[s], nil
+}
+
+// NextBaseFee 按 EIP-1559 公式由父区块推算下一个区块的 base fee：
+// gas 使用量高于目标值（gasLimit / 2）时上涨，低于目标值时下降，单个区块最多变化 1/8。
+func NextBaseFee(parent *types.Header) *big.Int {
+	if parent.GasLimit < params.DefaultElasticityMultiplier {
+		// 目标值为 0，eip1559.CalcBaseFee 会除以 0
+		return new(big.Int).Set(parent.BaseFee)
+	}
+	// 只要 London 已激活，各公链的公式参数相同，这里用所有分叉都从创世区块激活的配置
+	return new(big.Int).Set(eip1559.CalcBaseFee(params.AllDevChainProtocolChanges, parent))
+}
+
+// MaxBaseFee 返回从 baseFee 开始连续 blocks 个满块后 base fee 的上限：
+// 每块上涨 12.5%，且至少上涨 1 wei。
+func MaxBaseFee(baseFee *big.Int, blocks int) *big.Int {
+	fee := new(big.Int).Set(baseFee)
+	for i := 0; i < blocks; i++ {
+		delta := new(big.Int).Div(fee, big.NewInt(params.DefaultBaseFeeChangeDenominator))
+		if delta.Sign() == 0 {
+			delta.SetInt64(1)
+		}
+		fee.Add(fee, delta)
+	}
+	return fee
+}
+
+// medianReward 返回各区块第 i 个分位数奖励的中位数，忽略没有交易的空块；没有样本时返回 nil。
+func medianReward(history *ethereum.FeeHistory, i int) *big.Int {
+	var samples []*big.Int
+	for block, rewards := range history.Reward {
+		if block < len(history.GasUsedRatio) && history.GasUsedRatio[block] == 0 {
+			continue
+		}
+		if i < len(rewards) && rewards[i] != nil {
+			samples = append(samples, rewards[i])
+		}
+	}
+	if len(samples) == 0 {
+		return nil
+	}
+	slices.SortFunc(samples, (*big.Int).Cmp)
+	return new(big.Int).Set(samples[len(samples)/2])
+}
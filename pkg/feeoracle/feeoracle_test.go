@@ -0,0 +1,41 @@
+package feeoracle
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/consensus/misc/eip1559"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// 与 go-ethereum 校验区块头时使用的公式比较，小 base fee 时的取整最容易出错。
+func TestNextBaseFee(t *testing.T) {
+	const gasLimit = 30_000_000
+	london := params.MainnetChainConfig.LondonBlock
+	for _, baseFee := range []int64{0, 1, 7, 8, 9, 15, 16, 1_000_000_007, 30_000_000_000} {
+		for _, gasUsed := range []uint64{0, 1, gasLimit / 4, gasLimit/2 - 1, gasLimit / 2, gasLimit/2 + 1, gasLimit - 1, gasLimit} {
+			parent := &types.Header{Number: london, GasLimit: gasLimit, GasUsed: gasUsed, BaseFee: big.NewInt(baseFee)}
+			want := eip1559.CalcBaseFee(params.MainnetChainConfig, parent)
+			if got := NextBaseFee(parent); got.Cmp(want) != 0 {
+				t.Errorf("base fee %d, gas used %d: got %v, want %v", baseFee, gasUsed, got, want)
+			}
+		}
+	}
+}
+
+// MaxBaseFee 应等于连续满块后的 base fee。
+func TestMaxBaseFee(t *testing.T) {
+	const gasLimit = 30_000_000
+	london := params.MainnetChainConfig.LondonBlock
+	for _, baseFee := range []int64{0, 1, 7, 8, 9, 100, 1_000_000_007} {
+		want := big.NewInt(baseFee)
+		for blocks := 0; blocks <= 8; blocks++ {
+			if got := MaxBaseFee(big.NewInt(baseFee), blocks); got.Cmp(want) != 0 {
+				t.Errorf("base fee %d after %d full blocks: got %v, want %v", baseFee, blocks, got, want)
+			}
+			parent := &types.Header{Number: london, GasLimit: gasLimit, GasUsed: gasLimit, BaseFee: want}
+			want = eip1559.CalcBaseFee(params.MainnetChainConfig, parent)
+		}
+	}
+}
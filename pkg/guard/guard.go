@@ -0,0 +1,109 @@
+// Package guard 是发送交易前的签名守卫：签名前先用 eth_chainId 确认所连节点就是配置中的目标链，
+// 链 ID 不一致时拒绝签名；主网需显式开启（--allow-mainnet 或 ALLOW_MAINNET=true）才允许签名。
+// 仓库中所有发送交易的路径都通过 Guard 签名。
+package guard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ydh2333/dapp_stu/pkg/config"
+	"github.com/ydh2333/dapp_stu/pkg/errs"
+	"github.com/ydh2333/dapp_stu/pkg/signer"
+)
+
+var (
+	// ErrChainIDMismatch 表示节点返回的 eth_chainId 与配置的链 ID 不一致。
+	ErrChainIDMismatch = errors.New("chain ID mismatch")
+	// ErrMainnetDisabled 表示目标是主网但未显式允许在主网签名。
+	ErrMainnetDisabled = errors.New("signing on mainnet is disabled")
+)
+
+// mainnetChainID 是以太坊主网的链 ID。
+var mainnetChainID = big.NewInt(1)
+
+// Guard 保存经过节点确认的链 ID，并用对应的签名器为交易签名。
+type Guard struct {
+	client  ethereum.ChainIDReader
+	chainID *big.Int
+	signer  types.Signer
+}
+
+// New 查询节点的 eth_chainId 并与 expected 比较，一致时返回 Guard。
+// expected 为主网且 allowMainnet 为 false 时返回 ErrMainnetDisabled。
+func New(ctx context.Context, client ethereum.ChainIDReader, expected *big.Int, allowMainnet bool) (*Guard, error) {
+	const op = "guard.New"
+
+	if expected == nil || expected.Sign() <= 0 {
+		return nil, errs.Invalid(op, "expected chain ID must be positive")
+	}
+	if expected.Cmp(mainnetChainID) == 0 && !allowMainnet {
+		return nil, errs.Wrap(op, ErrMainnetDisabled)
+	}
+	g := &Guard{
+		client:  client,
+		chainID: new(big.Int).Set(expected),
+		signer:  types.LatestSignerForChainID(expected),
+	}
+	if err := g.Verify(ctx); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// FromConfig 按 cfg 中的链 ID 和主网开关创建 Guard。
+func FromConfig(ctx context.Context, client ethereum.ChainIDReader, cfg *config.Config) (*Guard, error) {
+	return New(ctx, client, cfg.ChainIDBig(), cfg.AllowMainnet)
+}
+
+// Verify 重新查询节点的 eth_chainId，确认仍与配置的链 ID 一致。
+func (g *Guard) Verify(ctx context.Context) error {
+	const op = "guard.Verify"
+
+	actual, err := g.client.ChainID(ctx)
+	if err != nil {
+		return errs.Wrap(op, err)
+	}
+	if actual.Cmp(g.chainID) != 0 {
+		return errs.Wrap(op, fmt.Errorf("%w: node reports %v, profile expects %v", ErrChainIDMismatch, actual, g.chainID))
+	}
+	return nil
+}
+
+// ChainID 返回已确认的链 ID。
+func (g *Guard) ChainID() *big.Int {
+	return new(big.Int).Set(g.chainID)
+}
+
+// Signer 返回该链上支持所有交易类型的签名器。
+func (g *Guard) Signer() types.Signer {
+	return g.signer
+}
+
+// SignTx 用 s 为 tx 签名。交易自带链 ID（EIP-2930 及之后的类型）且与 Guard 不一致时拒绝签名；
+// 未填写链 ID 的交易由签名器按 Guard 的链 ID 签名。
+func (g *Guard) SignTx(tx *types.Transaction, s signer.Signer) (*types.Transaction, error) {
+	return g.SignTxWith(tx, s, g.signer)
+}
+
+// SignTxWith 与 SignTx 相同，但使用调用方按分叉规则选定的签名器（见 txbuilder），
+// 签名器的链 ID 同样必须与 Guard 一致。
+func (g *Guard) SignTxWith(tx *types.Transaction, s signer.Signer, txSigner types.Signer) (*types.Transaction, error) {
+	const op = "guard.SignTx"
+
+	if id := txSigner.ChainID(); id.Cmp(g.chainID) != 0 {
+		return nil, errs.Wrap(op, fmt.Errorf("%w: signer has chain ID %v, profile expects %v", ErrChainIDMismatch, id, g.chainID))
+	}
+	if id := tx.ChainId(); tx.Type() != types.LegacyTxType && id.Sign() != 0 && id.Cmp(g.chainID) != 0 {
+		return nil, errs.Wrap(op, fmt.Errorf("%w: transaction has chain ID %v, profile expects %v", ErrChainIDMismatch, id, g.chainID))
+	}
+	signedTx, err := s.SignTx(tx, txSigner)
+	if err != nil {
+		return nil, errs.Wrap(op, err)
+	}
+	return signedTx, nil
+}
@@ -0,0 +1,186 @@
+// Package hdwallet 实现 BIP-39 助记词与 BIP-32/BIP-44 分层确定性钱包，
+// 按 m/44'/60'/0'/0/i 路径派生以太坊账户，用同一组助记词可以反复恢复出相同的测试钱包。
+package hdwallet
+
+import (
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/tyler-smith/go-bip39"
+	"github.com/ydh2333/dapp_stu/pkg/errs"
+	"github.com/ydh2333/dapp_stu/pkg/keys"
+	"github.com/ydh2333/dapp_stu/pkg/signer"
+)
+
+// ErrInvalidMnemonic 表示助记词不在 BIP-39 词表中或校验和错误。
+var ErrInvalidMnemonic = errors.New("invalid mnemonic")
+
+// hardened 是 BIP-32 强化派生索引的起点（2^31）。
+const hardened = 0x80000000
+
+// NewMnemonic 生成新的 BIP-39 英文助记词，bits 为熵的位数：128 → 12 个词，256 → 24 个词。
+func NewMnemonic(bits int) (string, error) {
+	const op = "hdwallet.NewMnemonic"
+
+	entropy, err := bip39.NewEntropy(bits)
+	if err != nil {
+		return "", errs.Invalid(op, err.Error())
+	}
+	mnemonic, err := bip39.NewMnemonic(entropy)
+	if err != nil {
+		return "", errs.Wrap(op, err)
+	}
+	return mnemonic, nil
+}
+
+// Wallet 是由助记词（及可选的 BIP-39 口令）恢复出的 HD 钱包。
+type Wallet struct {
+	mnemonic  string
+	masterKey *big.Int
+	chainCode []byte
+}
+
+// FromMnemonic 校验助记词并派生主私钥。passphrase 是 BIP-39 的第 25 个词，可以为空。
+func FromMnemonic(mnemonic, passphrase string) (*Wallet, error) {
+	const op = "hdwallet.FromMnemonic"
+
+	seed, err := bip39.NewSeedWithErrorChecking(mnemonic, passphrase)
+	if err != nil {
+		return nil, errs.Wrap(op, fmt.Errorf("%w: %v", ErrInvalidMnemonic, err))
+	}
+	w, err := fromSeed(seed)
+	if err != nil {
+		return nil, errs.Wrap(op, err)
+	}
+	w.mnemonic = mnemonic
+	return w, nil
+}
+
+// fromSeed 按 BIP-32 由种子计算主私钥和主链码：I = HMAC-SHA512("Bitcoin seed", seed)。
+func fromSeed(seed []byte) (*Wallet, error) {
+	mac := hmac.New(sha512.New, []byte("Bitcoin seed"))
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+
+	k := new(big.Int).SetBytes(sum[:32])
+	if k.Sign() == 0 || k.Cmp(crypto.S256().Params().N) >= 0 {
+		return nil, errors.New("invalid master key")
+	}
+	return &Wallet{masterKey: k, chainCode: sum[32:]}, nil
+}
+
+// Mnemonic 返回钱包的助记词。
+func (w *Wallet) Mnemonic() string {
+	return w.mnemonic
+}
+
+// Derive 沿 path 逐级派生子私钥（CKDpriv）。
+func (w *Wallet) Derive(path accounts.DerivationPath) (*ecdsa.PrivateKey, error) {
+	const op = "hdwallet.Derive"
+
+	k, c := w.masterKey, w.chainCode
+	for _, index := range path {
+		var err error
+		if k, c, err = deriveChild(k, c, index); err != nil {
+			return nil, errs.Wrap(op, fmt.Errorf("%s: %w", path, err))
+		}
+	}
+	key, err := crypto.ToECDSA(common.LeftPadBytes(k.Bytes(), 32))
+	if err != nil {
+		return nil, errs.Wrap(op, err)
+	}
+	return key, nil
+}
+
+// deriveChild 计算索引为 index 的子私钥：
+//   - 强化派生：data = 0x00 || ser256(k) || ser32(index)
+//   - 普通派生：data = serP(K) || ser32(index)，K 为压缩公钥
+//
+// I = HMAC-SHA512(c, data)，子私钥 = (IL + k) mod n，子链码 = IR。
+func deriveChild(k *big.Int, c []byte, index uint32) (*big.Int, []byte, error) {
+	n := crypto.S256().Params().N
+
+	var data []byte
+	if index >= hardened {
+		data = append([]byte{0}, common.LeftPadBytes(k.Bytes(), 32)...)
+	} else {
+		key, err := crypto.ToECDSA(common.LeftPadBytes(k.Bytes(), 32))
+		if err != nil {
+			return nil, nil, err
+		}
+		data = crypto.CompressPubkey(&key.PublicKey)
+	}
+	data = binary.BigEndian.AppendUint32(data, index)
+
+	mac := hmac.New(sha512.New, c)
+	mac.Write(data)
+	sum := mac.Sum(nil)
+
+	il := new(big.Int).SetBytes(sum[:32])
+	if il.Cmp(n) >= 0 {
+		return nil, nil, fmt.Errorf("index %d yields an invalid key, use the next index", index)
+	}
+	child := il.Add(il, k)
+	child.Mod(child, n)
+	if child.Sign() == 0 {
+		return nil, nil, fmt.Errorf("index %d yields an invalid key, use the next index", index)
+	}
+	return child, sum[32:], nil
+}
+
+// Account 是一个派生出的账户。
+type Account struct {
+	Path       accounts.DerivationPath
+	Address    common.Address
+	PrivateKey *ecdsa.PrivateKey
+}
+
+// Signer 返回可用于转账、部署等流程的签名账户。
+func (a *Account) Signer() signer.Signer {
+	return signer.FromKey(a.PrivateKey)
+}
+
+// Path 返回以太坊 BIP-44 路径 m/44'/60'/0'/0/index。
+func Path(index uint32) accounts.DerivationPath {
+	path := make(accounts.DerivationPath, len(accounts.DefaultBaseDerivationPath))
+	copy(path, accounts.DefaultBaseDerivationPath)
+	path[len(path)-1] = index
+	return path
+}
+
+// DeriveAccount 派生 path 对应的账户，并用官方方法和手动 Keccak 两种方式计算地址交叉校验。
+func (w *Wallet) DeriveAccount(path accounts.DerivationPath) (*Account, error) {
+	key, err := w.Derive(path)
+	if err != nil {
+		return nil, err
+	}
+	address := keys.Address(&key.PublicKey)
+	if manual := keys.KeccakAddress(&key.PublicKey); manual != address {
+		return nil, errs.Wrap("hdwallet.DeriveAccount", fmt.Errorf("address mismatch: %s != %s", manual, address))
+	}
+	return &Account{Path: path, Address: address, PrivateKey: key}, nil
+}
+
+// Accounts 派生 m/44'/60'/0'/0/0 起的前 n 个账户。
+func (w *Wallet) Accounts(n int) ([]*Account, error) {
+	if n < 0 {
+		return nil, errs.Invalid("hdwallet.Accounts", "negative account count")
+	}
+	list := make([]*Account, 0, n)
+	for i := 0; i < n; i++ {
+		account, err := w.DeriveAccount(Path(uint32(i)))
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, account)
+	}
+	return list, nil
+}
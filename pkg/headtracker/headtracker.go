@@ -0,0 +1,232 @@
+// Package headtracker 跟踪规范链的最新区块头：保存最近若干个区块头组成的窗口，
+// 逐个校验父哈希链接；新区块头无法接到当前链尾时沿父哈希回溯到共同祖先，
+// 产生明确的重组事件（被丢弃的区块和新加入的区块），供等待、索引、监听等模块回滚。
+package headtracker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ydh2333/dapp_stu/pkg/errs"
+)
+
+// DefaultWindow 是默认保留的区块头数量，重组深度超过窗口时无法定位共同祖先。
+const DefaultWindow = 128
+
+// ErrBrokenChain 表示节点返回的父区块与子区块的父哈希或高度对不上。
+var ErrBrokenChain = errors.New("header chain is not linked")
+
+// Event 是规范链的一次变化。Added 和 Dropped 都按高度升序排列。
+//   - 正常出块：Dropped 为空，Added 为新区块（断线后补齐时可能有多个）；
+//   - 重组：Dropped 为原规范链上被丢弃的区块，Added 为新分支上的区块，Ancestor 为共同祖先；
+//   - 重组深度超过窗口：Ancestor 为 nil，Dropped 为整个窗口。
+type Event struct {
+	Head     *types.Header // 事件之后的规范链头
+	Ancestor *types.Header // 新旧链的共同祖先，首个事件或深度重组时为 nil
+	Dropped  []*types.Header
+	Added    []*types.Header
+}
+
+// Reorg 报告该事件是否丢弃了已回报过的区块。
+func (e *Event) Reorg() bool {
+	return len(e.Dropped) > 0
+}
+
+// Depth 是重组深度，即被丢弃的区块数。
+func (e *Event) Depth() int {
+	return len(e.Dropped)
+}
+
+func (e *Event) String() string {
+	if !e.Reorg() {
+		return fmt.Sprintf("head %d %s (+%d)", e.Head.Number, e.Head.Hash().Hex(), len(e.Added))
+	}
+	if e.Ancestor == nil {
+		return fmt.Sprintf("reorg deeper than window: -%d +%d, head %d %s", len(e.Dropped), len(e.Added), e.Head.Number, e.Head.Hash().Hex())
+	}
+	return fmt.Sprintf("reorg at %d %s: -%d +%d, head %d %s", e.Ancestor.Number, e.Ancestor.Hash().Hex(), len(e.Dropped), len(e.Added), e.Head.Number, e.Head.Hash().Hex())
+}
+
+// Backend 是跟踪区块头所需的节点能力，*ethclient.Client 和模拟链客户端都满足该接口。
+type Backend interface {
+	HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error)
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error)
+}
+
+// Tracker 维护最近 window 个规范区块头，可被多个 goroutine 并发读取。
+type Tracker struct {
+	backend Backend
+	window  int
+
+	mu      sync.RWMutex
+	headers []*types.Header // 按高度升序且逐个链接的规范区块头
+	index   map[common.Hash]int
+}
+
+// New 创建区块头跟踪器，window <= 0 时使用 DefaultWindow。
+func New(backend Backend, window int) *Tracker {
+	if window <= 0 {
+		window = DefaultWindow
+	}
+	return &Tracker{backend: backend, window: window, index: make(map[common.Hash]int)}
+}
+
+// Head 返回当前规范链头，尚未处理过区块头时返回 nil。
+func (t *Tracker) Head() *types.Header {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if len(t.headers) == 0 {
+		return nil
+	}
+	return t.headers[len(t.headers)-1]
+}
+
+// Headers 返回窗口内的规范区块头副本，按高度升序。
+func (t *Tracker) Headers() []*types.Header {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return append([]*types.Header(nil), t.headers...)
+}
+
+// Canonical 返回窗口内 number 高度的规范区块头。
+func (t *Tracker) Canonical(number uint64) (*types.Header, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if len(t.headers) == 0 {
+		return nil, false
+	}
+	first := t.headers[0].Number.Uint64()
+	if number < first || number-first >= uint64(len(t.headers)) {
+		return nil, false
+	}
+	return t.headers[number-first], true
+}
+
+// Contains 报告 hash 是否是窗口内的规范区块。
+func (t *Tracker) Contains(hash common.Hash) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	_, ok := t.index[hash]
+	return ok
+}
+
+// Process 把一个新区块头接入规范链并返回对应事件；区块头已是当前链头时返回 nil。
+// 无法直接接到链尾时沿父哈希向节点查询父区块，直到找到窗口内的共同祖先。
+func (t *Tracker) Process(ctx context.Context, header *types.Header) (*Event, error) {
+	const op = "headtracker.Process"
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.headers) == 0 {
+		t.reset([]*types.Header{header})
+		return &Event{Head: header, Added: []*types.Header{header}}, nil
+	}
+	tip := t.headers[len(t.headers)-1]
+	hash := header.Hash()
+	if hash == tip.Hash() {
+		return nil, nil
+	}
+	// 已在窗口内但不是链头：规范链回退到了更短的分支
+	if i, ok := t.index[hash]; ok {
+		dropped := append([]*types.Header(nil), t.headers[i+1:]...)
+		t.reset(t.headers[:i+1])
+		return &Event{Head: header, Ancestor: header, Dropped: dropped}, nil
+	}
+
+	// 沿父哈希回溯，直到父区块在窗口内或低于窗口底部
+	bottom := t.headers[0].Number.Uint64()
+	branch := []*types.Header{header}
+	for {
+		cur := branch[0]
+		if i, ok := t.index[cur.ParentHash]; ok {
+			ancestor := t.headers[i]
+			if ancestor.Number.Uint64()+1 != cur.Number.Uint64() {
+				return nil, errs.Wrap(op, fmt.Errorf("%w: block %d has parent at height %d", ErrBrokenChain, cur.Number, ancestor.Number))
+			}
+			dropped := append([]*types.Header(nil), t.headers[i+1:]...)
+			t.reset(append(append([]*types.Header(nil), t.headers[:i+1]...), branch...))
+			return &Event{Head: header, Ancestor: ancestor, Dropped: dropped, Added: branch}, nil
+		}
+		if cur.Number.Uint64() <= bottom {
+			// 共同祖先在窗口之外：整个窗口作废，从新分支重新开始
+			dropped := t.headers
+			t.reset(branch)
+			return &Event{Head: header, Dropped: dropped, Added: branch}, nil
+		}
+		parent, err := t.backend.HeaderByHash(ctx, cur.ParentHash)
+		if err != nil {
+			return nil, errs.Wrap(op, err)
+		}
+		if parent.Hash() != cur.ParentHash || parent.Number.Uint64()+1 != cur.Number.Uint64() {
+			return nil, errs.Wrap(op, fmt.Errorf("%w: parent of block %d", ErrBrokenChain, cur.Number))
+		}
+		branch = append([]*types.Header{parent}, branch...)
+	}
+}
+
+// reset 用 headers 替换窗口，只保留最后 window 个并重建索引。调用方需持有写锁。
+func (t *Tracker) reset(headers []*types.Header) {
+	if len(headers) > t.window {
+		headers = headers[len(headers)-t.window:]
+	}
+	t.headers = headers
+	t.index = make(map[common.Hash]int, len(headers))
+	for i, h := range headers {
+		t.index[h.Hash()] = i
+	}
+}
+
+// Run 订阅新区块头并把每次规范链变化发送到 events，直到 ctx 取消或订阅出错。
+// 开始时先以当前最新区块作为起点发送一个事件。
+func (t *Tracker) Run(ctx context.Context, events chan<- *Event) error {
+	const op = "headtracker.Run"
+
+	headers := make(chan *types.Header, 16)
+	sub, err := t.backend.SubscribeNewHead(ctx, headers)
+	if err != nil {
+		return errs.Wrap(op, err)
+	}
+	defer sub.Unsubscribe()
+
+	latest, err := t.backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return errs.Wrap(op, err)
+	}
+	if err := t.emit(ctx, latest, events); err != nil {
+		return errs.Wrap(op, err)
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return errs.Wrap(op, ctx.Err())
+		case err := <-sub.Err():
+			return errs.Wrap(op, err)
+		case header := <-headers:
+			if err := t.emit(ctx, header, events); err != nil {
+				return errs.Wrap(op, err)
+			}
+		}
+	}
+}
+
+// emit 处理一个区块头，有变化时发送事件。
+func (t *Tracker) emit(ctx context.Context, header *types.Header, events chan<- *Event) error {
+	ev, err := t.Process(ctx, header)
+	if err != nil || ev == nil {
+		return err
+	}
+	select {
+	case events <- ev:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,170 @@
+package headtracker
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+)
+
+// commit 在模拟链上出 n 个块，返回新区块的哈希。
+func commit(sim *simulated.Backend, n int) []common.Hash {
+	var hashes []common.Hash
+	for range n {
+		hashes = append(hashes, sim.Commit())
+	}
+	return hashes
+}
+
+// process 把 number 高度的规范区块头交给 t，返回产生的事件。
+func process(t *testing.T, tr *Tracker, client simulated.Client, number uint64) *Event {
+	t.Helper()
+	ctx := context.Background()
+	header, err := client.HeaderByNumber(ctx, new(big.Int).SetUint64(number))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ev, err := tr.Process(ctx, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ev
+}
+
+func hashesOf(headers []*types.Header) []common.Hash {
+	hashes := make([]common.Hash, len(headers))
+	for i, h := range headers {
+		hashes[i] = h.Hash()
+	}
+	return hashes
+}
+
+func equalHashes(t *testing.T, what string, got, want []common.Hash) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s: got %d headers, want %d", what, len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("%s[%d]: got %s, want %s", what, i, got[i].Hex(), want[i].Hex())
+		}
+	}
+}
+
+// checkLinked 校验窗口内的区块头逐个通过父哈希和高度相连。
+func checkLinked(t *testing.T, tr *Tracker) {
+	t.Helper()
+	headers := tr.Headers()
+	for i := 1; i < len(headers); i++ {
+		if headers[i].ParentHash != headers[i-1].Hash() {
+			t.Fatalf("header %d: parent %s, previous header %s", headers[i].Number, headers[i].ParentHash.Hex(), headers[i-1].Hash().Hex())
+		}
+		if headers[i].Number.Uint64() != headers[i-1].Number.Uint64()+1 {
+			t.Fatalf("header %d follows header %d", headers[i].Number, headers[i-1].Number)
+		}
+	}
+	for _, h := range headers {
+		if !tr.Contains(h.Hash()) {
+			t.Fatalf("header %d missing from the index", h.Number)
+		}
+	}
+}
+
+func TestProcessReorg(t *testing.T) {
+	sim := simulated.NewBackend(types.GenesisAlloc{})
+	defer sim.Close()
+	client := sim.Client()
+
+	// 原链：1..5
+	old := commit(sim, 5)
+	tr := New(client, 16)
+	for n := uint64(1); n <= 5; n++ {
+		ev := process(t, tr, client, n)
+		if ev.Reorg() {
+			t.Fatalf("block %d: unexpected reorg %v", n, ev)
+		}
+	}
+	checkLinked(t, tr)
+
+	// 从区块 3 分叉出更长的分支 4'..6'。第一个区块调整时间戳，保证与原来的区块 4 不同
+	if err := sim.Fork(old[2]); err != nil {
+		t.Fatal(err)
+	}
+	if err := sim.AdjustTime(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	added := commit(sim, 2)
+	head, err := client.HeaderByNumber(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head.Number.Uint64() != 6 || head.Hash() != added[1] {
+		t.Fatalf("fork did not become canonical: head %d %s", head.Number, head.Hash().Hex())
+	}
+	first, err := client.HeaderByHash(context.Background(), added[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	added = append([]common.Hash{first.ParentHash}, added...)
+
+	ev := process(t, tr, client, 6)
+	if !ev.Reorg() || ev.Ancestor == nil || ev.Ancestor.Hash() != old[2] {
+		t.Fatalf("expected a reorg at block 3, got %v", ev)
+	}
+	equalHashes(t, "dropped", hashesOf(ev.Dropped), old[3:])
+	equalHashes(t, "added", hashesOf(ev.Added), added)
+	if ev.Head.Hash() != head.Hash() || tr.Head().Hash() != head.Hash() {
+		t.Fatalf("head: event %s, tracker %s, want %s", ev.Head.Hash().Hex(), tr.Head().Hash().Hex(), head.Hash().Hex())
+	}
+	checkLinked(t, tr)
+	equalHashes(t, "window", hashesOf(tr.Headers()[:3]), old[:3])
+	for _, h := range old[3:] {
+		if tr.Contains(h) {
+			t.Fatalf("dropped block %s is still in the window", h.Hex())
+		}
+	}
+
+	// 已处理过的链头不再产生事件
+	if ev := process(t, tr, client, 6); ev != nil {
+		t.Fatalf("repeated head: got %v", ev)
+	}
+}
+
+func TestProcessReorgDeeperThanWindow(t *testing.T) {
+	sim := simulated.NewBackend(types.GenesisAlloc{})
+	defer sim.Close()
+	client := sim.Client()
+
+	old := commit(sim, 6)
+	tr := New(client, 3)
+	for n := uint64(1); n <= 6; n++ {
+		process(t, tr, client, n)
+	}
+	equalHashes(t, "window", hashesOf(tr.Headers()), old[3:])
+
+	// 从区块 1 分叉，共同祖先已不在窗口内
+	if err := sim.Fork(old[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := sim.AdjustTime(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	commit(sim, 6)
+
+	ev := process(t, tr, client, 8)
+	if !ev.Reorg() || ev.Ancestor != nil {
+		t.Fatalf("expected a reorg without ancestor, got %v", ev)
+	}
+	equalHashes(t, "dropped", hashesOf(ev.Dropped), old[3:])
+	checkLinked(t, tr)
+	if got := len(tr.Headers()); got != 3 {
+		t.Fatalf("window holds %d headers, want 3", got)
+	}
+	if tr.Head().Hash() != ev.Head.Hash() {
+		t.Fatalf("head %s, want %s", tr.Head().Hash().Hex(), ev.Head.Hash().Hex())
+	}
+}
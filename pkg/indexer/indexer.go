@@ -0,0 +1,273 @@
+// Package indexer 把区块、交易和回执写入本地嵌入式数据库（LevelDB，纯 Go 实现，无需单独的数据库服务），
+// 并维护按地址、交易哈希、区块和事件 topic 查询的索引。链发生重组时回滚被替换的区块。
+//
+// 数据库中的键：
+//
+//	H                              → 索引头：区块号(8) + 区块哈希(32)
+//	S                              → 索引起点区块号(8)
+//	n + 区块号(8)                  → 规范链区块哈希
+//	b + 区块哈希                   → 区块（RLP 编码）
+//	r + 区块哈希                   → 回执列表（JSON 编码，保留 txHash、gasUsed 等派生字段）
+//	t + 交易哈希                   → 区块号(8) + 区块哈希(32) + 交易索引(4) + 发送方(20)
+//	a + 地址 + 区块号(8) + 索引(4) → 交易哈希：地址作为发送方、接收方、创建的合约或日志来源出现过的交易
+//	o + topic + 区块号(8) + 日志索引(4) → 区块哈希：任意位置包含该 topic 的日志
+package indexer
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/leveldb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ydh2333/dapp_stu/pkg/config"
+	"github.com/ydh2333/dapp_stu/pkg/errs"
+	"github.com/ydh2333/dapp_stu/pkg/transaction"
+)
+
+// ErrReorg 表示待写入的区块不是当前索引头的子区块，需要先回滚到与节点一致的祖先区块。
+var ErrReorg = errors.New("block does not extend the indexed chain")
+
+var (
+	keyHead  = []byte("H")
+	keyStart = []byte("S")
+
+	prefixCanonical = []byte("n")
+	prefixBlock     = []byte("b")
+	prefixReceipts  = []byte("r")
+	prefixTx        = []byte("t")
+	prefixAddress   = []byte("a")
+	prefixTopic     = []byte("o")
+)
+
+// Index 是本地链数据索引。方法可以并发调用，写入操作相互串行。
+type Index struct {
+	db      ethdb.KeyValueStore
+	chainID *big.Int
+	mu      sync.Mutex // 串行化 Insert / Rewind
+}
+
+// Open 打开（不存在时创建）dir 目录下的 LevelDB 索引，chainID 用于恢复交易发送方。
+func Open(dir string, chainID *big.Int) (*Index, error) {
+	db, err := leveldb.New(dir, 64, 64, "", false)
+	if err != nil {
+		return nil, errs.Wrap("indexer.Open", err)
+	}
+	return New(db, chainID), nil
+}
+
+// New 基于已有的键值数据库创建索引，例如测试用的 memorydb。
+func New(db ethdb.KeyValueStore, chainID *big.Int) *Index {
+	return &Index{db: db, chainID: chainID}
+}
+
+// FromConfig 打开配置中 --index / INDEX_DIR 指定的索引，未配置时返回 nil。
+func FromConfig(cfg *config.Config) (*Index, error) {
+	if cfg.IndexDir == "" {
+		return nil, nil
+	}
+	return Open(cfg.IndexDir, cfg.ChainIDBig())
+}
+
+// Close 关闭数据库。
+func (x *Index) Close() error {
+	return x.db.Close()
+}
+
+// Head 返回索引中最新区块的高度和哈希，索引为空时 ok 为 false。
+func (x *Index) Head() (number uint64, hash common.Hash, ok bool) {
+	v, err := x.db.Get(keyHead)
+	if err != nil || len(v) != 8+common.HashLength {
+		return 0, common.Hash{}, false
+	}
+	return binary.BigEndian.Uint64(v), common.BytesToHash(v[8:]), true
+}
+
+// Start 返回索引的起始区块号，索引为空时 ok 为 false。
+func (x *Index) Start() (number uint64, ok bool) {
+	v, err := x.db.Get(keyStart)
+	if err != nil || len(v) != 8 {
+		return 0, false
+	}
+	return binary.BigEndian.Uint64(v), true
+}
+
+// Insert 写入一个区块及其回执（与交易一一对应）。索引为空时任意区块都可以作为起点；
+// 否则区块必须是索引头的子区块，父哈希不一致时返回 ErrReorg。
+func (x *Index) Insert(b *types.Block, receipts []*types.Receipt) error {
+	const op = "indexer.Insert"
+
+	txs := b.Transactions()
+	if len(receipts) != len(txs) {
+		return errs.Invalid(op, fmt.Sprintf("block %d has %d transactions but %d receipts", b.NumberU64(), len(txs), len(receipts)))
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	batch := x.db.NewBatch()
+	if number, hash, ok := x.Head(); ok {
+		if b.NumberU64() != number+1 {
+			if b.NumberU64() <= number {
+				return errs.Wrap(op, fmt.Errorf("%w: block %d is not above head %d", ErrReorg, b.NumberU64(), number))
+			}
+			return errs.Invalid(op, fmt.Sprintf("block %d leaves a gap after head %d", b.NumberU64(), number))
+		}
+		if b.ParentHash() != hash {
+			return errs.Wrap(op, fmt.Errorf("%w: block %d parent %s, head %s", ErrReorg, b.NumberU64(), b.ParentHash().Hex(), hash.Hex()))
+		}
+	} else {
+		batch.Put(keyStart, encodeNumber(b.NumberU64()))
+	}
+
+	number, hash := b.NumberU64(), b.Hash()
+	blob, err := rlp.EncodeToBytes(b)
+	if err != nil {
+		return errs.Wrap(op, err)
+	}
+	rblob, err := json.Marshal(receipts)
+	if err != nil {
+		return errs.Wrap(op, err)
+	}
+	batch.Put(canonicalKey(number), hash.Bytes())
+	batch.Put(dbKey(prefixBlock, hash.Bytes()), blob)
+	batch.Put(dbKey(prefixReceipts, hash.Bytes()), rblob)
+	for i, tx := range txs {
+		from, err := transaction.Sender(tx, x.chainID)
+		if err != nil {
+			return errs.Wrap(op, err)
+		}
+		entry := dbKey(encodeNumber(number), hash.Bytes(), encodeIndex(uint32(i)), from.Bytes())
+		batch.Put(dbKey(prefixTx, tx.Hash().Bytes()), entry)
+		for _, addr := range touched(tx, from, receipts[i]) {
+			batch.Put(addressKey(addr, number, uint32(i)), tx.Hash().Bytes())
+		}
+		for _, l := range receipts[i].Logs {
+			for _, topic := range l.Topics {
+				batch.Put(topicKey(topic, number, uint32(l.Index)), hash.Bytes())
+			}
+		}
+	}
+	batch.Put(keyHead, dbKey(encodeNumber(number), hash.Bytes()))
+	return errs.Wrap(op, batch.Write())
+}
+
+// Rewind 回滚高于 number 的全部区块并删除它们的索引项。number 低于起点时清空索引。
+func (x *Index) Rewind(number uint64) error {
+	return x.rewind("indexer.Rewind", number, false)
+}
+
+// Reset 清空索引。
+func (x *Index) Reset() error {
+	return x.rewind("indexer.Reset", 0, true)
+}
+
+// rewind 从索引头开始逐块删除，直到索引头不高于 number；all 为 true 时删除全部区块。
+func (x *Index) rewind(op string, number uint64, all bool) error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	start, _ := x.Start()
+	for {
+		head, hash, ok := x.Head()
+		if !ok || (!all && head <= number) {
+			return nil
+		}
+		b, err := x.blockByHash(hash)
+		if err != nil {
+			return errs.Wrap(op, err)
+		}
+		receipts, err := x.receipts(hash)
+		if err != nil {
+			return errs.Wrap(op, err)
+		}
+		batch := x.db.NewBatch()
+		for i, tx := range b.Transactions() {
+			txKey := dbKey(prefixTx, tx.Hash().Bytes())
+			if entry, err := x.db.Get(txKey); err == nil && len(entry) == 8+common.HashLength+4+common.AddressLength {
+				from := common.BytesToAddress(entry[8+common.HashLength+4:])
+				for _, addr := range touched(tx, from, receipts[i]) {
+					batch.Delete(addressKey(addr, head, uint32(i)))
+				}
+			}
+			batch.Delete(txKey)
+			for _, l := range receipts[i].Logs {
+				for _, topic := range l.Topics {
+					batch.Delete(topicKey(topic, head, uint32(l.Index)))
+				}
+			}
+		}
+		batch.Delete(canonicalKey(head))
+		batch.Delete(dbKey(prefixBlock, hash.Bytes()))
+		batch.Delete(dbKey(prefixReceipts, hash.Bytes()))
+		if head == start {
+			batch.Delete(keyHead)
+			batch.Delete(keyStart)
+		} else {
+			batch.Put(keyHead, dbKey(encodeNumber(head-1), b.ParentHash().Bytes()))
+		}
+		if err := batch.Write(); err != nil {
+			return errs.Wrap(op, err)
+		}
+	}
+}
+
+// touched 返回交易涉及的地址：发送方、接收方、创建的合约以及产生日志的合约（去重）。
+func touched(tx *types.Transaction, from common.Address, r *types.Receipt) []common.Address {
+	seen := map[common.Address]bool{from: true}
+	addrs := []common.Address{from}
+	add := func(a common.Address) {
+		if !seen[a] {
+			seen[a] = true
+			addrs = append(addrs, a)
+		}
+	}
+	if tx.To() != nil {
+		add(*tx.To())
+	}
+	if r.ContractAddress != (common.Address{}) {
+		add(r.ContractAddress)
+	}
+	for _, l := range r.Logs {
+		add(l.Address)
+	}
+	return addrs
+}
+
+// dbKey 把各部分拼接成新的字节切片，不与 prefix 共享底层数组。
+func dbKey(prefix []byte, parts ...[]byte) []byte {
+	n := len(prefix)
+	for _, p := range parts {
+		n += len(p)
+	}
+	key := append(make([]byte, 0, n), prefix...)
+	for _, p := range parts {
+		key = append(key, p...)
+	}
+	return key
+}
+
+func encodeNumber(n uint64) []byte {
+	return binary.BigEndian.AppendUint64(nil, n)
+}
+
+func encodeIndex(i uint32) []byte {
+	return binary.BigEndian.AppendUint32(nil, i)
+}
+
+func canonicalKey(number uint64) []byte {
+	return dbKey(prefixCanonical, encodeNumber(number))
+}
+
+func addressKey(addr common.Address, number uint64, index uint32) []byte {
+	return dbKey(prefixAddress, addr.Bytes(), encodeNumber(number), encodeIndex(index))
+}
+
+func topicKey(topic common.Hash, number uint64, logIndex uint32) []byte {
+	return dbKey(prefixTopic, topic.Bytes(), encodeNumber(number), encodeIndex(logIndex))
+}
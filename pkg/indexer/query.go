@@ -0,0 +1,196 @@
+package indexer
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ydh2333/dapp_stu/pkg/errs"
+)
+
+// TxEntry 是索引中的一笔交易及其位置。
+type TxEntry struct {
+	Tx          *types.Transaction
+	From        common.Address
+	BlockHash   common.Hash
+	BlockNumber uint64
+	Index       uint
+}
+
+// get 读取键值，键不存在时返回 errs.ErrNotFound。
+func (x *Index) get(key []byte) ([]byte, error) {
+	if ok, err := x.db.Has(key); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, errs.ErrNotFound
+	}
+	return x.db.Get(key)
+}
+
+// CanonicalHash 返回索引中指定高度的区块哈希。
+func (x *Index) CanonicalHash(number uint64) (common.Hash, error) {
+	v, err := x.get(canonicalKey(number))
+	if err != nil {
+		return common.Hash{}, errs.Wrap("indexer.CanonicalHash", err)
+	}
+	return common.BytesToHash(v), nil
+}
+
+// BlockByNumber 返回索引中指定高度的区块，未索引时返回 errs.ErrNotFound。
+func (x *Index) BlockByNumber(number uint64) (*types.Block, error) {
+	v, err := x.get(canonicalKey(number))
+	if err != nil {
+		return nil, errs.Wrap("indexer.BlockByNumber", err)
+	}
+	b, err := x.blockByHash(common.BytesToHash(v))
+	return b, errs.Wrap("indexer.BlockByNumber", err)
+}
+
+// BlockByHash 通过哈希返回索引中的区块，未索引时返回 errs.ErrNotFound。
+func (x *Index) BlockByHash(hash common.Hash) (*types.Block, error) {
+	b, err := x.blockByHash(hash)
+	return b, errs.Wrap("indexer.BlockByHash", err)
+}
+
+func (x *Index) blockByHash(hash common.Hash) (*types.Block, error) {
+	blob, err := x.get(dbKey(prefixBlock, hash.Bytes()))
+	if err != nil {
+		return nil, err
+	}
+	b := new(types.Block)
+	if err := rlp.DecodeBytes(blob, b); err != nil {
+		return nil, fmt.Errorf("block %s: %w", hash.Hex(), err)
+	}
+	return b, nil
+}
+
+// Receipts 返回索引中区块的全部回执，顺序与区块内交易一致。
+func (x *Index) Receipts(blockHash common.Hash) ([]*types.Receipt, error) {
+	rs, err := x.receipts(blockHash)
+	return rs, errs.Wrap("indexer.Receipts", err)
+}
+
+func (x *Index) receipts(blockHash common.Hash) ([]*types.Receipt, error) {
+	blob, err := x.get(dbKey(prefixReceipts, blockHash.Bytes()))
+	if err != nil {
+		return nil, err
+	}
+	var rs []*types.Receipt
+	if err := json.Unmarshal(blob, &rs); err != nil {
+		return nil, fmt.Errorf("receipts of %s: %w", blockHash.Hex(), err)
+	}
+	return rs, nil
+}
+
+// Transaction 通过交易哈希查询交易及其所在区块，未索引时返回 errs.ErrNotFound。
+func (x *Index) Transaction(hash common.Hash) (*TxEntry, error) {
+	e, err := x.transaction(hash)
+	return e, errs.Wrap("indexer.Transaction", err)
+}
+
+func (x *Index) transaction(hash common.Hash) (*TxEntry, error) {
+	entry, err := x.get(dbKey(prefixTx, hash.Bytes()))
+	if err != nil {
+		return nil, err
+	}
+	e := &TxEntry{
+		BlockNumber: binary.BigEndian.Uint64(entry),
+		BlockHash:   common.BytesToHash(entry[8 : 8+common.HashLength]),
+		Index:       uint(binary.BigEndian.Uint32(entry[8+common.HashLength:])),
+		From:        common.BytesToAddress(entry[8+common.HashLength+4:]),
+	}
+	b, err := x.blockByHash(e.BlockHash)
+	if err != nil {
+		return nil, err
+	}
+	if e.Tx = b.Transaction(hash); e.Tx == nil {
+		return nil, fmt.Errorf("transaction %s missing from block %d", hash.Hex(), e.BlockNumber)
+	}
+	return e, nil
+}
+
+// Receipt 通过交易哈希查询回执，未索引时返回 errs.ErrNotFound。
+func (x *Index) Receipt(txHash common.Hash) (*types.Receipt, error) {
+	const op = "indexer.Receipt"
+
+	e, err := x.transaction(txHash)
+	if err != nil {
+		return nil, errs.Wrap(op, err)
+	}
+	rs, err := x.receipts(e.BlockHash)
+	if err != nil {
+		return nil, errs.Wrap(op, err)
+	}
+	if int(e.Index) >= len(rs) {
+		return nil, errs.Wrap(op, fmt.Errorf("receipt %d missing from block %d", e.Index, e.BlockNumber))
+	}
+	return rs[e.Index], nil
+}
+
+// TransactionsByAddress 按区块顺序返回 [from, to] 区块范围内涉及 addr 的交易
+// （作为发送方、接收方、创建的合约或产生了日志）。
+func (x *Index) TransactionsByAddress(addr common.Address, from, to uint64) ([]*TxEntry, error) {
+	const op = "indexer.TransactionsByAddress"
+
+	it := x.db.NewIterator(dbKey(prefixAddress, addr.Bytes()), encodeNumber(from))
+	defer it.Release()
+	var found []*TxEntry
+	for it.Next() {
+		key := it.Key()
+		if binary.BigEndian.Uint64(key[len(key)-12:]) > to {
+			break
+		}
+		e, err := x.transaction(common.BytesToHash(it.Value()))
+		if err != nil {
+			return nil, errs.Wrap(op, err)
+		}
+		found = append(found, e)
+	}
+	return found, errs.Wrap(op, it.Error())
+}
+
+// LogsByTopic 按区块顺序返回 [from, to] 区块范围内任意 topic 位置等于 topic 的日志。
+func (x *Index) LogsByTopic(topic common.Hash, from, to uint64) ([]*types.Log, error) {
+	const op = "indexer.LogsByTopic"
+
+	it := x.db.NewIterator(dbKey(prefixTopic, topic.Bytes()), encodeNumber(from))
+	defer it.Release()
+	var (
+		found     []*types.Log
+		blockHash common.Hash
+		receipts  []*types.Receipt
+	)
+	for it.Next() {
+		key := it.Key()
+		if binary.BigEndian.Uint64(key[len(key)-12:]) > to {
+			break
+		}
+		logIndex := uint(binary.BigEndian.Uint32(key[len(key)-4:]))
+		if hash := common.BytesToHash(it.Value()); hash != blockHash || receipts == nil {
+			rs, err := x.receipts(hash)
+			if err != nil {
+				return nil, errs.Wrap(op, err)
+			}
+			blockHash, receipts = hash, rs
+		}
+		if l := findLog(receipts, logIndex); l != nil {
+			found = append(found, l)
+		}
+	}
+	return found, errs.Wrap(op, it.Error())
+}
+
+// findLog 在区块回执中按区块内日志索引查找日志。
+func findLog(receipts []*types.Receipt, index uint) *types.Log {
+	for _, r := range receipts {
+		for _, l := range r.Logs {
+			if l.Index == index {
+				return l
+			}
+		}
+	}
+	return nil
+}
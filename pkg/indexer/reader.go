@@ -0,0 +1,150 @@
+package indexer
+
+import (
+	"context"
+	"math/big"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Reader 包装节点客户端：区块、交易和回执的查询先查本地索引，索引中没有时再请求节点。
+// 未覆盖的方法（发送交易、查询余额等）直接使用嵌入的 *ethclient.Client。
+// Index 为 nil 时所有查询都直接请求节点，因此可以无条件地用 Reader 代替客户端。
+type Reader struct {
+	*ethclient.Client
+	Index *Index
+
+	hits, misses atomic.Uint64
+}
+
+// NewReader 创建先查索引、再查节点的客户端，idx 可以为 nil。
+func NewReader(idx *Index, client *ethclient.Client) *Reader {
+	return &Reader{Client: client, Index: idx}
+}
+
+// Stats 返回由索引直接回答的查询数和转发给节点的查询数。
+func (r *Reader) Stats() (hits, misses uint64) {
+	return r.hits.Load(), r.misses.Load()
+}
+
+// lookup 记录一次索引查询的结果，返回是否命中。
+func (r *Reader) lookup(err error) bool {
+	if err != nil {
+		r.misses.Add(1)
+		return false
+	}
+	r.hits.Add(1)
+	return true
+}
+
+// indexedNumber 判断区块号参数能否由索引回答：latest、safe 等标签总是请求节点。
+func (r *Reader) indexedNumber(number *big.Int) (uint64, bool) {
+	if r.Index == nil || number == nil || number.Sign() < 0 || !number.IsUint64() {
+		return 0, false
+	}
+	return number.Uint64(), true
+}
+
+func (r *Reader) BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error) {
+	if n, ok := r.indexedNumber(number); ok {
+		if b, err := r.Index.BlockByNumber(n); r.lookup(err) {
+			return b, nil
+		}
+	}
+	return r.Client.BlockByNumber(ctx, number)
+}
+
+func (r *Reader) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	if n, ok := r.indexedNumber(number); ok {
+		if b, err := r.Index.BlockByNumber(n); r.lookup(err) {
+			return b.Header(), nil
+		}
+	}
+	return r.Client.HeaderByNumber(ctx, number)
+}
+
+func (r *Reader) BlockByHash(ctx context.Context, hash common.Hash) (*types.Block, error) {
+	if r.Index != nil {
+		if b, err := r.Index.BlockByHash(hash); r.lookup(err) {
+			return b, nil
+		}
+	}
+	return r.Client.BlockByHash(ctx, hash)
+}
+
+func (r *Reader) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	if r.Index != nil {
+		if b, err := r.Index.BlockByHash(hash); r.lookup(err) {
+			return b.Header(), nil
+		}
+	}
+	return r.Client.HeaderByHash(ctx, hash)
+}
+
+func (r *Reader) TransactionCount(ctx context.Context, blockHash common.Hash) (uint, error) {
+	if r.Index != nil {
+		if b, err := r.Index.BlockByHash(blockHash); r.lookup(err) {
+			return uint(len(b.Transactions())), nil
+		}
+	}
+	return r.Client.TransactionCount(ctx, blockHash)
+}
+
+func (r *Reader) TransactionInBlock(ctx context.Context, blockHash common.Hash, index uint) (*types.Transaction, error) {
+	if r.Index != nil {
+		if b, err := r.Index.BlockByHash(blockHash); r.lookup(err) && index < uint(len(b.Transactions())) {
+			return b.Transactions()[index], nil
+		}
+	}
+	return r.Client.TransactionInBlock(ctx, blockHash, index)
+}
+
+func (r *Reader) TransactionByHash(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+	if r.Index != nil {
+		if e, err := r.Index.Transaction(hash); r.lookup(err) {
+			return e.Tx, false, nil
+		}
+	}
+	return r.Client.TransactionByHash(ctx, hash)
+}
+
+func (r *Reader) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	if r.Index != nil {
+		if rc, err := r.Index.Receipt(txHash); r.lookup(err) {
+			return rc, nil
+		}
+	}
+	return r.Client.TransactionReceipt(ctx, txHash)
+}
+
+func (r *Reader) BlockReceipts(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) ([]*types.Receipt, error) {
+	if r.Index != nil {
+		if hash, ok := r.indexedBlock(blockNrOrHash); ok {
+			if rs, err := r.Index.Receipts(hash); r.lookup(err) {
+				return rs, nil
+			}
+		}
+	}
+	return r.Client.BlockReceipts(ctx, blockNrOrHash)
+}
+
+// indexedBlock 把区块号或哈希参数解析为索引中的区块哈希，标签以及索引之外的区块号返回 false。
+func (r *Reader) indexedBlock(blockNrOrHash rpc.BlockNumberOrHash) (common.Hash, bool) {
+	if hash, ok := blockNrOrHash.Hash(); ok {
+		return hash, true
+	}
+	n, ok := blockNrOrHash.Number()
+	if !ok || n < 0 {
+		return common.Hash{}, false
+	}
+	hash, err := r.Index.CanonicalHash(uint64(n))
+	if err != nil {
+		r.misses.Add(1)
+		return common.Hash{}, false
+	}
+	return hash, true
+}
@@ -0,0 +1,113 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ydh2333/dapp_stu/pkg/errs"
+	"github.com/ydh2333/dapp_stu/pkg/scanner"
+)
+
+// SyncOptions 配置 Sync。嵌入的 scanner.Options 控制并发与限速，Mode 固定为 scanner.Receipts，
+// 断点由索引头代替，Checkpoint 会被忽略。
+type SyncOptions struct {
+	scanner.Options
+	OnBlock  func(b *types.Block, receipts []*types.Receipt) // 每写入一个区块后调用
+	OnRewind func(from, to uint64)                           // 因重组删除了 [from, to] 内的区块后调用
+}
+
+// Sync 把 [from, to] 内的区块写入索引。索引非空时从索引头的下一个区块继续（from 只在索引为空时使用）。
+// 开始前以及写入时发现父哈希不一致，都会与节点逐块比对哈希，回滚到共同祖先后继续。
+func (x *Index) Sync(ctx context.Context, backend scanner.Backend, from, to uint64, opts SyncOptions) error {
+	const op = "indexer.Sync"
+
+	opts.Mode, opts.Checkpoint = scanner.Receipts, ""
+	for {
+		if err := x.reconcile(ctx, backend, opts.OnRewind); err != nil {
+			return errs.Wrap(op, err)
+		}
+		next := from
+		if head, _, ok := x.Head(); ok {
+			next = head + 1
+		}
+		if next > to {
+			return nil
+		}
+
+		runCtx, cancel := context.WithCancel(ctx)
+		results := make(chan *scanner.Result)
+		errc := make(chan error, 1)
+		go func() {
+			errc <- scanner.New(backend, opts.Options).Run(runCtx, next, to, results)
+			close(results)
+		}()
+		var insertErr error
+		for r := range results {
+			if insertErr != nil {
+				continue // 等待扫描器退出
+			}
+			if insertErr = x.Insert(r.Block, r.Receipts); insertErr != nil {
+				cancel()
+				continue
+			}
+			if opts.OnBlock != nil {
+				opts.OnBlock(r.Block, r.Receipts)
+			}
+		}
+		runErr := <-errc
+		cancel()
+
+		switch {
+		case errors.Is(insertErr, ErrReorg):
+			continue // 扫描期间发生了重组，比对后重新扫描
+		case insertErr != nil:
+			return insertErr
+		case runErr != nil:
+			return runErr
+		}
+		return nil
+	}
+}
+
+// reconcile 从索引头向下与节点比对区块哈希，回滚到第一个一致的区块；起点也不一致时清空索引。
+func (x *Index) reconcile(ctx context.Context, backend scanner.Backend, onRewind func(uint64, uint64)) error {
+	head, hash, ok := x.Head()
+	if !ok {
+		return nil
+	}
+	start, _ := x.Start()
+	for n, local := head, hash; ; {
+		h, err := backend.HeaderByNumber(ctx, new(big.Int).SetUint64(n))
+		switch {
+		case err == nil && h.Hash() == local:
+			if n == head {
+				return nil
+			}
+			if err := x.Rewind(n); err != nil {
+				return err
+			}
+			if onRewind != nil {
+				onRewind(n+1, head)
+			}
+			return nil
+		case err != nil && !errors.Is(err, errs.ErrNotFound):
+			return err
+		}
+		// 节点上该高度的区块不同（或节点还没有这个高度，例如切换到了落后的节点）
+		if n == start {
+			if err := x.Reset(); err != nil {
+				return err
+			}
+			if onRewind != nil {
+				onRewind(start, head)
+			}
+			return nil
+		}
+		n--
+		if local, err = x.CanonicalHash(n); err != nil {
+			return err
+		}
+	}
+}
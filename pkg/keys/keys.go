@@ -0,0 +1,29 @@
+// Package keys 封装 04_create_key 演示的「私钥 → 公钥 → Keccak256 → 地址」推导过程，
+// 供 HD 钱包、靓号生成等功能复用。
+package keys
+
+import (
+	"crypto/ecdsa"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"golang.org/x/crypto/sha3"
+)
+
+// PublicKeyBytes 返回 65 字节的未压缩公钥：1 字节前缀 04 + 32 字节 x 坐标 + 32 字节 y 坐标。
+func PublicKeyBytes(pub *ecdsa.PublicKey) []byte {
+	return crypto.FromECDSAPub(pub)
+}
+
+// KeccakAddress 手动计算地址：对去掉 04 前缀的 64 字节公钥做 Keccak256，截取最后 20 字节。
+// 结果与 crypto.PubkeyToAddress 相同，保留手动实现便于对照学习和交叉校验。
+func KeccakAddress(pub *ecdsa.PublicKey) common.Address {
+	hash := sha3.NewLegacyKeccak256()
+	hash.Write(PublicKeyBytes(pub)[1:])
+	return common.BytesToAddress(hash.Sum(nil)[12:])
+}
+
+// Address 用以太坊官方方法 crypto.PubkeyToAddress 计算地址。
+func Address(pub *ecdsa.PublicKey) common.Address {
+	return crypto.PubkeyToAddress(*pub)
+}
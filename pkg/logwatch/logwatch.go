@@ -0,0 +1,336 @@
+// Package logwatch 是通用的日志查询与监听：按合约地址、事件（事件名、事件签名或 topic0）
+// 和 indexed 参数过滤，历史日志用 FilterLogs 分段查询，实时日志用 SubscribeFilterLogs 订阅，
+// 结果可用 abireg 注册的 ABI 解码。
+package logwatch
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ydh2333/dapp_stu/pkg/abireg"
+	"github.com/ydh2333/dapp_stu/pkg/errs"
+)
+
+// DefaultChunk 是分段查询历史日志时每段的区块数，公共节点通常限制单次 eth_getLogs 的范围。
+const DefaultChunk = 2000
+
+// Query 描述要查询或监听的日志，各字段之间为“与”关系，同一字段内的多个值为“或”关系。
+type Query struct {
+	Addresses []common.Address
+	// Events 是要匹配的事件，每项可以是事件名（"Transfer"、"MyERC20.Transfer"）、
+	// 事件签名（"Transfer(address,address,uint256)"）或 32 字节的 topic0。
+	Events []string
+	// Args 按参数名过滤 indexed 参数，如 {"to": {"0xabc..."}}，需要 Events 中的事件都有 ABI。
+	Args map[string][]string
+	// Topics 按位置直接指定 topic1 ~ topic3，不依赖 ABI。
+	Topics    [3][]common.Hash
+	FromBlock *big.Int
+	ToBlock   *big.Int
+}
+
+// Resolve 借助注册表把 Query 转换为节点使用的 ethereum.FilterQuery。
+func (q *Query) Resolve(reg *abireg.Registry) (ethereum.FilterQuery, error) {
+	const op = "logwatch.Resolve"
+
+	fq := ethereum.FilterQuery{Addresses: q.Addresses, FromBlock: q.FromBlock, ToBlock: q.ToBlock}
+	topics := make([][]common.Hash, 4)
+
+	// topic0：每个事件说明对应一个或多个 topic0，并记下能用来定位 indexed 参数的 ABI 定义
+	var defs []*abi.Event
+	for _, spec := range q.Events {
+		ids, evs, err := resolveEvent(reg, spec)
+		if err != nil {
+			return fq, err
+		}
+		for _, id := range ids {
+			if !slices.Contains(topics[0], id) {
+				topics[0] = append(topics[0], id)
+			}
+		}
+		if len(evs) == 0 && len(q.Args) > 0 {
+			return fq, errs.Invalid(op, "no ABI for event "+spec+", cannot filter by argument name")
+		}
+		defs = append(defs, evs...)
+	}
+
+	if len(q.Args) > 0 && len(defs) == 0 {
+		return fq, errs.Invalid(op, "argument filters require at least one event")
+	}
+	for name, values := range q.Args {
+		pos, typ, err := indexedPosition(defs, name)
+		if err != nil {
+			return fq, err
+		}
+		for _, v := range values {
+			topic, err := ToTopic(typ, v)
+			if err != nil {
+				return fq, errs.Invalid(op, fmt.Sprintf("argument %s: %v", name, err))
+			}
+			topics[pos] = append(topics[pos], topic)
+		}
+	}
+	for i, raw := range q.Topics {
+		if len(raw) == 0 {
+			continue
+		}
+		if len(topics[i+1]) > 0 {
+			return fq, errs.Invalid(op, fmt.Sprintf("topic%d is set both by an argument filter and directly", i+1))
+		}
+		topics[i+1] = raw
+	}
+
+	// 去掉末尾的空位置，节点把 nil 视为通配
+	for len(topics) > 0 && len(topics[len(topics)-1]) == 0 {
+		topics = topics[:len(topics)-1]
+	}
+	fq.Topics = topics
+	return fq, nil
+}
+
+// resolveEvent 把一个事件说明解析为 topic0 列表以及对应的 ABI 定义（签名或 topic0 未注册时为空）。
+func resolveEvent(reg *abireg.Registry, spec string) ([]common.Hash, []*abi.Event, error) {
+	spec = strings.TrimSpace(spec)
+	var id common.Hash
+	switch {
+	case len(spec) == 66 && strings.HasPrefix(spec, "0x"):
+		b, err := hexutil.Decode(spec)
+		if err != nil {
+			return nil, nil, errs.Invalid("logwatch.Resolve", "invalid topic0 "+spec)
+		}
+		id = common.BytesToHash(b)
+	case strings.Contains(spec, "("):
+		id = crypto.Keccak256Hash([]byte(strings.ReplaceAll(spec, " ", "")))
+	default:
+		found := reg.EventsByName(spec)
+		if len(found) == 0 {
+			return nil, nil, errs.Invalid("logwatch.Resolve", "unknown event "+spec)
+		}
+		var ids []common.Hash
+		var evs []*abi.Event
+		for _, ev := range found {
+			if !slices.Contains(ids, ev.ID) {
+				ids = append(ids, ev.ID)
+			}
+			evs = append(evs, &ev.Event)
+		}
+		return ids, evs, nil
+	}
+	var evs []*abi.Event
+	for _, ev := range reg.EventsByTopic(id) {
+		evs = append(evs, &ev.Event)
+	}
+	return []common.Hash{id}, evs, nil
+}
+
+// indexedPosition 找到参数 name 在各事件中的 topic 位置（1 ~ 3），各事件的位置和类型必须一致。
+func indexedPosition(defs []*abi.Event, name string) (int, abi.Type, error) {
+	pos, typ := 0, abi.Type{}
+	for _, ev := range defs {
+		p, found := 0, false
+		for _, in := range ev.Inputs {
+			if !in.Indexed {
+				if in.Name == name {
+					return 0, typ, errs.Invalid("logwatch.Resolve", "argument "+name+" of "+ev.Name+" is not indexed")
+				}
+				continue
+			}
+			p++
+			if in.Name == name {
+				if pos != 0 && (p != pos || in.Type.String() != typ.String()) {
+					return 0, typ, errs.Invalid("logwatch.Resolve", "argument "+name+" has different positions or types across events")
+				}
+				pos, typ, found = p, in.Type, true
+				break
+			}
+		}
+		if !found {
+			return 0, typ, errs.Invalid("logwatch.Resolve", "event "+ev.Name+" has no indexed argument "+name)
+		}
+	}
+	return pos, typ, nil
+}
+
+// ToTopic 按参数类型把字符串形式的值编码为 topic：
+// 值类型左补零（负整数取补码），string / bytes 取 keccak256，定长字节右补零。
+func ToTopic(typ abi.Type, s string) (common.Hash, error) {
+	switch typ.T {
+	case abi.AddressTy:
+		if !common.IsHexAddress(s) {
+			return common.Hash{}, fmt.Errorf("invalid address %q", s)
+		}
+		return common.BytesToHash(common.HexToAddress(s).Bytes()), nil
+	case abi.UintTy, abi.IntTy:
+		n, ok := new(big.Int).SetString(s, 0)
+		if !ok || (typ.T == abi.UintTy && n.Sign() < 0) {
+			return common.Hash{}, fmt.Errorf("invalid %s %q", typ, s)
+		}
+		return common.BytesToHash(math.U256Bytes(n)), nil
+	case abi.BoolTy:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return common.Hash{}, fmt.Errorf("invalid bool %q", s)
+		}
+		var h common.Hash
+		if b {
+			h[31] = 1
+		}
+		return h, nil
+	case abi.FixedBytesTy:
+		b, err := hexutil.Decode(s)
+		if err != nil || len(b) != typ.Size {
+			return common.Hash{}, fmt.Errorf("invalid %s %q", typ, s)
+		}
+		var h common.Hash
+		copy(h[:], b)
+		return h, nil
+	case abi.StringTy:
+		return crypto.Keccak256Hash([]byte(s)), nil
+	case abi.BytesTy:
+		b, err := hexutil.Decode(s)
+		if err != nil {
+			return common.Hash{}, fmt.Errorf("invalid bytes %q", s)
+		}
+		return crypto.Keccak256Hash(b), nil
+	default:
+		// 数组、结构体等 indexed 参数的 topic 是其编码的哈希，只能直接给出 32 字节哈希
+		b, err := hexutil.Decode(s)
+		if err != nil || len(b) != common.HashLength {
+			return common.Hash{}, fmt.Errorf("%s arguments must be given as their 32-byte topic hash", typ)
+		}
+		return common.BytesToHash(b), nil
+	}
+}
+
+// Backend 是查询和订阅日志所需的节点能力，*ethclient.Client 和模拟链客户端都满足该接口。
+type Backend interface {
+	ethereum.LogFilterer
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+}
+
+// History 把 [q.FromBlock, q.ToBlock] 拆成每段 chunk 个区块调用 FilterLogs，按区块顺序返回全部日志。
+// FromBlock 为 nil 时从 0 开始，ToBlock 为 nil 时到当前最新区块；chunk 为 0 时使用 DefaultChunk。
+func History(ctx context.Context, backend Backend, q ethereum.FilterQuery, chunk uint64) ([]types.Log, error) {
+	const op = "logwatch.History"
+
+	if chunk == 0 {
+		chunk = DefaultChunk
+	}
+	from, to, err := blockRange(ctx, backend, q)
+	if err != nil {
+		return nil, errs.Wrap(op, err)
+	}
+	var all []types.Log
+	for start := from; start <= to; start += chunk {
+		end := min(start+chunk-1, to)
+		part := q
+		part.BlockHash = nil
+		part.FromBlock, part.ToBlock = new(big.Int).SetUint64(start), new(big.Int).SetUint64(end)
+		logs, err := backend.FilterLogs(ctx, part)
+		if err != nil {
+			return nil, errs.Wrap(op, fmt.Errorf("blocks %d-%d: %w", start, end, err))
+		}
+		all = append(all, logs...)
+	}
+	return all, nil
+}
+
+// blockRange 确定历史查询的区块范围。
+func blockRange(ctx context.Context, backend Backend, q ethereum.FilterQuery) (from, to uint64, err error) {
+	if q.FromBlock != nil {
+		if q.FromBlock.Sign() < 0 {
+			return 0, 0, errs.Invalid("logwatch.History", "block tags are not supported as FromBlock")
+		}
+		from = q.FromBlock.Uint64()
+	}
+	if q.ToBlock != nil && q.ToBlock.Sign() >= 0 {
+		return from, q.ToBlock.Uint64(), nil
+	}
+	head, err := backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	return from, head.Number.Uint64(), nil
+}
+
+// Watch 用 SubscribeFilterLogs 订阅新日志（需要 WebSocket）并发送到 logs，直到 ctx 取消或订阅出错。
+// 重组时节点会重新发送被回滚的日志，其 Removed 为 true。
+func Watch(ctx context.Context, backend Backend, q ethereum.FilterQuery, logs chan<- types.Log) error {
+	const op = "logwatch.Watch"
+
+	q.FromBlock, q.ToBlock, q.BlockHash = nil, nil, nil
+	ch := make(chan types.Log, 64)
+	sub, err := backend.SubscribeFilterLogs(ctx, q, ch)
+	if err != nil {
+		return errs.Wrap(op, err)
+	}
+	defer sub.Unsubscribe()
+	return errs.Wrap(op, forward(ctx, sub, ch, logs, 0))
+}
+
+// Follow 先发送从 q.FromBlock 到当前最新区块的历史日志，再无缝衔接实时日志。
+// 订阅在查询历史之前建立，历史范围内的实时日志会被跳过，因此两段之间不会遗漏也不会重复。
+func Follow(ctx context.Context, backend Backend, q ethereum.FilterQuery, chunk uint64, logs chan<- types.Log) error {
+	const op = "logwatch.Follow"
+
+	live := q
+	live.FromBlock, live.ToBlock, live.BlockHash = nil, nil, nil
+	ch := make(chan types.Log, 1024)
+	sub, err := backend.SubscribeFilterLogs(ctx, live, ch)
+	if err != nil {
+		return errs.Wrap(op, err)
+	}
+	defer sub.Unsubscribe()
+
+	head, err := backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return errs.Wrap(op, err)
+	}
+	q.ToBlock = head.Number
+	history, err := History(ctx, backend, q, chunk)
+	if err != nil {
+		return errs.Wrap(op, err)
+	}
+	for _, l := range history {
+		select {
+		case logs <- l:
+		case <-ctx.Done():
+			return errs.Wrap(op, ctx.Err())
+		}
+	}
+	return errs.Wrap(op, forward(ctx, sub, ch, logs, head.Number.Uint64()))
+}
+
+// forward 转发订阅到的日志，跳过区块号不大于 skipTo 的非回滚日志。
+func forward(ctx context.Context, sub ethereum.Subscription, in <-chan types.Log, out chan<- types.Log, skipTo uint64) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-sub.Err():
+			if err == nil {
+				err = fmt.Errorf("log subscription closed")
+			}
+			return err
+		case l := <-in:
+			if l.BlockNumber <= skipTo && !l.Removed {
+				continue
+			}
+			select {
+			case out <- l:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+}
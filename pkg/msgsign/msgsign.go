@@ -0,0 +1,113 @@
+// Package msgsign 提供 EIP-191 personal_sign 消息签名、原始哈希签名、签名校验，
+// 以及从签名恢复公钥和地址（ecrecover），用于证明钱包所有权。
+//
+// 签名统一为 65 字节 [R || S || V]，V 按钱包和 ecrecover 预编译合约的惯例为 27 或 28；
+// 校验和恢复时同时接受 V 为 0/1 的签名。
+package msgsign
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ydh2333/dapp_stu/pkg/errs"
+	"github.com/ydh2333/dapp_stu/pkg/signer"
+)
+
+// TextHash 返回 EIP-191 personal_sign 的消息哈希：
+// keccak256("\x19Ethereum Signed Message:\n" + len(msg) + msg)。
+// 加前缀是为了让签过的消息不可能被当作一笔交易重放。
+func TextHash(msg []byte) []byte {
+	return accounts.TextHash(msg)
+}
+
+// SignMessage 按 personal_sign 规则对消息签名。
+func SignMessage(s signer.Signer, msg []byte) ([]byte, error) {
+	return signHash("msgsign.SignMessage", s, TextHash(msg))
+}
+
+// SignHash 直接对 32 字节哈希签名，不加任何前缀。
+// 对任意哈希签名可能被诱导签下一笔交易，只应对自己计算出的哈希使用。
+func SignHash(s signer.Signer, hash []byte) ([]byte, error) {
+	return signHash("msgsign.SignHash", s, hash)
+}
+
+func signHash(op string, s signer.Signer, hash []byte) ([]byte, error) {
+	if len(hash) != common.HashLength {
+		return nil, errs.Invalid(op, fmt.Sprintf("hash must be %d bytes, got %d", common.HashLength, len(hash)))
+	}
+	sig, err := s.SignHash(hash)
+	if err != nil {
+		return nil, errs.Wrap(op, err)
+	}
+	sig[crypto.RecoveryIDOffset] += 27
+	return sig, nil
+}
+
+// RecoverPubkey 从哈希和签名恢复签名者公钥。
+func RecoverPubkey(hash, sig []byte) (*ecdsa.PublicKey, error) {
+	const op = "msgsign.RecoverPubkey"
+
+	if len(hash) != common.HashLength {
+		return nil, errs.Invalid(op, fmt.Sprintf("hash must be %d bytes, got %d", common.HashLength, len(hash)))
+	}
+	normalized, err := normalize(sig)
+	if err != nil {
+		return nil, errs.Wrap(op, err)
+	}
+	pub, err := crypto.SigToPub(hash, normalized)
+	if err != nil {
+		return nil, errs.Wrap(op, err)
+	}
+	return pub, nil
+}
+
+// RecoverAddress 从哈希和签名恢复签名者地址。
+func RecoverAddress(hash, sig []byte) (common.Address, error) {
+	pub, err := RecoverPubkey(hash, sig)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return crypto.PubkeyToAddress(*pub), nil
+}
+
+// RecoverMessage 从 personal_sign 消息和签名恢复签名者地址。
+func RecoverMessage(msg, sig []byte) (common.Address, error) {
+	return RecoverAddress(TextHash(msg), sig)
+}
+
+// VerifyHash 校验 sig 是否为 address 对 hash 的签名。
+// 除比较恢复出的地址外，还用 crypto.VerifySignature 拒绝 S 值处于高半区的可延展签名。
+func VerifyHash(hash, sig []byte, address common.Address) (bool, error) {
+	pub, err := RecoverPubkey(hash, sig)
+	if err != nil {
+		return false, err
+	}
+	if crypto.PubkeyToAddress(*pub) != address {
+		return false, nil
+	}
+	return crypto.VerifySignature(crypto.FromECDSAPub(pub), hash, sig[:crypto.RecoveryIDOffset]), nil
+}
+
+// VerifyMessage 校验 sig 是否为 address 对 personal_sign 消息 msg 的签名。
+func VerifyMessage(msg, sig []byte, address common.Address) (bool, error) {
+	return VerifyHash(TextHash(msg), sig, address)
+}
+
+// normalize 检查签名长度并把 V 规范为 crypto 包要求的 0 或 1，不修改传入的切片。
+func normalize(sig []byte) ([]byte, error) {
+	if len(sig) != crypto.SignatureLength {
+		return nil, errs.Invalid("msgsign", fmt.Sprintf("signature must be %d bytes, got %d", crypto.SignatureLength, len(sig)))
+	}
+	out := make([]byte, crypto.SignatureLength)
+	copy(out, sig)
+	if out[crypto.RecoveryIDOffset] >= 27 {
+		out[crypto.RecoveryIDOffset] -= 27
+	}
+	if out[crypto.RecoveryIDOffset] > 1 {
+		return nil, errs.Invalid("msgsign", fmt.Sprintf("invalid recovery id %d", sig[crypto.RecoveryIDOffset]))
+	}
+	return out, nil
+}
@@ -0,0 +1,467 @@
+// Package nonce 在本地为发送账户分配 nonce，使同一账户可以连续、并发地发送多笔交易：
+//   - 同一进程内的 goroutine 通过互斥锁串行分配；多个进程通过状态文件和锁文件共享分配进度；
+//   - 节点返回 "nonce too low"、"already known" 等错误时重新与节点同步；
+//   - 已分配但从未发出、或发出后被节点丢弃的 nonce 被识别为空洞，优先重新分配；
+//   - 状态保存在 JSON 文件中，进程重启后继续使用。
+//
+// Manager.Wrap 返回的 Backend 替换了 PendingNonceAt 和 SendTransaction，
+// 直接传给 transfer、contract 等包的发送函数即可，无需修改它们的代码。
+package nonce
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ydh2333/dapp_stu/pkg/errs"
+)
+
+// ErrNonceConflict 表示节点拒绝了交易的 nonce（已被使用），Manager 已重新同步，
+// 重新构造并发送交易即可，见 Retry。
+var ErrNonceConflict = errors.New("nonce conflict")
+
+// leaseTimeout 是已分配 nonce 的租期：超过该时间仍未发出的 nonce 视为被放弃，可重新分配。
+const leaseTimeout = time.Minute
+
+// Client 是 Manager 需要的节点能力，*ethclient.Client 和模拟链客户端都满足该接口。
+type Client interface {
+	bind.ContractBackend
+	ethereum.FeeHistoryReader
+	ethereum.TransactionReader
+	NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error)
+}
+
+// account 是单个账户的分配状态，会写入状态文件。
+type account struct {
+	Next     uint64                 `json:"next"`               // 下一个从未分配过的 nonce
+	Reserved map[uint64]time.Time   `json:"reserved,omitempty"` // 已分配、尚未发送
+	Sent     map[uint64]common.Hash `json:"sent,omitempty"`     // 已发送、尚未确认
+	Free     []uint64               `json:"free,omitempty"`     // 可重新分配的空洞
+}
+
+// Manager 为多个账户分配 nonce。
+type Manager struct {
+	client Client
+	path   string // 状态文件，为空时只保存在内存中
+
+	mu       sync.Mutex
+	accounts map[common.Address]*account
+	synced   map[common.Address]bool // 本进程是否已与节点同步过
+}
+
+// New 创建 Manager，path 为状态文件路径；path 为空时状态只保存在内存中。
+func New(client Client, path string) *Manager {
+	return &Manager{
+		client:   client,
+		path:     path,
+		accounts: make(map[common.Address]*account),
+		synced:   make(map[common.Address]bool),
+	}
+}
+
+// Next 为 from 分配下一个 nonce：优先复用最小的空洞，否则取 Next。
+func (m *Manager) Next(ctx context.Context, from common.Address) (uint64, error) {
+	var nonce uint64
+	err := m.update(ctx, from, syncOnce, func(a *account) error {
+		m.expireLeases(a)
+		if len(a.Free) > 0 {
+			nonce, a.Free = a.Free[0], a.Free[1:]
+		} else {
+			nonce = a.Next
+			a.Next++
+		}
+		a.Reserved[nonce] = time.Now()
+		return nil
+	})
+	if err != nil {
+		return 0, errs.Wrap("nonce.Next", err)
+	}
+	return nonce, nil
+}
+
+// Sent 记录 nonce 已由交易 hash 发出。
+func (m *Manager) Sent(ctx context.Context, from common.Address, nonce uint64, hash common.Hash) error {
+	return errs.Wrap("nonce.Sent", m.update(ctx, from, noSync, func(a *account) error {
+		delete(a.Reserved, nonce)
+		a.Sent[nonce] = hash
+		if a.Next <= nonce {
+			a.Next = nonce + 1
+		}
+		a.Free = slices.DeleteFunc(a.Free, func(n uint64) bool { return n == nonce })
+		return nil
+	}))
+}
+
+// Release 归还已分配但没有发出的 nonce，它会被下一次 Next 优先复用。
+func (m *Manager) Release(ctx context.Context, from common.Address, nonce uint64) error {
+	return errs.Wrap("nonce.Release", m.update(ctx, from, noSync, func(a *account) error {
+		if _, ok := a.Reserved[nonce]; !ok {
+			return nil
+		}
+		delete(a.Reserved, nonce)
+		addFree(a, nonce)
+		return nil
+	}))
+}
+
+// reserved 报告 nonce 是否由 Next 分配、尚未发送。
+func (m *Manager) reserved(ctx context.Context, from common.Address, nonce uint64) (bool, error) {
+	var ok bool
+	err := m.update(ctx, from, noSync, func(a *account) error {
+		_, ok = a.Reserved[nonce]
+		return nil
+	})
+	return ok, err
+}
+
+// Resync 重新与节点同步 from 的状态：丢弃已确认和被节点占用的 nonce，重新识别空洞。
+func (m *Manager) Resync(ctx context.Context, from common.Address) error {
+	return errs.Wrap("nonce.Resync", m.update(ctx, from, syncAlways, func(a *account) error { return nil }))
+}
+
+// Gaps 与节点同步后返回 from 当前的空洞：低于 Next、却没有任何交易占用的 nonce。
+// 空洞之后的交易在空洞被填上之前都无法打包。
+func (m *Manager) Gaps(ctx context.Context, from common.Address) ([]uint64, error) {
+	var gaps []uint64
+	err := m.update(ctx, from, syncAlways, func(a *account) error {
+		m.expireLeases(a)
+		gaps = slices.Clone(a.Free)
+		return nil
+	})
+	if err != nil {
+		return nil, errs.Wrap("nonce.Gaps", err)
+	}
+	return gaps, nil
+}
+
+// observation 是一次向节点查询 from 状态的结果，在文件锁之外取得，在锁内合并。
+type observation struct {
+	latest  uint64
+	pending uint64
+	dropped map[uint64]common.Hash // 已发送、但节点查不到的交易
+}
+
+// observe 向节点查询 latest / pending nonce，并检查 sent 中的交易是否还在节点上。
+func (m *Manager) observe(ctx context.Context, from common.Address, sent map[uint64]common.Hash) (*observation, error) {
+	latest, err := m.client.NonceAt(ctx, from, nil)
+	if err != nil {
+		return nil, err
+	}
+	pending, err := m.client.PendingNonceAt(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+	obs := &observation{latest: latest, pending: pending, dropped: make(map[uint64]common.Hash)}
+	for n, hash := range sent {
+		if n < pending {
+			continue
+		}
+		if _, _, err := m.client.TransactionByHash(ctx, hash); errors.Is(err, ethereum.NotFound) {
+			obs.dropped[n] = hash
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	return obs, nil
+}
+
+// apply 用节点的查询结果校正 a。查询之后其他进程可能已更新状态文件，
+// 只丢弃记录的交易仍与查询时相同的 nonce。
+func (obs *observation) apply(a *account) {
+	// 低于 latest 的 nonce 已上链确认；低于 pending 的已被交易池中的交易占用
+	for n := range a.Sent {
+		if n < obs.latest {
+			delete(a.Sent, n)
+		}
+	}
+	for n := range a.Reserved {
+		if n < obs.pending {
+			delete(a.Reserved, n)
+		}
+	}
+	a.Free = slices.DeleteFunc(a.Free, func(n uint64) bool { return n < obs.pending })
+	if a.Next < obs.pending {
+		a.Next = obs.pending
+	}
+	// 已发送但节点查不到的交易已被丢弃，它的 nonce 成为空洞
+	for n, hash := range obs.dropped {
+		if a.Sent[n] == hash {
+			delete(a.Sent, n)
+			addFree(a, n)
+		}
+	}
+	// 本地记录之外的空洞（例如状态文件来自已崩溃的进程）
+	for n := obs.pending; n < a.Next; n++ {
+		_, sent := a.Sent[n]
+		_, reserved := a.Reserved[n]
+		if !sent && !reserved {
+			addFree(a, n)
+		}
+	}
+}
+
+// expireLeases 回收租期已过仍未发送的 nonce。
+func (m *Manager) expireLeases(a *account) {
+	for n, at := range a.Reserved {
+		if time.Since(at) > leaseTimeout {
+			delete(a.Reserved, n)
+			addFree(a, n)
+		}
+	}
+}
+
+// addFree 把 n 有序地加入空洞列表。
+func addFree(a *account, n uint64) {
+	i, found := slices.BinarySearch(a.Free, n)
+	if !found {
+		a.Free = slices.Insert(a.Free, i, n)
+	}
+}
+
+// syncMode 决定 update 是否先与节点同步。
+type syncMode int
+
+const (
+	noSync     syncMode = iota // 只读写本地状态
+	syncOnce                   // 本进程尚未与节点同步过该账户时先同步
+	syncAlways                 // 总是先同步
+)
+
+// update 在进程内互斥锁和跨进程文件锁的保护下，加载最新状态、执行 fn 并保存。
+// 需要同步时先在文件锁之外查询节点，锁内只读写状态文件，节点响应慢不会拖长文件锁的持有时间。
+func (m *Manager) update(ctx context.Context, from common.Address, mode syncMode, fn func(a *account) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var obs *observation
+	if mode == syncAlways || (mode == syncOnce && !m.synced[from]) {
+		// 状态文件通过重命名整体替换，不加锁读取也不会读到写了一半的内容
+		if m.path != "" {
+			if err := m.load(); err != nil {
+				return err
+			}
+		}
+		var sent map[uint64]common.Hash
+		if a, ok := m.accounts[from]; ok {
+			sent = maps.Clone(a.Sent)
+		}
+		var err error
+		if obs, err = m.observe(ctx, from, sent); err != nil {
+			return err
+		}
+	}
+
+	if m.path != "" {
+		unlock, err := lockFile(ctx, m.path+".lock")
+		if err != nil {
+			return err
+		}
+		defer unlock()
+		if err := m.load(); err != nil {
+			return err
+		}
+	}
+	a, ok := m.accounts[from]
+	if !ok {
+		a = &account{}
+		m.accounts[from] = a
+	}
+	if a.Reserved == nil {
+		a.Reserved = make(map[uint64]time.Time)
+	}
+	if a.Sent == nil {
+		a.Sent = make(map[uint64]common.Hash)
+	}
+	if obs != nil {
+		obs.apply(a)
+	}
+	if err := fn(a); err != nil {
+		return err
+	}
+	if m.path != "" {
+		if err := m.save(); err != nil {
+			return err
+		}
+	}
+	if obs != nil {
+		m.synced[from] = true
+	}
+	return nil
+}
+
+// load 从状态文件读取全部账户状态，文件不存在时保持为空。
+func (m *Manager) load() error {
+	data, err := os.ReadFile(m.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	accounts := make(map[common.Address]*account)
+	if err := json.Unmarshal(data, &accounts); err != nil {
+		return fmt.Errorf("%s: %w", m.path, err)
+	}
+	m.accounts = accounts
+	return nil
+}
+
+// save 先写临时文件再重命名，避免进程中途退出留下损坏的状态文件。
+func (m *Manager) save() error {
+	data, err := json.MarshalIndent(m.accounts, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := m.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, m.path)
+}
+
+// staleLock 是锁文件的最长持有时间，超过后视为持有者已崩溃。
+// 持有锁期间只读写本地状态文件，不访问节点，正常情况下远小于该时间。
+const staleLock = 10 * time.Second
+
+// lockFile 通过独占创建锁文件实现跨进程互斥，返回释放函数。
+func lockFile(ctx context.Context, path string) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > staleLock {
+			os.Remove(path)
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+// Backend 是经 Manager 分配 nonce 的节点客户端，可替代原客户端传给各发送函数。
+type Backend struct {
+	Client
+	manager *Manager
+}
+
+// Wrap 返回使用 m 分配 nonce 的 Backend。
+func (m *Manager) Wrap(client Client) *Backend {
+	return &Backend{Client: client, manager: m}
+}
+
+// PendingNonceAt 从 Manager 分配 nonce，而不是直接询问节点。
+func (b *Backend) PendingNonceAt(ctx context.Context, from common.Address) (uint64, error) {
+	return b.manager.Next(ctx, from)
+}
+
+// ReleaseNonce 归还经 PendingNonceAt 分配、但最终没有发出交易的 nonce，实现 Releaser。
+func (b *Backend) ReleaseNonce(ctx context.Context, from common.Address, nonce uint64) error {
+	return b.manager.Release(ctx, from, nonce)
+}
+
+// SendTransaction 发送交易并更新 nonce 状态：
+//   - 成功或节点返回 "already known"（同一笔交易已在交易池中）时记录为已发送；
+//   - nonce 已被占用时重新同步，返回 ErrNonceConflict。交易池中已有同一 nonce 的交易
+//     （"replacement transaction underpriced"）只在该 nonce 是由 Manager 新分配的时候才算冲突；
+//     调用方指定 nonce 有意替换交易（如 replace.SpeedUp）时原样返回错误，不改动状态；
+//   - 其他错误归还 nonce。
+func (b *Backend) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	const op = "nonce.SendTransaction"
+
+	from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		return errs.Wrap(op, err)
+	}
+	sendErr := b.Client.SendTransaction(ctx, tx)
+	switch {
+	case sendErr == nil || IsAlreadyKnown(sendErr):
+		return b.manager.Sent(ctx, from, tx.Nonce(), tx.Hash())
+	case IsUnderpriced(sendErr):
+		reserved, err := b.manager.reserved(ctx, from, tx.Nonce())
+		if err != nil {
+			return errs.Wrap(op, errors.Join(sendErr, err))
+		}
+		if !reserved {
+			return sendErr
+		}
+		fallthrough
+	case IsNonceConflict(sendErr):
+		if err := b.manager.Resync(ctx, from); err != nil {
+			return errs.Wrap(op, errors.Join(sendErr, err))
+		}
+		return errs.Wrap(op, fmt.Errorf("%w: %v", ErrNonceConflict, sendErr))
+	default:
+		if err := b.manager.Release(ctx, from, tx.Nonce()); err != nil {
+			return errs.Wrap(op, errors.Join(sendErr, err))
+		}
+		return sendErr
+	}
+}
+
+// Releaser 由自行分配 nonce 的客户端实现（如 *Backend）。调用 PendingNonceAt 之后、
+// 交易发出之前的步骤（gas 估算、签名、Guard 检查等）失败时，应归还已分配的 nonce，
+// 否则它要等租期结束才能被重新分配，期间后续交易都排在空洞之后。
+type Releaser interface {
+	ReleaseNonce(ctx context.Context, from common.Address, nonce uint64) error
+}
+
+// ReleaseUnsent 在 client 实现了 Releaser 时归还 nonce；直接询问节点得到的 nonce 无需归还。
+func ReleaseUnsent(ctx context.Context, client any, from common.Address, nonce uint64) error {
+	if r, ok := client.(Releaser); ok {
+		return r.ReleaseNonce(ctx, from, nonce)
+	}
+	return nil
+}
+
+// IsAlreadyKnown 判断节点是否因交易已在交易池中而拒绝。
+func IsAlreadyKnown(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "already known")
+}
+
+// IsNonceConflict 判断节点是否因 nonce 已上链而拒绝交易。
+func IsNonceConflict(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "nonce too low")
+}
+
+// IsUnderpriced 判断节点是否因交易池中已有同一 nonce 的交易、而新交易的手续费涨幅不够而拒绝。
+func IsUnderpriced(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "replacement transaction underpriced")
+}
+
+// Retry 调用 send 构造并发送交易，遇到 ErrNonceConflict 时（Manager 已重新同步）
+// 最多重试 attempts 次。
+func Retry(ctx context.Context, attempts int, send func() (*types.Transaction, error)) (*types.Transaction, error) {
+	for i := 0; ; i++ {
+		tx, err := send()
+		if err == nil || !errors.Is(err, ErrNonceConflict) || i+1 >= attempts {
+			return tx, err
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+	}
+}
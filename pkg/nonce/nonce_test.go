@@ -0,0 +1,182 @@
+package nonce
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+)
+
+var (
+	chainID = big.NewInt(1337)
+	to      = common.HexToAddress("0x1234567890123456789012345678901234567890")
+)
+
+// newChain 创建给 key 对应账户预置余额的模拟链。
+func newChain(t *testing.T) (*simulated.Backend, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	balance := new(big.Int).Mul(big.NewInt(100), big.NewInt(1e18))
+	sim := simulated.NewBackend(types.GenesisAlloc{crypto.PubkeyToAddress(key.PublicKey): {Balance: balance}})
+	t.Cleanup(func() { sim.Close() })
+	return sim, key
+}
+
+// signTransfer 用 nonce 签一笔 1 wei 的转账，feeCap 同时作为 tip。
+func signTransfer(key *ecdsa.PrivateKey, nonce uint64, feeCap int64) *types.Transaction {
+	return types.MustSignNewTx(key, types.LatestSignerForChainID(chainID), &types.DynamicFeeTx{
+		ChainID:   chainID,
+		Nonce:     nonce,
+		GasTipCap: big.NewInt(feeCap),
+		GasFeeCap: big.NewInt(feeCap),
+		Gas:       21000,
+		To:        &to,
+		Value:     big.NewInt(1),
+	})
+}
+
+// send 从 b 分配 nonce 并发出一笔转账。
+func send(ctx context.Context, b *Backend, key *ecdsa.PrivateKey) (*types.Transaction, error) {
+	n, err := b.PendingNonceAt(ctx, crypto.PubkeyToAddress(key.PublicKey))
+	if err != nil {
+		return nil, err
+	}
+	tx := signTransfer(key, n, 10e9)
+	return tx, b.SendTransaction(ctx, tx)
+}
+
+func TestConcurrentNext(t *testing.T) {
+	sim, key := newChain(t)
+	client := sim.Client()
+	ctx := context.Background()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	b := New(client, filepath.Join(t.TempDir(), "nonces.json")).Wrap(client)
+
+	const senders = 20
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		nonces = make(map[uint64]bool)
+	)
+	for range senders {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tx, err := send(ctx, b, key)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			nonces[tx.Nonce()] = true
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	if len(nonces) != senders {
+		t.Fatalf("got %d distinct nonces from %d senders", len(nonces), senders)
+	}
+	sim.Commit()
+	if n, err := client.NonceAt(ctx, from, nil); err != nil || n != senders {
+		t.Fatalf("chain nonce %d (%v), want %d", n, err, senders)
+	}
+}
+
+func TestReleaseAfterFailedSign(t *testing.T) {
+	sim, key := newChain(t)
+	client := sim.Client()
+	ctx := context.Background()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	b := New(client, "").Wrap(client)
+
+	// 分配到 nonce 后签名失败，调用方归还 nonce
+	n, err := b.PendingNonceAt(ctx, from)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ReleaseUnsent(ctx, b, from, n); err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := send(ctx, b, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.Nonce() != n {
+		t.Fatalf("released nonce %d was not reused, got %d", n, tx.Nonce())
+	}
+	if gaps, err := b.manager.Gaps(ctx, from); err != nil || len(gaps) != 0 {
+		t.Fatalf("gaps %v (%v), want none", gaps, err)
+	}
+}
+
+func TestResyncAfterExternalSend(t *testing.T) {
+	sim, key := newChain(t)
+	client := sim.Client()
+	ctx := context.Background()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	b := New(client, filepath.Join(t.TempDir(), "nonces.json")).Wrap(client)
+
+	if _, err := send(ctx, b, key); err != nil {
+		t.Fatal(err)
+	}
+	sim.Commit()
+
+	// 另一个程序绕过 Manager 用掉了 nonce 1
+	if err := client.SendTransaction(ctx, signTransfer(key, 1, 20e9)); err != nil {
+		t.Fatal(err)
+	}
+	sim.Commit()
+
+	// Manager 仍分配 nonce 1，节点拒绝后重新同步
+	_, err := send(ctx, b, key)
+	if !errors.Is(err, ErrNonceConflict) {
+		t.Fatalf("got %v, want ErrNonceConflict", err)
+	}
+	tx, err := send(ctx, b, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.Nonce() != 2 {
+		t.Fatalf("after resync got nonce %d, want 2", tx.Nonce())
+	}
+	sim.Commit()
+	if n, err := client.NonceAt(ctx, from, nil); err != nil || n != 3 {
+		t.Fatalf("chain nonce %d (%v), want 3", n, err)
+	}
+}
+
+func TestUnderpricedReplacement(t *testing.T) {
+	sim, key := newChain(t)
+	client := sim.Client()
+	ctx := context.Background()
+	b := New(client, "").Wrap(client)
+
+	orig, err := send(ctx, b, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 调用方指定 nonce 有意替换交易，手续费涨幅不够：原样返回节点的错误
+	err = b.SendTransaction(ctx, signTransfer(key, orig.Nonce(), 10e9+1))
+	if !IsUnderpriced(err) || errors.Is(err, ErrNonceConflict) {
+		t.Fatalf("got %v, want the node's underpriced error", err)
+	}
+	tx, err := send(ctx, b, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.Nonce() != orig.Nonce()+1 {
+		t.Fatalf("got nonce %d, want %d", tx.Nonce(), orig.Nonce()+1)
+	}
+}
@@ -0,0 +1,81 @@
+// Package receipt 封装交易回执的查询以及回执日志的解码（对应 03_search_receipt），等待交易上链见 waiter 包。
+package receipt
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ydh2333/dapp_stu/pkg/abireg"
+	"github.com/ydh2333/dapp_stu/pkg/errs"
+)
+
+// BlockReceiptsReader 可以一次性获取整个区块的交易回执（eth_getBlockReceipts）。
+type BlockReceiptsReader interface {
+	BlockReceipts(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) ([]*types.Receipt, error)
+}
+
+// QueryReceipt 通过交易哈希查询交易回执。
+func QueryReceipt(ctx context.Context, client ethereum.TransactionReader, hash common.Hash) (*types.Receipt, error) {
+	receipt, err := client.TransactionReceipt(ctx, hash)
+	if err != nil {
+		return nil, errs.Wrap("receipt.QueryReceipt", err)
+	}
+	return receipt, nil
+}
+
+// QueryBlockReceipts 通过区块号或区块哈希查询区块内全部交易回执。
+func QueryBlockReceipts(ctx context.Context, client BlockReceiptsReader, blockNrOrHash rpc.BlockNumberOrHash) ([]*types.Receipt, error) {
+	receipts, err := client.BlockReceipts(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, errs.Wrap("receipt.QueryBlockReceipts", err)
+	}
+	return receipts, nil
+}
+
+// DecodeLogs 按 topic0 在注册表中查找事件定义，解码回执中的每条日志（包括从 topics 中还原的 indexed 参数）。
+// 注册表中没有的事件保留原始 topics 和 data。
+func DecodeLogs(reg *abireg.Registry, r *types.Receipt) []*abireg.DecodedLog {
+	decoded := make([]*abireg.DecodedLog, len(r.Logs))
+	for i, l := range r.Logs {
+		decoded[i] = reg.Decode(*l)
+	}
+	return decoded
+}
+
+// WriteLogs 以可读形式输出解码后的日志：已知事件逐行列出参数名、类型和值，
+// 未知事件列出原始 topics，data 按 32 字节分行输出。
+func WriteLogs(w io.Writer, logs []*abireg.DecodedLog) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, d := range logs {
+		l := d.Log
+		if d.Event == nil {
+			fmt.Fprintf(tw, "log #%d %s unknown event\n", l.Index, l.Address.Hex())
+			if d.Err != nil {
+				fmt.Fprintf(tw, "  decode error:\t%v\n", d.Err)
+			}
+			for i, t := range l.Topics {
+				fmt.Fprintf(tw, "  topic%d\t%s\n", i, t.Hex())
+			}
+			for i := 0; i < len(l.Data); i += 32 {
+				fmt.Fprintf(tw, "  data[%d]\t%s\n", i/32, hexutil.Encode(l.Data[i:min(i+32, len(l.Data))]))
+			}
+			continue
+		}
+		fmt.Fprintf(tw, "log #%d %s %s.%s\n", l.Index, l.Address.Hex(), d.Event.Contract, d.Event.Sig)
+		for _, a := range d.Args {
+			kind := a.Type.String()
+			if a.Indexed {
+				kind += " indexed"
+			}
+			fmt.Fprintf(tw, "  %s\t%s\t%s\n", a.Name, kind, abireg.FormatValue(a.Value))
+		}
+	}
+	return tw.Flush()
+}
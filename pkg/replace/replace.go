@@ -0,0 +1,237 @@
+// Package replace 处理卡住的交易：用同一 nonce、更高手续费重新签名来加速（replace-by-fee），
+// 或发送同一 nonce 的 0 金额自转账来取消；Scan 比较账户的 pending / latest nonce，
+// 列出正在阻塞后续交易的 nonce。
+package replace
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"math/big"
+	"slices"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ydh2333/dapp_stu/pkg/errs"
+	"github.com/ydh2333/dapp_stu/pkg/feeoracle"
+	"github.com/ydh2333/dapp_stu/pkg/guard"
+	"github.com/ydh2333/dapp_stu/pkg/signer"
+	"github.com/ydh2333/dapp_stu/pkg/txbuilder"
+)
+
+// PriceBump 是节点交易池的默认涨价规则（geth 的 --txpool.pricebump）：
+// 替换交易的 gasPrice，或 maxFeePerGas 与 maxPriorityFeePerGas 两者，都必须至少上涨该百分比。
+const PriceBump = 10
+
+// cancelGas 是 0 金额自转账消耗的 gas。
+const cancelGas = 21000
+
+// Backend 是替换交易所需的节点能力，*ethclient.Client 和模拟链客户端都满足该接口。
+type Backend interface {
+	txbuilder.Backend
+	ethereum.FeeHistoryReader
+	ethereum.TransactionReader
+}
+
+// Bump 返回 old 上涨 percent% 后的值（向上取整），保证满足节点的 >= 比较。
+func Bump(old *big.Int, percent int64) *big.Int {
+	v := new(big.Int).Mul(old, big.NewInt(100+percent))
+	v.Add(v, big.NewInt(99))
+	return v.Div(v, big.NewInt(100))
+}
+
+// SpeedUp 用更高的手续费重新签名 hash 对应的待处理交易，nonce、接收方、金额、数据均不变。
+// 新的手续费取「旧值按涨价规则上调」和 feeoracle 按 strategy 给出的当前建议值中较大者。
+func SpeedUp(ctx context.Context, backend Backend, g *guard.Guard, s signer.Signer, hash common.Hash, strategy feeoracle.Strategy) (*types.Transaction, error) {
+	const op = "replace.SpeedUp"
+
+	old, err := pendingTx(ctx, backend, g, s, hash)
+	if err != nil {
+		return nil, errs.Wrap(op, err)
+	}
+	spec := txbuilder.Spec{
+		To:         old.To(),
+		Value:      old.Value(),
+		Data:       old.Data(),
+		AccessList: old.AccessList(),
+		AuthList:   old.SetCodeAuthorizations(),
+		Gas:        old.Gas(),
+	}
+	return send(ctx, op, backend, g, s, old, spec, strategy)
+}
+
+// Cancel 发送与 hash 对应交易同一 nonce 的 0 金额自转账，手续费按涨价规则上调，
+// 被打包后原交易即失效。
+func Cancel(ctx context.Context, backend Backend, g *guard.Guard, s signer.Signer, hash common.Hash, strategy feeoracle.Strategy) (*types.Transaction, error) {
+	const op = "replace.Cancel"
+
+	old, err := pendingTx(ctx, backend, g, s, hash)
+	if err != nil {
+		return nil, errs.Wrap(op, err)
+	}
+	self := s.Address()
+	spec := txbuilder.Spec{
+		To:    &self,
+		Value: new(big.Int),
+		Gas:   cancelGas,
+	}
+	return send(ctx, op, backend, g, s, old, spec, strategy)
+}
+
+// pendingTx 查询 hash 对应的交易，确认它仍在交易池中、由 s 发送，且不是 blob 交易：
+// 交易池只接受用 blob 交易替换 blob 交易，而节点返回的交易不带 blob 数据，无法据此重新签名，
+// 也无法构造 0 金额的普通交易来取消它。
+func pendingTx(ctx context.Context, backend Backend, g *guard.Guard, s signer.Signer, hash common.Hash) (*types.Transaction, error) {
+	tx, isPending, err := backend.TransactionByHash(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	if !isPending {
+		return nil, fmt.Errorf("%w: transaction %s is already mined", errs.ErrInvalidArgument, hash.Hex())
+	}
+	from, err := types.Sender(g.Signer(), tx)
+	if err != nil {
+		return nil, err
+	}
+	if from != s.Address() {
+		return nil, fmt.Errorf("%w: transaction %s was sent by %s, not %s", errs.ErrInvalidArgument, hash.Hex(), from.Hex(), s.Address().Hex())
+	}
+	if tx.Type() == types.BlobTxType {
+		return nil, fmt.Errorf("%w: blob transaction %s can only be replaced by a blob transaction carrying its sidecar", errs.ErrInvalidArgument, hash.Hex())
+	}
+	return tx, nil
+}
+
+// send 以 old 的 nonce 和上调后的手续费填充 spec 并发送。
+func send(ctx context.Context, op string, backend Backend, g *guard.Guard, s signer.Signer, old *types.Transaction, spec txbuilder.Spec, strategy feeoracle.Strategy) (*types.Transaction, error) {
+	nonce := old.Nonce()
+	spec.Nonce = &nonce
+
+	switch old.Type() {
+	case types.LegacyTxType, types.AccessListTxType:
+		spec.Mode = txbuilder.Legacy
+		if old.Type() == types.AccessListTxType {
+			spec.Mode = txbuilder.AccessList
+		}
+		suggested, err := backend.SuggestGasPrice(ctx)
+		if err != nil {
+			return nil, errs.Wrap(op, err)
+		}
+		spec.GasPrice = maxBig(Bump(old.GasPrice(), PriceBump), suggested)
+	default:
+		spec.Mode = txbuilder.DynamicFee
+		if len(spec.AuthList) > 0 {
+			spec.Mode = txbuilder.SetCode
+		}
+		fees, err := feeoracle.New(backend).Suggest(ctx, strategy)
+		if err != nil {
+			return nil, errs.Wrap(op, err)
+		}
+		spec.GasTipCap = maxBig(Bump(old.GasTipCap(), PriceBump), fees.GasTipCap)
+		spec.GasFeeCap = maxBig(Bump(old.GasFeeCap(), PriceBump), fees.GasFeeCap)
+	}
+	signedTx, err := txbuilder.New(backend, g).Send(ctx, s, spec)
+	if err != nil {
+		return nil, errs.Wrap(op, err)
+	}
+	return signedTx, nil
+}
+
+// maxBig 返回 a、b 中较大者。
+func maxBig(a, b *big.Int) *big.Int {
+	if a.Cmp(b) >= 0 {
+		return a
+	}
+	return new(big.Int).Set(b)
+}
+
+// PoolReader 调用节点的 txpool 命名空间（如 geth 的 txpool_contentFrom），
+// *rpc.Client（ethclient.Client.Client()）满足该接口。
+type PoolReader interface {
+	CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error
+}
+
+// NonceReader 是 Scan 所需的 nonce 查询能力。
+type NonceReader interface {
+	NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error)
+	PendingNonceAt(ctx context.Context, account common.Address) (uint64, error)
+}
+
+// Report 是一个账户的卡单扫描结果。
+type Report struct {
+	Account      common.Address
+	LatestNonce  uint64 // 已上链的交易数，即下一笔会被打包的 nonce
+	PendingNonce uint64 // 计入交易池中可执行交易后的下一个 nonce
+
+	// Pending 和 Queued 是交易池中该账户的交易（按 nonce 排序），需要节点开放 txpool 接口。
+	// Pending 中 nonce 最小的一笔就是正在阻塞其余交易的那笔；Queued 中的交易因前面缺少 nonce 而无法执行。
+	Pending []*types.Transaction
+	Queued  []*types.Transaction
+	Gaps    []uint64 // 阻塞 Queued 交易的缺失 nonce
+	PoolErr error    // 查询 txpool 失败时的错误，此时只有 nonce 信息
+}
+
+// Stuck 报告是否有交易在等待打包。
+func (r *Report) Stuck() bool {
+	return r.PendingNonce > r.LatestNonce || len(r.Queued) > 0
+}
+
+// Blocking 返回阻塞该账户的 nonce：已进入交易池但迟迟未打包的最小 nonce，以及缺失的 nonce。
+func (r *Report) Blocking() []uint64 {
+	var blocking []uint64
+	if r.PendingNonce > r.LatestNonce {
+		blocking = append(blocking, r.LatestNonce)
+	}
+	return append(blocking, r.Gaps...)
+}
+
+// Scan 比较 account 的 latest 和 pending nonce，pool 不为 nil 时再查询交易池列出具体交易和缺失的 nonce。
+func Scan(ctx context.Context, client NonceReader, pool PoolReader, account common.Address) (*Report, error) {
+	const op = "replace.Scan"
+
+	latest, err := client.NonceAt(ctx, account, nil)
+	if err != nil {
+		return nil, errs.Wrap(op, err)
+	}
+	pending, err := client.PendingNonceAt(ctx, account)
+	if err != nil {
+		return nil, errs.Wrap(op, err)
+	}
+	r := &Report{Account: account, LatestNonce: latest, PendingNonce: pending}
+	if pool == nil {
+		return r, nil
+	}
+	var content map[string]map[string]*types.Transaction
+	if err := pool.CallContext(ctx, &content, "txpool_contentFrom", account); err != nil {
+		r.PoolErr = err
+		return r, nil
+	}
+	r.Pending = sortByNonce(content["pending"])
+	r.Queued = sortByNonce(content["queued"])
+
+	// queued 交易之前缺失的 nonce
+	next := pending
+	for _, tx := range r.Queued {
+		for ; next < tx.Nonce(); next++ {
+			r.Gaps = append(r.Gaps, next)
+		}
+		next = tx.Nonce() + 1
+	}
+	return r, nil
+}
+
+// sortByNonce 把 txpool 返回的 nonce → 交易映射转换为按 nonce 排序的切片。
+func sortByNonce(m map[string]*types.Transaction) []*types.Transaction {
+	txs := make([]*types.Transaction, 0, len(m))
+	for key, tx := range m {
+		if _, err := strconv.ParseUint(key, 10, 64); err == nil && tx != nil {
+			txs = append(txs, tx)
+		}
+	}
+	slices.SortFunc(txs, func(a, b *types.Transaction) int {
+		return cmp.Compare(a.Nonce(), b.Nonce())
+	})
+	return txs
+}
@@ -0,0 +1,415 @@
+// Package scanner 并发扫描一段区块范围：固定数量的 worker 拉取区块头、区块和回执，按区块号顺序输出结果，
+// 支持请求限速，并把进度写入断点文件，进程中途退出后可以从断点继续。
+package scanner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ydh2333/dapp_stu/pkg/errs"
+)
+
+// Mode 决定每个区块拉取哪些数据。
+type Mode int
+
+const (
+	Headers  Mode = iota // 只取区块头（HeaderByNumber）
+	Blocks               // 完整区块（BlockByNumber）
+	Receipts             // 完整区块及全部回执
+)
+
+// 默认参数。
+const (
+	DefaultWorkers         = 8
+	DefaultRetries         = 3
+	DefaultCheckpointEvery = 100
+)
+
+// MaxRate 是 Options.Rate 的上限：限流间隔以纳秒为单位，每秒超过 1e9 个请求时间隔为 0。
+const MaxRate = 1e9
+
+// Options 配置扫描行为，零值字段使用默认值。
+type Options struct {
+	Mode    Mode
+	Workers int     // 并发 worker 数量
+	Rate    float64 // 每秒最多发出的 RPC 请求数，0 表示不限速，不能超过 MaxRate
+	Retries int     // 单个区块拉取失败后的重试次数，负数表示不重试
+
+	// Checkpoint 是断点文件路径，为空表示不保存进度。
+	// 每输出 CheckpointEvery 个区块以及扫描结束、出错退出时写入一次，
+	// 因此续扫时可能重复输出上次最后不到 CheckpointEvery 个区块。
+	Checkpoint      string
+	CheckpointEvery uint64
+}
+
+// Result 是一个区块的扫描结果。
+type Result struct {
+	Number   uint64
+	Header   *types.Header
+	Block    *types.Block     // Mode 为 Blocks、Receipts 时有值
+	Receipts []*types.Receipt // Mode 为 Receipts 时有值，与区块内交易一一对应
+}
+
+// Backend 是扫描所需的节点能力，*ethclient.Client 和模拟链客户端都满足该接口。
+// 如果实现了 BlockReceipts（如 *ethclient.Client），优先用 eth_getBlockReceipts 一次取回整块回执。
+type Backend interface {
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error)
+	TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
+}
+
+type blockReceiptsReader interface {
+	BlockReceipts(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) ([]*types.Receipt, error)
+}
+
+// Checkpoint 记录扫描进度：From 到 Next-1 的区块都已输出。
+type Checkpoint struct {
+	From uint64 `json:"from"`
+	To   uint64 `json:"to"`
+	Next uint64 `json:"next"`
+}
+
+// LoadCheckpoint 读取断点文件，文件不存在时返回 nil。
+func LoadCheckpoint(path string) (*Checkpoint, error) {
+	const op = "scanner.LoadCheckpoint"
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errs.Wrap(op, err)
+	}
+	var cp Checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, errs.Wrap(op, fmt.Errorf("%s: %w", path, err))
+	}
+	return &cp, nil
+}
+
+// save 先写临时文件再重命名，避免进程中途退出留下损坏的断点文件。
+func (cp *Checkpoint) save(path string) error {
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Scanner 扫描区块范围。同一个 Scanner 不能同时执行多次 Run。
+type Scanner struct {
+	backend Backend
+	opts    Options
+	limiter *limiter
+
+	requests        atomic.Uint64
+	noBlockReceipts atomic.Bool // 节点不支持 eth_getBlockReceipts，改为逐笔查询
+}
+
+// New 创建扫描器。
+func New(backend Backend, opts Options) *Scanner {
+	if opts.Workers <= 0 {
+		opts.Workers = DefaultWorkers
+	}
+	if opts.Retries < 0 {
+		opts.Retries = 0
+	} else if opts.Retries == 0 {
+		opts.Retries = DefaultRetries
+	}
+	if opts.CheckpointEvery == 0 {
+		opts.CheckpointEvery = DefaultCheckpointEvery
+	}
+	return &Scanner{backend: backend, opts: opts}
+}
+
+// Requests 返回已发出的 RPC 请求数。
+func (s *Scanner) Requests() uint64 {
+	return s.requests.Load()
+}
+
+// BlockReceiptsSupported 报告是否在用 eth_getBlockReceipts 拉取回执。
+func (s *Scanner) BlockReceiptsSupported() bool {
+	_, ok := s.backend.(blockReceiptsReader)
+	return ok && !s.noBlockReceipts.Load()
+}
+
+// Run 扫描 [from, to] 内的区块，按区块号从小到大把结果发送到 out，全部输出后返回 nil。
+// 断点文件中的起点与 from 相同时从断点继续（to 可以与上次不同）；ctx 取消或某个区块重试后仍失败时返回错误，
+// 此时断点文件记录的是已输出的位置。
+func (s *Scanner) Run(ctx context.Context, from, to uint64, out chan<- *Result) error {
+	const op = "scanner.Run"
+
+	if from > to {
+		return errs.Invalid(op, fmt.Sprintf("from %d is after to %d", from, to))
+	}
+	if r := s.opts.Rate; math.IsNaN(r) || r > MaxRate {
+		return errs.Invalid(op, fmt.Sprintf("rate %v must be at most %g requests per second", r, float64(MaxRate)))
+	}
+	cp := &Checkpoint{From: from, To: to, Next: from}
+	if s.opts.Checkpoint != "" {
+		saved, err := LoadCheckpoint(s.opts.Checkpoint)
+		if err != nil {
+			return err
+		}
+		if saved != nil && saved.From == from && saved.Next > from {
+			cp.Next = saved.Next
+		}
+	}
+	save := func() error {
+		if s.opts.Checkpoint == "" {
+			return nil
+		}
+		return errs.Wrap(op, cp.save(s.opts.Checkpoint))
+	}
+	if cp.Next > to {
+		return save()
+	}
+
+	s.limiter = newLimiter(s.opts.Rate)
+	defer s.limiter.stop()
+
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	defer cancel()
+
+	// 最多同时有 window 个区块在拉取或等待按序输出，避免前面的区块很慢时结果无限堆积
+	window := s.opts.Workers * 4
+	tokens := make(chan struct{}, window)
+	jobs := make(chan uint64)
+	results := make(chan *Result, window)
+	errc := make(chan error, 1)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(jobs)
+		for n := cp.Next; n <= to; n++ {
+			select {
+			case tokens <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case jobs <- n:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	for i := 0; i < s.opts.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := range jobs {
+				r, err := s.fetch(ctx, n)
+				if err != nil {
+					if ctx.Err() != nil {
+						return
+					}
+					select {
+					case errc <- errs.Wrap(op, fmt.Errorf("block %d: %w", n, err)):
+					default:
+					}
+					cancel()
+					return
+				}
+				select {
+				case results <- r:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	stopped := func() error {
+		err := ctx.Err()
+		select {
+		case err = <-errc:
+		default:
+		}
+		if serr := save(); serr != nil {
+			return serr
+		}
+		return err
+	}
+	pending := make(map[uint64]*Result)
+	for cp.Next <= to {
+		select {
+		case r := <-results:
+			pending[r.Number] = r
+		case <-ctx.Done():
+			return stopped()
+		}
+		for r, ok := pending[cp.Next]; ok; r, ok = pending[cp.Next] {
+			select {
+			case out <- r:
+			case <-ctx.Done():
+				return stopped()
+			}
+			delete(pending, cp.Next)
+			<-tokens
+			cp.Next++
+			if (cp.Next-from)%s.opts.CheckpointEvery == 0 {
+				if err := save(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return save()
+}
+
+// fetch 拉取一个区块的数据，失败后按指数退避重试。
+func (s *Scanner) fetch(ctx context.Context, n uint64) (*Result, error) {
+	backoff := 500 * time.Millisecond
+	for attempt := 0; ; attempt++ {
+		r, err := s.fetchOnce(ctx, n)
+		if err == nil || attempt >= s.opts.Retries || ctx.Err() != nil {
+			return r, err
+		}
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		backoff *= 2
+	}
+}
+
+func (s *Scanner) fetchOnce(ctx context.Context, n uint64) (*Result, error) {
+	number := new(big.Int).SetUint64(n)
+	if s.opts.Mode == Headers {
+		if err := s.wait(ctx); err != nil {
+			return nil, err
+		}
+		h, err := s.backend.HeaderByNumber(ctx, number)
+		if err != nil {
+			return nil, err
+		}
+		return &Result{Number: n, Header: h}, nil
+	}
+
+	if err := s.wait(ctx); err != nil {
+		return nil, err
+	}
+	b, err := s.backend.BlockByNumber(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	r := &Result{Number: n, Header: b.Header(), Block: b}
+	if s.opts.Mode == Receipts {
+		if r.Receipts, err = s.receipts(ctx, b); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// receipts 优先用 eth_getBlockReceipts 按区块哈希取回整块回执（与区块体一致，不受重组影响），
+// 节点不支持该方法时改为逐笔调用 TransactionReceipt。
+func (s *Scanner) receipts(ctx context.Context, b *types.Block) ([]*types.Receipt, error) {
+	txs := b.Transactions()
+	if len(txs) == 0 {
+		return nil, nil
+	}
+	if br, ok := s.backend.(blockReceiptsReader); ok && !s.noBlockReceipts.Load() {
+		if err := s.wait(ctx); err != nil {
+			return nil, err
+		}
+		rs, err := br.BlockReceipts(ctx, rpc.BlockNumberOrHashWithHash(b.Hash(), false))
+		switch {
+		case err == nil && len(rs) == len(txs):
+			return rs, nil
+		case err == nil:
+			return nil, fmt.Errorf("eth_getBlockReceipts returned %d receipts for %d transactions", len(rs), len(txs))
+		case !unsupported(err):
+			return nil, err
+		}
+		s.noBlockReceipts.Store(true)
+	}
+
+	rs := make([]*types.Receipt, len(txs))
+	for i, tx := range txs {
+		if err := s.wait(ctx); err != nil {
+			return nil, err
+		}
+		r, err := s.backend.TransactionReceipt(ctx, tx.Hash())
+		if err != nil {
+			return nil, fmt.Errorf("receipt %s: %w", tx.Hash().Hex(), err)
+		}
+		rs[i] = r
+	}
+	return rs, nil
+}
+
+// unsupported 判断错误是否表示节点没有提供该 RPC 方法。
+func unsupported(err error) bool {
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) && rpcErr.ErrorCode() == -32601 {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	for _, s := range []string{"method not found", "does not exist", "not supported", "unsupported", "not available"} {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// wait 在发出请求前等待限速器放行，并计数。
+func (s *Scanner) wait(ctx context.Context) error {
+	if err := s.limiter.wait(ctx); err != nil {
+		return err
+	}
+	s.requests.Add(1)
+	return nil
+}
+
+// limiter 是简单的匀速限流器：每 1/rate 秒放行一个请求，nil 表示不限速。
+type limiter struct {
+	ticker *time.Ticker
+}
+
+func newLimiter(rate float64) *limiter {
+	if rate <= 0 {
+		return nil
+	}
+	return &limiter{ticker: time.NewTicker(time.Duration(float64(time.Second) / rate))}
+}
+
+func (l *limiter) wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	select {
+	case <-l.ticker.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (l *limiter) stop() {
+	if l != nil {
+		l.ticker.Stop()
+	}
+}
@@ -0,0 +1,138 @@
+// Package signer 提供交易签名账户的抽象。默认从 Web3 Secret Storage 格式的 keystore 文件
+// 加载私钥并用口令解锁；直接使用 PRIVATE_KEY 十六进制私钥只在显式开启 --insecure-private-key 时允许。
+package signer
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/console/prompt"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ydh2333/dapp_stu/pkg/config"
+	"github.com/ydh2333/dapp_stu/pkg/errs"
+)
+
+// ErrNoSigner 表示既没有配置 keystore，也没有显式允许使用原始私钥。
+var ErrNoSigner = errors.New("no signer configured: set --keystore/KEYSTORE, or pass --insecure-private-key to use PRIVATE_KEY")
+
+// Signer 是一个可以签名的以太坊账户。
+type Signer interface {
+	// Address 返回签名账户的地址。
+	Address() common.Address
+	// SignHash 对 32 字节哈希签名，返回 65 字节 [R || S || V] 签名，V 为 0 或 1。
+	SignHash(hash []byte) ([]byte, error)
+	// SignTx 用 txSigner 规定的规则（链 ID、交易类型）为交易签名。
+	SignTx(tx *types.Transaction, txSigner types.Signer) (*types.Transaction, error)
+}
+
+// keySigner 是持有已解锁私钥的 Signer。
+type keySigner struct {
+	key     *ecdsa.PrivateKey
+	address common.Address
+}
+
+// FromKey 用内存中的私钥创建 Signer，适合测试或已由调用方妥善保管的私钥。
+func FromKey(key *ecdsa.PrivateKey) Signer {
+	return &keySigner{key: key, address: crypto.PubkeyToAddress(key.PublicKey)}
+}
+
+// FromHex 解析十六进制私钥（可带 0x 前缀）创建 Signer。明文私钥不安全，仅用于本地调试。
+func FromHex(hexKey string) (Signer, error) {
+	key, err := crypto.HexToECDSA(strings.TrimPrefix(strings.TrimSpace(hexKey), "0x"))
+	if err != nil {
+		return nil, errs.Wrap("signer.FromHex", err)
+	}
+	return FromKey(key), nil
+}
+
+func (s *keySigner) Address() common.Address {
+	return s.address
+}
+
+func (s *keySigner) SignHash(hash []byte) ([]byte, error) {
+	sig, err := crypto.Sign(hash, s.key)
+	if err != nil {
+		return nil, errs.Wrap("signer.SignHash", err)
+	}
+	return sig, nil
+}
+
+func (s *keySigner) SignTx(tx *types.Transaction, txSigner types.Signer) (*types.Transaction, error) {
+	signedTx, err := types.SignTx(tx, txSigner, s.key)
+	if err != nil {
+		return nil, errs.Wrap("signer.SignTx", err)
+	}
+	return signedTx, nil
+}
+
+// LoadKeystore 读取 keystore 文件并用 passphrase 解密私钥。
+func LoadKeystore(path, passphrase string) (Signer, error) {
+	const op = "signer.LoadKeystore"
+
+	keyJSON, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errs.Wrap(op, err)
+	}
+	key, err := keystore.DecryptKey(keyJSON, passphrase)
+	if err != nil {
+		return nil, errs.Wrap(op, fmt.Errorf("%s: %w", path, err))
+	}
+	return &keySigner{key: key.PrivateKey, address: key.Address}, nil
+}
+
+// ImportKey 把私钥用 passphrase 加密后写入 dir 目录下的新 keystore 文件，返回文件路径。
+// 用于把原来 .env 中的明文私钥迁移到 keystore。
+func ImportKey(dir string, key *ecdsa.PrivateKey, passphrase string) (string, error) {
+	ks := keystore.NewKeyStore(dir, keystore.StandardScryptN, keystore.StandardScryptP)
+	account, err := ks.ImportECDSA(key, passphrase)
+	if err != nil {
+		return "", errs.Wrap("signer.ImportKey", err)
+	}
+	return account.URL.Path, nil
+}
+
+// FromConfig 按配置加载签名账户：
+//   - 配置了 keystore 时，口令依次取自口令文件、KEYSTORE_PASSPHRASE 环境变量、终端输入；
+//   - 否则仅在 cfg.InsecurePrivateKey 为 true 时读取 PRIVATE_KEY 明文私钥；
+//   - 两者都没有时返回 ErrNoSigner。
+func FromConfig(cfg *config.Config) (Signer, error) {
+	const op = "signer.FromConfig"
+
+	if cfg.Keystore != "" {
+		passphrase, err := passphrase(cfg)
+		if err != nil {
+			return nil, errs.Wrap(op, err)
+		}
+		return LoadKeystore(cfg.Keystore, passphrase)
+	}
+	if !cfg.InsecurePrivateKey {
+		return nil, errs.Wrap(op, ErrNoSigner)
+	}
+	hexKey := os.Getenv("PRIVATE_KEY")
+	if hexKey == "" {
+		return nil, errs.Invalid(op, "PRIVATE_KEY is not set")
+	}
+	fmt.Fprintln(os.Stderr, "WARNING: signing with a plaintext PRIVATE_KEY (--insecure-private-key); use a keystore file instead")
+	return FromHex(hexKey)
+}
+
+// passphrase 读取 keystore 口令。
+func passphrase(cfg *config.Config) (string, error) {
+	if cfg.PassphraseFile != "" {
+		data, err := os.ReadFile(cfg.PassphraseFile)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+	if v, ok := os.LookupEnv("KEYSTORE_PASSPHRASE"); ok {
+		return v, nil
+	}
+	return prompt.Stdin.PromptPassword("Keystore passphrase: ")
+}
@@ -0,0 +1,230 @@
+// Package snapshot 通过重放 Transfer 事件重建 ERC20 代币在任意区块的全部持有人余额，
+// 用于空投、治理投票权等需要某一区块持仓快照的场景；重建结果可以抽样与链上 BalanceOf、
+// TotalSupply 对照，并导出带排名的 CSV / JSON。
+package snapshot
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	token "github.com/ydh2333/dapp_stu/08_search_token_balance/erc20"
+	"github.com/ydh2333/dapp_stu/pkg/balance"
+	"github.com/ydh2333/dapp_stu/pkg/errs"
+	"github.com/ydh2333/dapp_stu/pkg/tokenhistory"
+)
+
+// Holder 是快照中的一个持有人。余额相同的持有人排名相同。
+type Holder struct {
+	Rank    int            `json:"rank"`
+	Address common.Address `json:"address"`
+	Balance *big.Int       `json:"balance"`
+	Share   float64        `json:"share"` // 占全部持仓的比例，0 ~ 1
+}
+
+// Snapshot 是代币在 Block 区块结束时的持仓快照。
+type Snapshot struct {
+	Token     common.Address   `json:"token"`
+	From      uint64           `json:"fromBlock"` // 重放的第一个区块，应不晚于代币合约的部署区块
+	Block     uint64           `json:"block"`
+	Transfers int              `json:"transfers"`
+	Total     *big.Int         `json:"total"`              // 全部持有人余额之和
+	Negative  []common.Address `json:"negative,omitempty"` // 重放后余额为负的地址，通常说明 From 晚于首次铸币
+	Holders   []*Holder        `json:"holders"`
+}
+
+// Replayer 按顺序累计 Transfer 事件得到各地址余额。零地址（铸币来源、销毁去向）不计入持有人。
+type Replayer struct {
+	balances  map[common.Address]*big.Int
+	transfers int
+}
+
+// NewReplayer 创建空的余额累计器。
+func NewReplayer() *Replayer {
+	return &Replayer{balances: make(map[common.Address]*big.Int)}
+}
+
+// Apply 累计一条事件，Approval 会被忽略。
+func (r *Replayer) Apply(e *tokenhistory.Event) {
+	if e.Kind != tokenhistory.Transfer {
+		return
+	}
+	r.transfers++
+	r.add(e.From, new(big.Int).Neg(e.Value))
+	r.add(e.To, e.Value)
+}
+
+func (r *Replayer) add(addr common.Address, delta *big.Int) {
+	if addr == (common.Address{}) {
+		return
+	}
+	bal, ok := r.balances[addr]
+	if !ok {
+		bal = new(big.Int)
+		r.balances[addr] = bal
+	}
+	bal.Add(bal, delta)
+}
+
+// Snapshot 按当前累计的余额生成快照：去掉余额为 0 的地址，按余额从高到低排名。
+func (r *Replayer) Snapshot(tokenAddress common.Address, from, block uint64) *Snapshot {
+	s := &Snapshot{Token: tokenAddress, From: from, Block: block, Transfers: r.transfers, Total: new(big.Int)}
+	for addr, bal := range r.balances {
+		switch bal.Sign() {
+		case 1:
+			s.Holders = append(s.Holders, &Holder{Address: addr, Balance: new(big.Int).Set(bal)})
+			s.Total.Add(s.Total, bal)
+		case -1:
+			s.Negative = append(s.Negative, addr)
+		}
+	}
+	slices.SortFunc(s.Negative, func(a, b common.Address) int { return bytes.Compare(a[:], b[:]) })
+	slices.SortFunc(s.Holders, func(a, b *Holder) int {
+		return cmp.Or(b.Balance.Cmp(a.Balance), bytes.Compare(a.Address[:], b.Address[:]))
+	})
+	total := new(big.Float).SetInt(s.Total)
+	for i, h := range s.Holders {
+		h.Rank = i + 1
+		if i > 0 && h.Balance.Cmp(s.Holders[i-1].Balance) == 0 {
+			h.Rank = s.Holders[i-1].Rank
+		}
+		h.Share, _ = new(big.Float).Quo(new(big.Float).SetInt(h.Balance), total).Float64()
+	}
+	return s
+}
+
+// Build 用 erc20 绑定的 FilterTransfer 分段拉取 [from, block] 内的 Transfer 事件并重放，得到 block 处的快照。
+// from 应不晚于代币合约的部署区块，否则之前的转账不会计入；分段大小和缩段行为由 opts 控制。
+func Build(ctx context.Context, backend bind.ContractFilterer, tokenAddress common.Address, from, block uint64, opts tokenhistory.Options) (*Snapshot, error) {
+	r := NewReplayer()
+	opts.SkipApprovals = true
+	err := tokenhistory.Scan(ctx, backend, tokenAddress, from, block, opts, func(_, _ uint64, events []*tokenhistory.Event) error {
+		for _, e := range events {
+			r.Apply(e)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errs.Wrap("snapshot.Build", err)
+	}
+	return r.Snapshot(tokenAddress, from, block), nil
+}
+
+// FromStore 用 tokenhistory 历史库中已索引的事件重建快照，历史库需要覆盖 [起始区块, block]。
+func FromStore(store *tokenhistory.Store, tokenAddress common.Address, block uint64) (*Snapshot, error) {
+	const op = "snapshot.FromStore"
+
+	start, _ := store.Start(tokenAddress)
+	head, _, ok := store.Head(tokenAddress)
+	if !ok || head < block {
+		return nil, errs.Invalid(op, fmt.Sprintf("history of %s does not reach block %d", tokenAddress.Hex(), block))
+	}
+	events, err := store.Events(tokenAddress, start, block)
+	if err != nil {
+		return nil, errs.Wrap(op, err)
+	}
+	r := NewReplayer()
+	for _, e := range events {
+		r.Apply(e)
+	}
+	return r.Snapshot(tokenAddress, start, block), nil
+}
+
+// Mismatch 是重建余额与链上 BalanceOf 不一致的地址。
+type Mismatch struct {
+	Address  common.Address `json:"address"`
+	Snapshot *big.Int       `json:"snapshot"`
+	OnChain  *big.Int       `json:"onChain"`
+}
+
+// Verification 是快照与链上状态的对照结果。
+type Verification struct {
+	Checked       int         `json:"checked"`
+	Mismatches    []*Mismatch `json:"mismatches,omitempty"`
+	TotalSupply   *big.Int    `json:"totalSupply"`
+	SupplyMatches bool        `json:"supplyMatches"`
+}
+
+// OK 报告抽样余额与总供应量是否全部一致。
+func (v *Verification) OK() bool {
+	return len(v.Mismatches) == 0 && v.SupplyMatches
+}
+
+// Verify 在快照区块上调用 BalanceOf（CallOpts.BlockNumber）核对 sample 个持有人：排名前一半，
+// 其余从剩下的持有人中按区块号为种子随机抽取，便于复现；并核对持仓之和与 TotalSupply。
+// sample 不大于 0 时只核对 TotalSupply。查询历史区块的状态需要归档节点。
+func (s *Snapshot) Verify(ctx context.Context, caller bind.ContractCaller, sample int) (*Verification, error) {
+	const op = "snapshot.Verify"
+
+	instance, err := token.NewErc20Caller(s.Token, caller)
+	if err != nil {
+		return nil, errs.Wrap(op, err)
+	}
+	opts := &bind.CallOpts{Context: ctx, BlockNumber: new(big.Int).SetUint64(s.Block)}
+	supply, err := instance.TotalSupply(opts)
+	if err != nil {
+		return nil, errs.Wrap(op, err)
+	}
+	v := &Verification{TotalSupply: supply, SupplyMatches: supply.Cmp(s.Total) == 0}
+	for _, h := range s.sample(sample) {
+		bal, err := instance.BalanceOf(opts, h.Address)
+		if err != nil {
+			return nil, errs.Wrap(op, fmt.Errorf("balanceOf %s: %w", h.Address.Hex(), err))
+		}
+		v.Checked++
+		if bal.Cmp(h.Balance) != 0 {
+			v.Mismatches = append(v.Mismatches, &Mismatch{Address: h.Address, Snapshot: h.Balance, OnChain: bal})
+		}
+	}
+	return v, nil
+}
+
+// sample 选出要核对的持有人：前 n/2 名加上从其余持有人中随机抽取的部分。
+func (s *Snapshot) sample(n int) []*Holder {
+	if n <= 0 {
+		return nil
+	}
+	if n >= len(s.Holders) {
+		return s.Holders
+	}
+	top := n / 2
+	picked := slices.Clone(s.Holders[:top])
+	rest := slices.Clone(s.Holders[top:])
+	rng := rand.New(rand.NewPCG(s.Block, 0))
+	rng.Shuffle(len(rest), func(i, j int) { rest[i], rest[j] = rest[j], rest[i] })
+	return append(picked, rest[:n-top]...)
+}
+
+// WriteCSV 导出 rank,address,balance,amount,share 五列，amount 为按 decimals 换算后的数量。
+func (s *Snapshot) WriteCSV(w io.Writer, decimals uint8) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"rank", "address", "balance", "amount", "share"})
+	for _, h := range s.Holders {
+		cw.Write([]string{
+			strconv.Itoa(h.Rank),
+			h.Address.Hex(),
+			h.Balance.String(),
+			balance.ToUnits(h.Balance, int(decimals)).Text('f', int(decimals)),
+			strconv.FormatFloat(h.Share, 'f', 8, 64),
+		})
+	}
+	cw.Flush()
+	return errs.Wrap("snapshot.WriteCSV", cw.Error())
+}
+
+// WriteJSON 以缩进的 JSON 导出整个快照。
+func (s *Snapshot) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return errs.Wrap("snapshot.WriteJSON", enc.Encode(s))
+}
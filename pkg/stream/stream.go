@@ -0,0 +1,414 @@
+// Package stream 提供不怕断线的区块与日志流：WebSocket 连接断开或长时间收不到新区块时
+// 按指数退避重连，并补齐断线期间错过的区块头和日志；节点只提供 HTTP 时退回轮询
+// （eth_newBlockFilter + eth_getFilterChanges，过滤器不可用时轮询最新区块号）。
+// 无论哪种方式，消费者都只从一个按区块顺序排列的通道接收事件，重组由 headtracker 识别。
+package stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ydh2333/dapp_stu/pkg/errs"
+	"github.com/ydh2333/dapp_stu/pkg/headtracker"
+)
+
+// ErrStalled 表示订阅连接在 StallTimeout 内没有收到任何新区块，视为连接已失效。
+var ErrStalled = errors.New("no new head within stall timeout")
+
+// Mode 是当前获取新区块的方式。
+type Mode int
+
+const (
+	Subscribe  Mode = iota // eth_subscribe newHeads（WebSocket / IPC）
+	PollFilter             // eth_newBlockFilter + eth_getFilterChanges（HTTP）
+	PollNumber             // 轮询最新区块号（HTTP，节点不支持过滤器时）
+)
+
+var modeNames = [...]string{"subscribe", "poll-filter", "poll-number"}
+
+func (m Mode) String() string {
+	if int(m) < len(modeNames) {
+		return modeNames[m]
+	}
+	return fmt.Sprintf("Mode(%d)", int(m))
+}
+
+// Event 是流中的一个区块。区块按高度升序到达；发生重组时，先按高度降序到达
+// 被移出规范链的区块（Removed 为 true），再到达新分支上的区块。
+type Event struct {
+	Header  *types.Header
+	Logs    []types.Log // 该区块中匹配 Options.Query 的日志，按日志序号排列；Removed 时为空
+	Removed bool        // 区块因重组被移出规范链，消费者应回滚基于该区块哈希的数据
+}
+
+// Dialer 建立一条新的节点连接，每次重连都会调用一次。
+type Dialer func(ctx context.Context) (*ethclient.Client, error)
+
+// Options 是流的选项，零值即可使用。
+type Options struct {
+	Query        *ethereum.FilterQuery // 随区块一起获取的日志条件，nil 表示只要区块头；区块范围字段会被忽略
+	Start        uint64                // 从该高度开始补齐，0 表示从当前最新区块开始
+	Window       int                   // 重组检测窗口，默认 headtracker.DefaultWindow
+	PollInterval time.Duration         // HTTP 轮询间隔，默认 4 秒
+	MinBackoff   time.Duration         // 首次重连等待，默认 1 秒，之后每次翻倍
+	MaxBackoff   time.Duration         // 重连等待上限，默认 30 秒
+	StallTimeout time.Duration         // 订阅多久收不到新区块就重连，默认 2 分钟
+	LogRange     uint64                // 补齐日志时单次 eth_getLogs 的最大区块数，默认 500
+	OnConnect    func(Mode)            // 连接建立后回调，可以为 nil
+	OnError      func(error)           // 连接出错、即将重连时回调，可以为 nil
+}
+
+// Stream 是可自动重连的区块与日志流。
+type Stream struct {
+	dial    Dialer
+	opts    Options
+	current *current
+	tracker *headtracker.Tracker
+}
+
+// current 指向当前连接，headtracker 回溯父区块时总是使用最新的连接。
+type current struct {
+	*ethclient.Client
+}
+
+// New 创建区块流，dial 通常是 cfg.DialWS。
+func New(dial Dialer, opts Options) *Stream {
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = 4 * time.Second
+	}
+	if opts.MinBackoff <= 0 {
+		opts.MinBackoff = time.Second
+	}
+	if opts.MaxBackoff < opts.MinBackoff {
+		opts.MaxBackoff = max(30*time.Second, opts.MinBackoff)
+	}
+	if opts.StallTimeout <= 0 {
+		opts.StallTimeout = 2 * time.Minute
+	}
+	if opts.LogRange == 0 {
+		opts.LogRange = 500
+	}
+	cur := &current{}
+	return &Stream{dial: dial, opts: opts, current: cur, tracker: headtracker.New(cur, opts.Window)}
+}
+
+// Head 返回已发送的最新规范区块头，尚未发送过时返回 nil。
+func (s *Stream) Head() *types.Header {
+	return s.tracker.Head()
+}
+
+// Run 持续把区块事件发送到 events，连接出错时自动重连，只在 ctx 取消时返回。
+func (s *Stream) Run(ctx context.Context, events chan<- *Event) error {
+	const op = "stream.Run"
+
+	backoff := s.opts.MinBackoff
+	for {
+		progressed, err := s.session(ctx, events)
+		if ctx.Err() != nil {
+			return errs.Wrap(op, ctx.Err())
+		}
+		if progressed {
+			backoff = s.opts.MinBackoff
+		}
+		if s.opts.OnError != nil {
+			s.opts.OnError(err)
+		}
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errs.Wrap(op, ctx.Err())
+		case <-timer.C:
+		}
+		backoff = min(backoff*2, s.opts.MaxBackoff)
+	}
+}
+
+// session 使用一条连接推送区块，直到连接出错。progressed 报告本次连接是否推进过链头。
+func (s *Stream) session(ctx context.Context, events chan<- *Event) (progressed bool, err error) {
+	client, err := s.dial(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer client.Close()
+	s.current.Client = client
+
+	c := &conn{Stream: s, client: client, events: events}
+	heads := make(chan *types.Header, 64)
+	sub, err := client.SubscribeNewHead(ctx, heads)
+	switch {
+	case err == nil:
+		defer sub.Unsubscribe()
+		err = c.subscribe(ctx, sub, heads)
+	case errors.Is(err, rpc.ErrNotificationsUnsupported):
+		err = c.poll(ctx)
+	}
+	return c.progressed, err
+}
+
+// conn 是一次连接期间的状态。
+type conn struct {
+	*Stream
+	client     *ethclient.Client
+	events     chan<- *Event
+	progressed bool
+}
+
+// subscribe 处理 newHeads 订阅。
+func (c *conn) subscribe(ctx context.Context, sub ethereum.Subscription, heads <-chan *types.Header) error {
+	if c.opts.OnConnect != nil {
+		c.opts.OnConnect(Subscribe)
+	}
+	// 订阅建立之后再补齐，保证断线期间的区块不会遗漏
+	if err := c.catchUp(ctx); err != nil {
+		return err
+	}
+	stall := time.NewTimer(c.opts.StallTimeout)
+	defer stall.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-sub.Err():
+			if err == nil {
+				err = errors.New("subscription closed")
+			}
+			return err
+		case <-stall.C:
+			return ErrStalled
+		case header := <-heads:
+			if err := c.advance(ctx, header); err != nil {
+				return err
+			}
+			stall.Reset(c.opts.StallTimeout)
+		}
+	}
+}
+
+// poll 通过 HTTP 轮询新区块：优先使用区块过滤器，过滤器不可用或失效时按最新区块号轮询。
+func (c *conn) poll(ctx context.Context) error {
+	rc := c.client.Client()
+	filterID, err := c.newBlockFilter(ctx, rc)
+	if err != nil {
+		return err
+	}
+	mode := PollNumber
+	if filterID != "" {
+		mode = PollFilter
+	}
+	if c.opts.OnConnect != nil {
+		c.opts.OnConnect(mode)
+	}
+	if filterID != "" {
+		defer rc.CallContext(context.WithoutCancel(ctx), nil, "eth_uninstallFilter", filterID)
+	}
+	if err := c.catchUp(ctx); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(c.opts.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		if filterID != "" {
+			var hashes []common.Hash
+			err := rc.CallContext(ctx, &hashes, "eth_getFilterChanges", filterID)
+			if err == nil {
+				for _, hash := range hashes {
+					header, err := c.client.HeaderByHash(ctx, hash)
+					if err != nil {
+						return err
+					}
+					if err := c.advance(ctx, header); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			// 节点会清理长时间未读取的过滤器（"filter not found"），重建后本轮按区块号补齐
+			if filterID, err = c.newBlockFilter(ctx, rc); err != nil {
+				return err
+			}
+		}
+		if err := c.catchUp(ctx); err != nil {
+			return err
+		}
+	}
+}
+
+// newBlockFilter 创建区块过滤器。节点返回 JSON-RPC 错误（不支持过滤器）时返回空字符串，
+// 连接本身出错时返回错误。
+func (c *conn) newBlockFilter(ctx context.Context, rc *rpc.Client) (string, error) {
+	var id string
+	if err := rc.CallContext(ctx, &id, "eth_newBlockFilter"); err != nil {
+		var rpcErr rpc.Error
+		if errors.As(err, &rpcErr) {
+			return "", nil
+		}
+		return "", err
+	}
+	return id, nil
+}
+
+// catchUp 取当前最新区块推进链头；首次运行且设置了 Start 时先从 Start 逐块补齐。
+func (c *conn) catchUp(ctx context.Context) error {
+	latest, err := c.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if c.tracker.Head() == nil && c.opts.Start > 0 && c.opts.Start < latest.Number.Uint64() {
+		return c.backfill(ctx, c.opts.Start, latest)
+	}
+	return c.advance(ctx, latest)
+}
+
+// backfill 按区块号获取 [from, latest) 的区块头，分批推进链头，最后推进 latest。
+func (c *conn) backfill(ctx context.Context, from uint64, latest *types.Header) error {
+	to := latest.Number.Uint64()
+	for start := from; start < to; start += c.opts.LogRange {
+		end := min(start+c.opts.LogRange, to)
+		var added []*types.Header
+		for n := start; n < end; n++ {
+			header, err := c.client.HeaderByNumber(ctx, new(big.Int).SetUint64(n))
+			if err != nil {
+				return err
+			}
+			ev, err := c.tracker.Process(ctx, header)
+			if err != nil {
+				return err
+			}
+			if ev == nil {
+				continue
+			}
+			if ev.Reorg() {
+				// 补齐过程中发生重组：先把已取到的区块发出去，再按事件处理
+				if err := c.emit(ctx, added); err != nil {
+					return err
+				}
+				added = nil
+				if err := c.apply(ctx, ev); err != nil {
+					return err
+				}
+				continue
+			}
+			added = append(added, ev.Added...)
+		}
+		if err := c.emit(ctx, added); err != nil {
+			return err
+		}
+	}
+	return c.advance(ctx, latest)
+}
+
+// advance 把一个区块头交给 headtracker，并按顺序发送由此产生的事件。
+// 每个区块头都要经过 headtracker：窗口内已有的旧区块头表示规范链回退到了更短的分支，
+// 同高度的不同区块头表示同高度重组，两者都会产生移除事件。
+func (c *conn) advance(ctx context.Context, header *types.Header) error {
+	ev, err := c.tracker.Process(ctx, header)
+	if err != nil || ev == nil {
+		return err
+	}
+	return c.apply(ctx, ev)
+}
+
+// apply 发送 headtracker 事件：先按高度降序发送被移除的区块，再发送新区块。
+func (c *conn) apply(ctx context.Context, ev *headtracker.Event) error {
+	for i := len(ev.Dropped) - 1; i >= 0; i-- {
+		if err := c.send(ctx, &Event{Header: ev.Dropped[i], Removed: true}); err != nil {
+			return err
+		}
+	}
+	return c.emit(ctx, ev.Added)
+}
+
+// emit 获取 headers（高度连续升序）对应的日志并依次发送。
+func (c *conn) emit(ctx context.Context, headers []*types.Header) error {
+	for len(headers) > 0 {
+		n := min(uint64(len(headers)), c.opts.LogRange)
+		batch := headers[:n]
+		headers = headers[n:]
+		logs, err := c.logs(ctx, batch)
+		if err != nil {
+			return err
+		}
+		for _, header := range batch {
+			if err := c.send(ctx, &Event{Header: header, Logs: logs[header.Hash()]}); err != nil {
+				return err
+			}
+		}
+	}
+	c.progressed = true
+	return nil
+}
+
+// logs 按区块哈希分组获取 headers 中的日志。单个区块按区块哈希查询；多个区块按高度范围查询，
+// 范围查询结果中区块哈希与 headers 不一致（查询期间发生重组）的区块再按哈希单独查询。
+func (c *conn) logs(ctx context.Context, headers []*types.Header) (map[common.Hash][]types.Log, error) {
+	if c.opts.Query == nil || len(headers) == 0 {
+		return nil, nil
+	}
+	q := *c.opts.Query
+	q.FromBlock, q.ToBlock, q.BlockHash = nil, nil, nil
+	if len(headers) == 1 {
+		hash := headers[0].Hash()
+		q.BlockHash = &hash
+		logs, err := c.client.FilterLogs(ctx, q)
+		if err != nil {
+			return nil, err
+		}
+		return map[common.Hash][]types.Log{hash: logs}, nil
+	}
+
+	q.FromBlock, q.ToBlock = headers[0].Number, headers[len(headers)-1].Number
+	logs, err := c.client.FilterLogs(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	first := headers[0].Number.Uint64()
+	grouped := make(map[common.Hash][]types.Log, len(headers))
+	var stale []*types.Header
+	for _, l := range logs {
+		if l.BlockNumber < first || l.BlockNumber-first >= uint64(len(headers)) {
+			continue
+		}
+		header := headers[l.BlockNumber-first]
+		if l.BlockHash != header.Hash() {
+			if !slices.Contains(stale, header) {
+				stale = append(stale, header)
+			}
+			continue
+		}
+		grouped[l.BlockHash] = append(grouped[l.BlockHash], l)
+	}
+	for _, header := range stale {
+		one, err := c.logs(ctx, []*types.Header{header})
+		if err != nil {
+			return nil, err
+		}
+		grouped[header.Hash()] = one[header.Hash()]
+	}
+	return grouped, nil
+}
+
+// send 发送一个事件，ctx 取消时放弃。
+func (c *conn) send(ctx context.Context, ev *Event) error {
+	select {
+	case c.events <- ev:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
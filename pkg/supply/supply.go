@@ -0,0 +1,334 @@
+// Package supply 审计可增发 / 销毁代币的供应量：从零地址转出的 Transfer 记为铸币，转入零地址的记为销毁，
+// 每一笔都归属到发起交易的地址和当时的合约 owner；再在各检查点把按事件推算的供应量与链上 TotalSupply() 对账，
+// 标出不一致的区间，并给出按区间汇总的发行时间线。
+package supply
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	token "github.com/ydh2333/dapp_stu/08_search_token_balance/erc20"
+	"github.com/ydh2333/dapp_stu/pkg/abireg"
+	"github.com/ydh2333/dapp_stu/pkg/balance"
+	"github.com/ydh2333/dapp_stu/pkg/errs"
+	"github.com/ydh2333/dapp_stu/pkg/tokenhistory"
+	"github.com/ydh2333/dapp_stu/pkg/transaction"
+)
+
+// DefaultInterval 是对账检查点之间的区块数。
+const DefaultInterval = 10000
+
+// Kind 是供应量变化的类型。
+type Kind string
+
+const (
+	Mint Kind = "mint"
+	Burn Kind = "burn"
+)
+
+// Change 是一次铸币或销毁。
+type Change struct {
+	Kind        Kind            `json:"kind"`
+	Account     common.Address  `json:"account"` // 铸币的接收方或被销毁代币的持有人
+	Amount      *big.Int        `json:"amount"`
+	BlockNumber uint64          `json:"blockNumber"`
+	TxHash      common.Hash     `json:"txHash"`
+	LogIndex    uint            `json:"logIndex"`
+	Caller      common.Address  `json:"caller"`               // 发起交易的地址
+	Contract    *common.Address `json:"contract,omitempty"`   // 交易直接调用的合约，经由其他合约调用时与代币地址不同
+	Call        string          `json:"call,omitempty"`       // 解码后的交易调用（配置了 Options.Registry 时）
+	Owner       common.Address  `json:"owner"`                // 该区块上代币合约的 owner()，合约没有 owner 或查询失败时为零地址
+	OwnerErr    string          `json:"ownerError,omitempty"` // owner() 查询失败的原因（如节点无法查询历史状态），合约没有 owner 时为空
+	ByOwner     bool            `json:"byOwner"`              // 交易是否由 owner 发起
+	Supply      *big.Int        `json:"supply"`               // 之后按事件推算的供应量
+}
+
+// Period 是时间线上的一个区间，结束区块即对账检查点。
+type Period struct {
+	From        uint64   `json:"fromBlock"`
+	To          uint64   `json:"toBlock"`
+	Mints       int      `json:"mints"`
+	Burns       int      `json:"burns"`
+	Minted      *big.Int `json:"minted"`
+	Burned      *big.Int `json:"burned"`
+	Supply      *big.Int `json:"supply"`                // 区间结束时按事件推算的供应量
+	TotalSupply *big.Int `json:"totalSupply,omitempty"` // 区间结束区块上的 TotalSupply()，查询失败时为 nil
+	Discrepancy *big.Int `json:"discrepancy,omitempty"` // TotalSupply - Supply，一致时为 0
+	Err         string   `json:"error,omitempty"`       // TotalSupply() 查询失败的原因
+}
+
+// OK 报告该检查点的链上供应量与推算值是否一致。
+func (p *Period) OK() bool {
+	return p.TotalSupply != nil && p.Discrepancy.Sign() == 0
+}
+
+// Report 是 [From, To] 区块范围内的供应量审计结果。
+type Report struct {
+	Token   common.Address `json:"token"`
+	From    uint64         `json:"fromBlock"`
+	To      uint64         `json:"toBlock"`
+	Opening *big.Int       `json:"opening"` // From 之前的 TotalSupply()，From 为 0 时为 0
+	Minted  *big.Int       `json:"minted"`
+	Burned  *big.Int       `json:"burned"`
+	Changes []*Change      `json:"changes"`
+	Periods []*Period      `json:"periods"`
+}
+
+// Discrepancies 返回对账不一致（或无法查询 TotalSupply）的检查点。
+func (r *Report) Discrepancies() []*Period {
+	var found []*Period
+	for _, p := range r.Periods {
+		if !p.OK() {
+			found = append(found, p)
+		}
+	}
+	return found
+}
+
+// Backend 是审计所需的节点能力：查询日志、调用合约和查询交易，*ethclient.Client 满足该接口。
+type Backend interface {
+	bind.ContractFilterer
+	bind.ContractCaller
+	TransactionByHash(ctx context.Context, hash common.Hash) (tx *types.Transaction, isPending bool, err error)
+}
+
+// Options 配置 Track。嵌入的 tokenhistory.Options 控制日志分段查询，事件过滤条件由 Track 设置。
+type Options struct {
+	tokenhistory.Options
+	Interval uint64           // 检查点间隔区块数，0 表示 DefaultInterval
+	ChainID  *big.Int         // 用于恢复交易发送方
+	Registry *abireg.Registry // 非 nil 时用来解码交易调用，填入 Change.Call
+}
+
+// Track 查找 [from, to] 内代币的全部铸币和销毁，归属到发起交易的地址和 owner，并每隔 opts.Interval 个区块
+// （以及 to 处）与 TotalSupply() 对账。from 大于 0 时以 from-1 处的 TotalSupply() 作为期初供应量；
+// 查询历史区块的合约状态需要归档节点。
+func Track(ctx context.Context, backend Backend, tokenAddress common.Address, from, to uint64, opts Options) (*Report, error) {
+	const op = "supply.Track"
+
+	caller, err := token.NewErc20Caller(tokenAddress, backend)
+	if err != nil {
+		return nil, errs.Wrap(op, err)
+	}
+	r := &Report{Token: tokenAddress, From: from, To: to, Opening: new(big.Int), Minted: new(big.Int), Burned: new(big.Int)}
+	if from > 0 {
+		if r.Opening, err = caller.TotalSupply(atBlock(ctx, from-1)); err != nil {
+			return nil, errs.Wrap(op, fmt.Errorf("opening totalSupply at block %d: %w", from-1, err))
+		}
+	}
+
+	// 1. 铸币与销毁：分别按 from = 零地址、to = 零地址过滤 Transfer，合并后按区块和日志顺序排列
+	var events []*tokenhistory.Event
+	collect := func(_, _ uint64, part []*tokenhistory.Event) error {
+		events = append(events, part...)
+		return nil
+	}
+	zero := []common.Address{{}}
+	for _, filter := range []tokenhistory.Options{{TransferFrom: zero}, {TransferTo: zero}} {
+		scan := opts.Options
+		scan.SkipApprovals, scan.TransferFrom, scan.TransferTo = true, filter.TransferFrom, filter.TransferTo
+		if err := tokenhistory.Scan(ctx, backend, tokenAddress, from, to, scan, collect); err != nil {
+			return nil, errs.Wrap(op, err)
+		}
+	}
+	slices.SortFunc(events, func(a, b *tokenhistory.Event) int {
+		return cmp.Or(cmp.Compare(a.BlockNumber, b.BlockNumber), cmp.Compare(a.LogIndex, b.LogIndex))
+	})
+	events = slices.CompactFunc(events, func(a, b *tokenhistory.Event) bool {
+		return a.BlockNumber == b.BlockNumber && a.LogIndex == b.LogIndex
+	})
+
+	// 2. 归属：同一交易、同一区块只查询一次
+	a := &attributor{ctx: ctx, backend: backend, caller: caller, opts: opts, txs: map[common.Hash]*Change{}, owners: map[uint64]ownerResult{}}
+	supply := new(big.Int).Set(r.Opening)
+	for _, e := range events {
+		c := &Change{Kind: Mint, Account: e.To, Amount: e.Value, BlockNumber: e.BlockNumber, TxHash: e.TxHash, LogIndex: e.LogIndex}
+		if e.From == (common.Address{}) {
+			supply.Add(supply, e.Value)
+			r.Minted.Add(r.Minted, e.Value)
+		} else {
+			c.Kind, c.Account = Burn, e.From
+			supply.Sub(supply, e.Value)
+			r.Burned.Add(r.Burned, e.Value)
+		}
+		c.Supply = new(big.Int).Set(supply)
+		if err := a.attribute(c); err != nil {
+			return nil, errs.Wrap(op, err)
+		}
+		r.Changes = append(r.Changes, c)
+	}
+
+	// 3. 时间线与对账：每个区间结束时比较推算值和链上 TotalSupply()
+	interval := opts.Interval
+	if interval == 0 {
+		interval = DefaultInterval
+	}
+	supply.Set(r.Opening)
+	next := 0
+	for start := from; start <= to; {
+		end := to
+		if to-start >= interval {
+			end = start + interval - 1
+		}
+		p := &Period{From: start, To: end, Minted: new(big.Int), Burned: new(big.Int)}
+		for ; next < len(r.Changes) && r.Changes[next].BlockNumber <= end; next++ {
+			c := r.Changes[next]
+			if c.Kind == Mint {
+				p.Mints++
+				p.Minted.Add(p.Minted, c.Amount)
+			} else {
+				p.Burns++
+				p.Burned.Add(p.Burned, c.Amount)
+			}
+			supply = c.Supply
+		}
+		p.Supply = new(big.Int).Set(supply)
+		if ts, err := caller.TotalSupply(atBlock(ctx, end)); err != nil {
+			if ctx.Err() != nil {
+				return nil, errs.Wrap(op, ctx.Err())
+			}
+			p.Err = err.Error()
+		} else {
+			p.TotalSupply, p.Discrepancy = ts, new(big.Int).Sub(ts, p.Supply)
+		}
+		r.Periods = append(r.Periods, p)
+		if end == to {
+			break
+		}
+		start = end + 1
+	}
+	return r, nil
+}
+
+// attributor 为铸币 / 销毁查找发起交易和 owner，并缓存查询结果。
+type attributor struct {
+	ctx     context.Context
+	backend Backend
+	caller  *token.Erc20Caller
+	opts    Options
+	txs     map[common.Hash]*Change // 交易哈希 → 已填好 Caller / Contract / Call 的记录
+	owners  map[uint64]ownerResult
+}
+
+// ownerResult 是某个区块上 owner() 的查询结果。
+type ownerResult struct {
+	owner common.Address
+	err   string
+}
+
+func (a *attributor) attribute(c *Change) error {
+	if seen, ok := a.txs[c.TxHash]; ok {
+		c.Caller, c.Contract, c.Call = seen.Caller, seen.Contract, seen.Call
+	} else {
+		tx, _, err := a.backend.TransactionByHash(a.ctx, c.TxHash)
+		if err != nil {
+			return fmt.Errorf("transaction %s: %w", c.TxHash.Hex(), err)
+		}
+		if c.Caller, err = transaction.Sender(tx, a.opts.ChainID); err != nil {
+			return err
+		}
+		c.Contract = tx.To()
+		if a.opts.Registry != nil {
+			c.Call = a.opts.Registry.DecodeTx(tx).String()
+		}
+		a.txs[c.TxHash] = c
+	}
+	res, ok := a.owners[c.BlockNumber]
+	if !ok {
+		owner, err := a.caller.Owner(atBlock(a.ctx, c.BlockNumber))
+		switch {
+		case a.ctx.Err() != nil:
+			return a.ctx.Err()
+		case err == nil:
+			res.owner = owner
+		case !noOwner(err):
+			// 节点出错或无法查询历史状态：记录原因，不中断整个审计
+			res.err = err.Error()
+		}
+		a.owners[c.BlockNumber] = res
+	}
+	c.Owner, c.OwnerErr = res.owner, res.err
+	c.ByOwner = res.owner != (common.Address{}) && res.owner == c.Caller
+	return nil
+}
+
+// noOwner 判断 owner() 调用失败是否因为合约本身没有 owner：该区块上没有合约代码、调用被回滚，
+// 或者调用没有返回数据（没有该方法、由空的 fallback 处理）。
+func noOwner(err error) bool {
+	if errors.Is(err, bind.ErrNoCode) {
+		return true
+	}
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) && rpcErr.ErrorCode() == 3 {
+		return true
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "execution reverted") || strings.Contains(msg, "unmarshal an empty string")
+}
+
+func atBlock(ctx context.Context, number uint64) *bind.CallOpts {
+	return &bind.CallOpts{Context: ctx, BlockNumber: new(big.Int).SetUint64(number)}
+}
+
+// Write 以可读形式输出铸币 / 销毁明细和按区间汇总的发行时间线，数量按 decimals 换算。
+func (r *Report) Write(w io.Writer, decimals uint8) error {
+	units := func(v *big.Int) string {
+		return balance.ToUnits(v, int(decimals)).Text('f', int(decimals))
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "token:\t%s\n", r.Token.Hex())
+	fmt.Fprintf(tw, "blocks:\t%d - %d\n", r.From, r.To)
+	fmt.Fprintf(tw, "opening supply:\t%s\n", units(r.Opening))
+	fmt.Fprintf(tw, "minted:\t%s\n", units(r.Minted))
+	fmt.Fprintf(tw, "burned:\t%s\n", units(r.Burned))
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w)
+	tw = tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "block\tkind\taccount\tamount\tsupply\tcaller\towner\ttx\tcall")
+	for _, c := range r.Changes {
+		by := c.Owner.Hex()
+		switch {
+		case c.ByOwner:
+			by = "(caller)"
+		case c.OwnerErr != "":
+			by = "unavailable: " + c.OwnerErr
+		case c.Owner == (common.Address{}):
+			by = "-"
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", c.BlockNumber, c.Kind, c.Account.Hex(), units(c.Amount), units(c.Supply), c.Caller.Hex(), by, c.TxHash.Hex(), c.Call)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w)
+	tw = tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "blocks\tmints\tminted\tburns\tburned\tsupply\ttotalSupply\tstatus")
+	for _, p := range r.Periods {
+		onChain, status := "-", "ok"
+		switch {
+		case p.TotalSupply == nil:
+			status = "unavailable: " + p.Err
+		case !p.OK():
+			onChain, status = units(p.TotalSupply), "DISCREPANCY "+units(p.Discrepancy)
+		default:
+			onChain = units(p.TotalSupply)
+		}
+		fmt.Fprintf(tw, "%d - %d\t%d\t%s\t%d\t%s\t%s\t%s\t%s\n", p.From, p.To, p.Mints, units(p.Minted), p.Burns, units(p.Burned), units(p.Supply), onChain, status)
+	}
+	return tw.Flush()
+}
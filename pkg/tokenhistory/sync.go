@@ -0,0 +1,221 @@
+package tokenhistory
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	token "github.com/ydh2333/dapp_stu/08_search_token_balance/erc20"
+	"github.com/ydh2333/dapp_stu/pkg/errs"
+)
+
+// DefaultChunk 是每次查询的最大区块数；节点报告结果过多时减半，成功后逐步恢复。
+const DefaultChunk = 2000
+
+// Backend 是同步所需的节点能力，*ethclient.Client 和模拟链客户端都满足该接口。
+type Backend interface {
+	bind.ContractFilterer
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+}
+
+// Options 配置 Scan 和 Sync。
+type Options struct {
+	Chunk         uint64                             // 每次查询的最大区块数，0 表示 DefaultChunk
+	SkipApprovals bool                               // 只拉取 Transfer 事件
+	TransferFrom  []common.Address                   // 只拉取 from 在其中的 Transfer（indexed 参数过滤，由节点完成）
+	TransferTo    []common.Address                   // 只拉取 to 在其中的 Transfer
+	OnChunk       func(from, to uint64, events int)  // 每写入一段后调用（仅 Sync）
+	OnShrink      func(from, to uint64, next uint64) // [from, to] 结果过多、缩小为 next 个区块时调用
+	OnRewind      func(from, to uint64)              // 因重组删除了 [from, to] 内的事件后调用（仅 Sync）
+}
+
+// Scan 分段查询 [from, to] 内代币的 Transfer 和 Approval 事件，每段的事件按区块和日志顺序交给 fn。
+// 节点报告结果过多（如 "query returned more than 10000 results"）时把该段减半重试，
+// 单个区块仍然过多时返回错误；查询成功后区块数翻倍，最多恢复到 opts.Chunk。
+func Scan(ctx context.Context, backend bind.ContractFilterer, tokenAddress common.Address, from, to uint64, opts Options, fn func(from, to uint64, events []*Event) error) error {
+	const op = "tokenhistory.Scan"
+
+	filterer, err := token.NewErc20Filterer(tokenAddress, backend)
+	if err != nil {
+		return errs.Wrap(op, err)
+	}
+	limit := opts.Chunk
+	if limit == 0 {
+		limit = DefaultChunk
+	}
+	size := limit
+	for start := from; start <= to; {
+		end := to
+		if to-start >= size {
+			end = start + size - 1
+		}
+		events, err := fetch(ctx, filterer, tokenAddress, start, end, opts)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if !tooManyResults(err) || end == start {
+				return errs.Wrap(op, fmt.Errorf("blocks %d-%d: %w", start, end, err))
+			}
+			size = (end - start + 1) / 2
+			if opts.OnShrink != nil {
+				opts.OnShrink(start, end, size)
+			}
+			continue
+		}
+		if err := fn(start, end, events); err != nil {
+			return err
+		}
+		if end == to {
+			return nil
+		}
+		start, size = end+1, min(size*2, limit)
+	}
+	return nil
+}
+
+// fetch 查询 [from, to] 内的 Transfer（以及 Approval）事件，合并后按区块和日志顺序返回。
+func fetch(ctx context.Context, filterer *token.Erc20Filterer, tokenAddress common.Address, from, to uint64, o Options) ([]*Event, error) {
+	opts := &bind.FilterOpts{Start: from, End: &to, Context: ctx}
+	var events []*Event
+	transfers, err := filterer.FilterTransfer(opts, o.TransferFrom, o.TransferTo)
+	if err != nil {
+		return nil, err
+	}
+	for transfers.Next() {
+		ev := transfers.Event
+		events = append(events, newEvent(Transfer, tokenAddress, ev.From, ev.To, ev.Value, ev.Raw))
+	}
+	if err := errors.Join(transfers.Error(), transfers.Close()); err != nil {
+		return nil, err
+	}
+	if !o.SkipApprovals {
+		it, err := filterer.FilterApproval(opts, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		for it.Next() {
+			ev := it.Event
+			events = append(events, newEvent(Approval, tokenAddress, ev.Owner, ev.Spender, ev.Value, ev.Raw))
+		}
+		if err := errors.Join(it.Error(), it.Close()); err != nil {
+			return nil, err
+		}
+	}
+	slices.SortFunc(events, func(a, b *Event) int {
+		return cmp.Or(cmp.Compare(a.BlockNumber, b.BlockNumber), cmp.Compare(a.LogIndex, b.LogIndex))
+	})
+	return events, nil
+}
+
+func newEvent(kind Kind, tokenAddress, from, to common.Address, value *big.Int, l types.Log) *Event {
+	return &Event{
+		Kind:        kind,
+		Token:       tokenAddress,
+		From:        from,
+		To:          to,
+		Value:       value,
+		BlockNumber: l.BlockNumber,
+		BlockHash:   l.BlockHash,
+		TxHash:      l.TxHash,
+		TxIndex:     l.TxIndex,
+		LogIndex:    l.Index,
+	}
+}
+
+// tooManyResults 判断错误是否是节点拒绝了过大的日志查询（各家节点的措辞不同）。
+func tooManyResults(err error) bool {
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) && rpcErr.ErrorCode() == -32005 {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	for _, s := range []string{"too many", "more than", "exceed", "range too large", "block range", "response size"} {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// Sync 把代币在 [from, to] 内的事件写入历史库。库中已有该代币时从索引头的下一个区块继续（from 只在首次同步时使用），
+// 开始前与节点比对已写入的段结束区块哈希，不一致时回滚到最近一致的段。每段的事件与索引头原子写入，
+// 中断后重新运行即可续传。to 通常取最新区块减去确认数，以避开尚未稳定的区块。
+func (s *Store) Sync(ctx context.Context, backend Backend, tokenAddress common.Address, from, to uint64, opts Options) error {
+	const op = "tokenhistory.Sync"
+
+	if err := s.reconcile(ctx, backend, tokenAddress, opts.OnRewind); err != nil {
+		return errs.Wrap(op, err)
+	}
+	next := from
+	if head, _, ok := s.Head(tokenAddress); ok {
+		next = head + 1
+	}
+	if next > to {
+		return nil
+	}
+	return Scan(ctx, backend, tokenAddress, next, to, opts, func(start, end uint64, events []*Event) error {
+		h, err := backend.HeaderByNumber(ctx, new(big.Int).SetUint64(end))
+		if err != nil {
+			return errs.Wrap(op, fmt.Errorf("block %d: %w", end, err))
+		}
+		if err := s.commit(tokenAddress, start, end, h.Hash(), events); err != nil {
+			return errs.Wrap(op, err)
+		}
+		if opts.OnChunk != nil {
+			opts.OnChunk(start, end, len(events))
+		}
+		return nil
+	})
+}
+
+// reconcile 从最后一段向前与节点比对段结束区块的哈希，回滚到第一个一致的段；都不一致时清空该代币的历史。
+func (s *Store) reconcile(ctx context.Context, backend Backend, tokenAddress common.Address, onRewind func(uint64, uint64)) error {
+	head, hash, ok := s.Head(tokenAddress)
+	if !ok {
+		return nil
+	}
+	h, err := backend.HeaderByNumber(ctx, new(big.Int).SetUint64(head))
+	switch {
+	case err == nil && h.Hash() == hash:
+		return nil
+	case err != nil && !errors.Is(err, errs.ErrNotFound):
+		return err
+	}
+	// 节点上索引头的区块不同（或节点还没有这个高度），逐段向前找仍然一致的段
+	cps, err := s.checkpoints(tokenAddress)
+	if err != nil {
+		return err
+	}
+	for i := len(cps) - 1; i >= 0; i-- {
+		h, err := backend.HeaderByNumber(ctx, new(big.Int).SetUint64(cps[i].number))
+		if err != nil && !errors.Is(err, errs.ErrNotFound) {
+			return err
+		}
+		if err == nil && h.Hash() == cps[i].hash {
+			if err := s.rewind(tokenAddress, cps[i]); err != nil {
+				return err
+			}
+			if onRewind != nil {
+				onRewind(cps[i].number+1, head)
+			}
+			return nil
+		}
+	}
+	start, _ := s.Start(tokenAddress)
+	if err := s.Reset(tokenAddress); err != nil {
+		return err
+	}
+	if onRewind != nil {
+		onRewind(start, head)
+	}
+	return nil
+}
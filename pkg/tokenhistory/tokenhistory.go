@@ -0,0 +1,335 @@
+// Package tokenhistory 是按代币划分的 ERC20 历史索引：通过 erc20 绑定的 FilterTransfer / FilterApproval
+// 分段拉取 Transfer 和 Approval 事件（节点报告结果过多时自动缩小区块范围），写入本地 LevelDB，
+// 支持断点续传，并按地址查询转账记录及逐笔的余额变化。
+//
+// 数据库中的键（token 为代币合约地址，同一个库可以保存多个代币）：
+//
+//	h + token                               → 已索引到的区块号(8) + 区块哈希(32)
+//	s + token                               → 起始区块号(8)
+//	c + token + 区块号(8)                   → 区块哈希：每段的结束区块，重组时用来找共同祖先
+//	e + token + 区块号(8) + 日志索引(4)     → 事件（JSON 编码）
+//	x + token + 地址 + 区块号(8) + 日志索引(4) → 空：地址作为 from/to（Approval 为 owner/spender）出现过的事件
+package tokenhistory
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/leveldb"
+	"github.com/ydh2333/dapp_stu/pkg/errs"
+)
+
+var (
+	prefixHead       = []byte("h")
+	prefixStart      = []byte("s")
+	prefixCheckpoint = []byte("c")
+	prefixEvent      = []byte("e")
+	prefixAddress    = []byte("x")
+)
+
+// Kind 是事件类型。
+type Kind string
+
+const (
+	Transfer Kind = "Transfer"
+	Approval Kind = "Approval"
+)
+
+// Event 是一条 Transfer 或 Approval 事件。Approval 的 From、To、Value 分别是 owner、spender 和授权额度。
+type Event struct {
+	Kind        Kind           `json:"kind"`
+	Token       common.Address `json:"token"`
+	From        common.Address `json:"from"`
+	To          common.Address `json:"to"`
+	Value       *big.Int       `json:"value"`
+	BlockNumber uint64         `json:"blockNumber"`
+	BlockHash   common.Hash    `json:"blockHash"`
+	TxHash      common.Hash    `json:"txHash"`
+	TxIndex     uint           `json:"txIndex"`
+	LogIndex    uint           `json:"logIndex"`
+}
+
+// Entry 是地址转账记录中的一项：Delta 为该笔转账对地址余额的影响（转出为负，转给自己为 0），
+// Balance 为转账后的余额。
+type Entry struct {
+	*Event
+	Delta   *big.Int
+	Balance *big.Int
+}
+
+// Store 是本地代币历史库。方法可以并发调用，写入操作相互串行。
+type Store struct {
+	db ethdb.KeyValueStore
+	mu sync.Mutex // 串行化写入与回滚
+}
+
+// Open 打开（不存在时创建）dir 目录下的 LevelDB 历史库。
+func Open(dir string) (*Store, error) {
+	db, err := leveldb.New(dir, 16, 16, "", false)
+	if err != nil {
+		return nil, errs.Wrap("tokenhistory.Open", err)
+	}
+	return New(db), nil
+}
+
+// New 基于已有的键值数据库创建历史库，例如测试用的 memorydb。
+func New(db ethdb.KeyValueStore) *Store {
+	return &Store{db: db}
+}
+
+// Close 关闭数据库。
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Head 返回代币已索引到的区块号和哈希，尚未索引时 ok 为 false。
+func (s *Store) Head(tokenAddress common.Address) (number uint64, hash common.Hash, ok bool) {
+	v, err := s.db.Get(dbKey(prefixHead, tokenAddress.Bytes()))
+	if err != nil || len(v) != 8+common.HashLength {
+		return 0, common.Hash{}, false
+	}
+	return binary.BigEndian.Uint64(v), common.BytesToHash(v[8:]), true
+}
+
+// Start 返回代币索引的起始区块号，尚未索引时 ok 为 false。
+func (s *Store) Start(tokenAddress common.Address) (number uint64, ok bool) {
+	v, err := s.db.Get(dbKey(prefixStart, tokenAddress.Bytes()))
+	if err != nil || len(v) != 8 {
+		return 0, false
+	}
+	return binary.BigEndian.Uint64(v), true
+}
+
+// commit 原子地写入 [from, to] 一段的事件，并把索引头推进到 to。
+// 库中没有该代币时 from 成为起始区块，否则 from 必须紧接索引头。
+func (s *Store) commit(tokenAddress common.Address, from, to uint64, hash common.Hash, events []*Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	batch := s.db.NewBatch()
+	if head, _, ok := s.Head(tokenAddress); !ok {
+		batch.Put(dbKey(prefixStart, tokenAddress.Bytes()), encodeNumber(from))
+	} else if from != head+1 {
+		return fmt.Errorf("blocks %d-%d do not follow head %d", from, to, head)
+	}
+	for _, e := range events {
+		blob, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		batch.Put(eventKey(tokenAddress, e.BlockNumber, uint32(e.LogIndex)), blob)
+		for _, addr := range participants(e) {
+			batch.Put(addressKey(tokenAddress, addr, e.BlockNumber, uint32(e.LogIndex)), nil)
+		}
+	}
+	batch.Put(checkpointKey(tokenAddress, to), hash.Bytes())
+	batch.Put(dbKey(prefixHead, tokenAddress.Bytes()), dbKey(encodeNumber(to), hash.Bytes()))
+	return batch.Write()
+}
+
+// checkpoint 是一段的结束区块。
+type checkpoint struct {
+	number uint64
+	hash   common.Hash
+}
+
+// checkpoints 按区块顺序返回代币的全部段结束区块。
+func (s *Store) checkpoints(tokenAddress common.Address) ([]checkpoint, error) {
+	prefix := dbKey(prefixCheckpoint, tokenAddress.Bytes())
+	it := s.db.NewIterator(prefix, nil)
+	defer it.Release()
+	var cps []checkpoint
+	for it.Next() {
+		cps = append(cps, checkpoint{
+			number: binary.BigEndian.Uint64(it.Key()[len(prefix):]),
+			hash:   common.BytesToHash(it.Value()),
+		})
+	}
+	return cps, it.Error()
+}
+
+// Reset 删除代币的全部历史，下次同步从头开始。
+func (s *Store) Reset(tokenAddress common.Address) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	batch := s.db.NewBatch()
+	for _, prefix := range [][]byte{prefixCheckpoint, prefixEvent, prefixAddress} {
+		it := s.db.NewIterator(dbKey(prefix, tokenAddress.Bytes()), nil)
+		for it.Next() {
+			batch.Delete(common.CopyBytes(it.Key()))
+		}
+		it.Release()
+		if err := it.Error(); err != nil {
+			return errs.Wrap("tokenhistory.Reset", err)
+		}
+	}
+	batch.Delete(dbKey(prefixHead, tokenAddress.Bytes()))
+	batch.Delete(dbKey(prefixStart, tokenAddress.Bytes()))
+	return errs.Wrap("tokenhistory.Reset", batch.Write())
+}
+
+// rewind 删除 cp 之后的事件和段，把索引头退回到 cp。
+func (s *Store) rewind(tokenAddress common.Address, cp checkpoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	batch := s.db.NewBatch()
+	it := s.db.NewIterator(dbKey(prefixEvent, tokenAddress.Bytes()), encodeNumber(cp.number+1))
+	for it.Next() {
+		var e Event
+		if err := json.Unmarshal(it.Value(), &e); err != nil {
+			it.Release()
+			return err
+		}
+		for _, addr := range participants(&e) {
+			batch.Delete(addressKey(tokenAddress, addr, e.BlockNumber, uint32(e.LogIndex)))
+		}
+		batch.Delete(common.CopyBytes(it.Key()))
+	}
+	it.Release()
+	if err := it.Error(); err != nil {
+		return err
+	}
+	it = s.db.NewIterator(dbKey(prefixCheckpoint, tokenAddress.Bytes()), encodeNumber(cp.number+1))
+	for it.Next() {
+		batch.Delete(common.CopyBytes(it.Key()))
+	}
+	it.Release()
+	if err := it.Error(); err != nil {
+		return err
+	}
+	batch.Put(dbKey(prefixHead, tokenAddress.Bytes()), dbKey(encodeNumber(cp.number), cp.hash.Bytes()))
+	return batch.Write()
+}
+
+// Events 按区块和日志顺序返回 [from, to] 区块范围内已索引的全部事件。
+func (s *Store) Events(tokenAddress common.Address, from, to uint64) ([]*Event, error) {
+	const op = "tokenhistory.Events"
+
+	prefix := dbKey(prefixEvent, tokenAddress.Bytes())
+	it := s.db.NewIterator(prefix, encodeNumber(from))
+	defer it.Release()
+	var found []*Event
+	for it.Next() {
+		if binary.BigEndian.Uint64(it.Key()[len(prefix):]) > to {
+			break
+		}
+		e := new(Event)
+		if err := json.Unmarshal(it.Value(), e); err != nil {
+			return nil, errs.Wrap(op, err)
+		}
+		found = append(found, e)
+	}
+	return found, errs.Wrap(op, it.Error())
+}
+
+// ByAddress 按区块和日志顺序返回 addr 参与的全部事件。
+func (s *Store) ByAddress(tokenAddress, addr common.Address) ([]*Event, error) {
+	const op = "tokenhistory.ByAddress"
+
+	it := s.db.NewIterator(dbKey(prefixAddress, tokenAddress.Bytes(), addr.Bytes()), nil)
+	defer it.Release()
+	var found []*Event
+	for it.Next() {
+		key := it.Key()
+		number := binary.BigEndian.Uint64(key[len(key)-12:])
+		logIndex := binary.BigEndian.Uint32(key[len(key)-4:])
+		blob, err := s.db.Get(eventKey(tokenAddress, number, logIndex))
+		if err != nil {
+			return nil, errs.Wrap(op, fmt.Errorf("event %d/%d: %w", number, logIndex, err))
+		}
+		e := new(Event)
+		if err := json.Unmarshal(blob, e); err != nil {
+			return nil, errs.Wrap(op, err)
+		}
+		found = append(found, e)
+	}
+	return found, errs.Wrap(op, it.Error())
+}
+
+// History 返回 addr 的全部 Transfer 记录及逐笔之后的余额。opening 是起始区块之前的余额
+// （可用 BalanceOf 在起始区块减一处查询），为 nil 时按 0 计算。
+func (s *Store) History(tokenAddress, addr common.Address, opening *big.Int) ([]*Entry, error) {
+	events, err := s.ByAddress(tokenAddress, addr)
+	if err != nil {
+		return nil, errs.Wrap("tokenhistory.History", err)
+	}
+	balance := new(big.Int)
+	if opening != nil {
+		balance.Set(opening)
+	}
+	var entries []*Entry
+	for _, e := range events {
+		if e.Kind != Transfer {
+			continue
+		}
+		delta := new(big.Int)
+		if e.To == addr {
+			delta.Add(delta, e.Value)
+		}
+		if e.From == addr {
+			delta.Sub(delta, e.Value)
+		}
+		balance = new(big.Int).Add(balance, delta)
+		entries = append(entries, &Entry{Event: e, Delta: delta, Balance: balance})
+	}
+	return entries, nil
+}
+
+// Approvals 返回 addr 作为 owner 或 spender 的全部 Approval 事件。
+func (s *Store) Approvals(tokenAddress, addr common.Address) ([]*Event, error) {
+	events, err := s.ByAddress(tokenAddress, addr)
+	if err != nil {
+		return nil, errs.Wrap("tokenhistory.Approvals", err)
+	}
+	var found []*Event
+	for _, e := range events {
+		if e.Kind == Approval {
+			found = append(found, e)
+		}
+	}
+	return found, nil
+}
+
+// participants 返回事件涉及的地址（去重）。
+func participants(e *Event) []common.Address {
+	if e.From == e.To {
+		return []common.Address{e.From}
+	}
+	return []common.Address{e.From, e.To}
+}
+
+// dbKey 把各部分拼接成新的字节切片，不与 prefix 共享底层数组。
+func dbKey(prefix []byte, parts ...[]byte) []byte {
+	n := len(prefix)
+	for _, p := range parts {
+		n += len(p)
+	}
+	key := append(make([]byte, 0, n), prefix...)
+	for _, p := range parts {
+		key = append(key, p...)
+	}
+	return key
+}
+
+func encodeNumber(n uint64) []byte {
+	return binary.BigEndian.AppendUint64(nil, n)
+}
+
+func checkpointKey(tokenAddress common.Address, number uint64) []byte {
+	return dbKey(prefixCheckpoint, tokenAddress.Bytes(), encodeNumber(number))
+}
+
+func eventKey(tokenAddress common.Address, number uint64, logIndex uint32) []byte {
+	return dbKey(prefixEvent, tokenAddress.Bytes(), encodeNumber(number), binary.BigEndian.AppendUint32(nil, logIndex))
+}
+
+func addressKey(tokenAddress, addr common.Address, number uint64, logIndex uint32) []byte {
+	return dbKey(prefixAddress, tokenAddress.Bytes(), addr.Bytes(), encodeNumber(number), binary.BigEndian.AppendUint32(nil, logIndex))
+}
@@ -0,0 +1,243 @@
+package transaction
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/big"
+	"text/tabwriter"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ydh2333/dapp_stu/pkg/errs"
+)
+
+// TypeName 返回交易类型的可读名称。
+func TypeName(txType uint8) string {
+	switch txType {
+	case types.LegacyTxType:
+		return "legacy"
+	case types.AccessListTxType:
+		return "access-list (EIP-2930)"
+	case types.DynamicFeeTxType:
+		return "dynamic-fee (EIP-1559)"
+	case types.BlobTxType:
+		return "blob (EIP-4844)"
+	case types.SetCodeTxType:
+		return "set-code (EIP-7702)"
+	default:
+		return fmt.Sprintf("unknown (0x%02x)", txType)
+	}
+}
+
+// Authorization 是 EIP-7702 交易中的一条授权及其签名者。
+type Authorization struct {
+	types.SetCodeAuthorization
+	Authority    common.Address // 签署授权的账户，其代码将指向 Address
+	AuthorityErr error          // 授权签名无法恢复时的原因（这条授权会被跳过）
+}
+
+// Details 是按交易类型整理的交易详情。手续费相关字段只有在交易已上链（Receipt 不为 nil）时才有值。
+type Details struct {
+	Tx       *types.Transaction
+	Type     uint8
+	From     common.Address
+	To       *common.Address // nil 表示合约创建
+	Pending  bool
+	ChainID  *big.Int
+	GasPrice *big.Int // 传统与 EIP-2930 交易的 gasPrice
+
+	// EIP-1559 及之后的交易类型
+	GasTipCap *big.Int
+	GasFeeCap *big.Int
+
+	AccessList types.AccessList // EIP-2930 及之后的交易类型
+
+	// EIP-4844 blob 交易
+	BlobFeeCap *big.Int
+	BlobHashes []common.Hash
+
+	Authorizations []Authorization // EIP-7702 set-code 交易
+
+	// 来自回执与所在区块
+	Receipt           *types.Receipt
+	BaseFee           *big.Int // 所在区块的基础费用，伦敦升级之前为 nil
+	EffectiveGasPrice *big.Int // 实际每单位 gas 支付的价格
+	Fee               *big.Int // GasUsed × EffectiveGasPrice
+	BurntFee          *big.Int // GasUsed × BaseFee，被销毁的部分
+	PriorityFee       *big.Int // Fee − BurntFee，支付给出块者的小费
+	BlobFee           *big.Int // BlobGasUsed × BlobGasPrice，blob 交易才有
+	TotalFee          *big.Int // Fee + BlobFee
+}
+
+// Describe 解析交易本身的字段（不访问节点），发送方用支持全部交易类型的最新签名器恢复。
+func Describe(tx *types.Transaction, chainID *big.Int) (*Details, error) {
+	from, err := Sender(tx, chainID)
+	if err != nil {
+		return nil, err
+	}
+	d := &Details{Tx: tx, Type: tx.Type(), From: from, To: tx.To(), ChainID: tx.ChainId()}
+	switch tx.Type() {
+	case types.LegacyTxType, types.AccessListTxType:
+		d.GasPrice = tx.GasPrice()
+	default:
+		d.GasTipCap, d.GasFeeCap = tx.GasTipCap(), tx.GasFeeCap()
+	}
+	if tx.Type() != types.LegacyTxType {
+		d.AccessList = tx.AccessList()
+	}
+	if tx.Type() == types.BlobTxType {
+		d.BlobFeeCap, d.BlobHashes = tx.BlobGasFeeCap(), tx.BlobHashes()
+	}
+	for _, auth := range tx.SetCodeAuthorizations() {
+		authority, err := auth.Authority()
+		d.Authorizations = append(d.Authorizations, Authorization{SetCodeAuthorization: auth, Authority: authority, AuthorityErr: err})
+	}
+	return d, nil
+}
+
+// AttachReceipt 根据回执和所在区块的基础费用（可以为 nil）计算实际支付的手续费。
+func (d *Details) AttachReceipt(r *types.Receipt, baseFee *big.Int) {
+	d.Receipt, d.BaseFee = r, baseFee
+	gasUsed := new(big.Int).SetUint64(r.GasUsed)
+
+	d.EffectiveGasPrice = r.EffectiveGasPrice
+	if d.EffectiveGasPrice == nil {
+		// 个别节点的回执不带 effectiveGasPrice，按规则自行计算：min(tipCap + baseFee, feeCap)
+		d.EffectiveGasPrice = d.Tx.GasPrice()
+		if baseFee != nil && d.GasFeeCap != nil {
+			if tip, err := d.Tx.EffectiveGasTip(baseFee); err == nil {
+				d.EffectiveGasPrice = new(big.Int).Add(baseFee, tip)
+			}
+		}
+	}
+	d.Fee = new(big.Int).Mul(gasUsed, d.EffectiveGasPrice)
+	d.TotalFee = new(big.Int).Set(d.Fee)
+	if baseFee != nil {
+		d.BurntFee = new(big.Int).Mul(gasUsed, baseFee)
+		d.PriorityFee = new(big.Int).Sub(d.Fee, d.BurntFee)
+	}
+	if r.BlobGasUsed > 0 && r.BlobGasPrice != nil {
+		d.BlobFee = new(big.Int).Mul(new(big.Int).SetUint64(r.BlobGasUsed), r.BlobGasPrice)
+		d.TotalFee.Add(d.TotalFee, d.BlobFee)
+	}
+}
+
+// Backend 是查询交易详情所需的节点能力，*ethclient.Client 和模拟链客户端都满足该接口。
+type Backend interface {
+	ethereum.TransactionReader
+	HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error)
+}
+
+// Inspect 查询交易及其回执、所在区块的基础费用，返回完整的交易详情。交易仍在交易池中时没有手续费信息。
+func Inspect(ctx context.Context, backend Backend, hash common.Hash, chainID *big.Int) (*Details, error) {
+	const op = "transaction.Inspect"
+
+	tx, pending, err := backend.TransactionByHash(ctx, hash)
+	if err != nil {
+		return nil, errs.Wrap(op, err)
+	}
+	d, err := Describe(tx, chainID)
+	if err != nil {
+		return nil, errs.Wrap(op, err)
+	}
+	d.Pending = pending
+	if pending {
+		return d, nil
+	}
+	r, err := backend.TransactionReceipt(ctx, hash)
+	if err != nil {
+		return nil, errs.Wrap(op, err)
+	}
+	header, err := backend.HeaderByHash(ctx, r.BlockHash)
+	if err != nil {
+		return nil, errs.Wrap(op, err)
+	}
+	d.AttachReceipt(r, header.BaseFee)
+	return d, nil
+}
+
+// Write 以可读形式输出交易详情，只列出该交易类型具有的字段。
+func (d *Details) Write(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	tx := d.Tx
+	fmt.Fprintf(tw, "hash:\t%s\n", tx.Hash().Hex())
+	fmt.Fprintf(tw, "type:\t%d %s\n", d.Type, TypeName(d.Type))
+	if d.ChainID != nil && d.ChainID.Sign() > 0 {
+		fmt.Fprintf(tw, "chain id:\t%s\n", d.ChainID)
+	}
+	fmt.Fprintf(tw, "from:\t%s\n", d.From.Hex())
+	if d.To == nil {
+		fmt.Fprintf(tw, "to:\t(contract creation)\n")
+	} else {
+		fmt.Fprintf(tw, "to:\t%s\n", d.To.Hex())
+	}
+	fmt.Fprintf(tw, "nonce:\t%d\n", tx.Nonce())
+	fmt.Fprintf(tw, "value:\t%s wei\n", tx.Value())
+	fmt.Fprintf(tw, "gas limit:\t%d\n", tx.Gas())
+	if d.GasPrice != nil {
+		fmt.Fprintf(tw, "gas price:\t%s wei\n", d.GasPrice)
+	}
+	if d.GasFeeCap != nil {
+		fmt.Fprintf(tw, "max priority fee:\t%s wei\n", d.GasTipCap)
+		fmt.Fprintf(tw, "max fee:\t%s wei\n", d.GasFeeCap)
+	}
+	if d.Type != types.LegacyTxType {
+		fmt.Fprintf(tw, "access list:\t%d entries\n", len(d.AccessList))
+		for _, t := range d.AccessList {
+			fmt.Fprintf(tw, "  %s\t%d storage keys\n", t.Address.Hex(), len(t.StorageKeys))
+			for _, k := range t.StorageKeys {
+				fmt.Fprintf(tw, "    \t%s\n", k.Hex())
+			}
+		}
+	}
+	if d.Type == types.BlobTxType {
+		fmt.Fprintf(tw, "max fee per blob gas:\t%s wei\n", d.BlobFeeCap)
+		fmt.Fprintf(tw, "blob hashes:\t%d\n", len(d.BlobHashes))
+		for _, h := range d.BlobHashes {
+			fmt.Fprintf(tw, "  \t%s\n", h.Hex())
+		}
+	}
+	if d.Type == types.SetCodeTxType {
+		fmt.Fprintf(tw, "authorizations:\t%d\n", len(d.Authorizations))
+		for i, a := range d.Authorizations {
+			authority := a.Authority.Hex()
+			if a.AuthorityErr != nil {
+				authority = "invalid signature: " + a.AuthorityErr.Error()
+			}
+			fmt.Fprintf(tw, "  #%d\tchain %s, delegate to %s, nonce %d, authority %s\n", i, a.ChainID.ToBig(), a.Address.Hex(), a.Nonce, authority)
+		}
+	}
+	fmt.Fprintf(tw, "data:\t%d bytes\n", len(tx.Data()))
+
+	switch {
+	case d.Pending:
+		fmt.Fprintf(tw, "status:\tpending\n")
+	case d.Receipt != nil:
+		r := d.Receipt
+		status := "success"
+		if r.Status != types.ReceiptStatusSuccessful {
+			status = "failed"
+		}
+		fmt.Fprintf(tw, "status:\t%s (block %s, index %d)\n", status, r.BlockNumber, r.TransactionIndex)
+		fmt.Fprintf(tw, "gas used:\t%d (%.2f%% of limit)\n", r.GasUsed, float64(r.GasUsed)*100/float64(tx.Gas()))
+		if d.BaseFee != nil {
+			fmt.Fprintf(tw, "base fee:\t%s wei\n", d.BaseFee)
+		}
+		fmt.Fprintf(tw, "effective gas price:\t%s wei\n", d.EffectiveGasPrice)
+		fmt.Fprintf(tw, "fee:\t%s wei\n", d.Fee)
+		if d.BurntFee != nil {
+			fmt.Fprintf(tw, "  burnt:\t%s wei\n", d.BurntFee)
+			fmt.Fprintf(tw, "  priority:\t%s wei\n", d.PriorityFee)
+		}
+		if d.BlobFee != nil {
+			fmt.Fprintf(tw, "blob fee:\t%s wei (%d blob gas × %s wei)\n", d.BlobFee, r.BlobGasUsed, r.BlobGasPrice)
+		}
+		fmt.Fprintf(tw, "total fee:\t%s wei\n", d.TotalFee)
+		if r.ContractAddress != (common.Address{}) {
+			fmt.Fprintf(tw, "contract:\t%s\n", r.ContractAddress.Hex())
+		}
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,41 @@
+// Package transaction 封装交易的查询、发送方解析以及按交易类型展示交易详情（对应 02_search_transaction）。
+package transaction
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ydh2333/dapp_stu/pkg/errs"
+)
+
+// QueryTransaction 通过交易哈希查询交易，isPending 表示交易是否仍在交易池中等待确认。
+func QueryTransaction(ctx context.Context, client ethereum.TransactionReader, hash common.Hash) (tx *types.Transaction, isPending bool, err error) {
+	tx, isPending, err = client.TransactionByHash(ctx, hash)
+	if err != nil {
+		return nil, false, errs.Wrap("transaction.QueryTransaction", err)
+	}
+	return tx, isPending, nil
+}
+
+// TransactionInBlock 按索引查询区块内的交易。
+func TransactionInBlock(ctx context.Context, client ethereum.ChainReader, blockHash common.Hash, index uint) (*types.Transaction, error) {
+	tx, err := client.TransactionInBlock(ctx, blockHash, index)
+	if err != nil {
+		return nil, errs.Wrap("transaction.TransactionInBlock", err)
+	}
+	return tx, nil
+}
+
+// Sender 结合链 ID 解析交易签名，得到交易发送方地址。
+// 使用支持全部交易类型的最新签名器（传统、EIP-2930、EIP-1559、EIP-4844 blob、EIP-7702 set-code），
+// 未带链 ID 的早期传统交易（EIP-155 之前）同样可以解析。
+func Sender(tx *types.Transaction, chainID *big.Int) (common.Address, error) {
+	sender, err := types.Sender(types.LatestSignerForChainID(chainID), tx)
+	if err != nil {
+		return common.Address{}, errs.Wrap("transaction.Sender", err)
+	}
+	return sender, nil
+}
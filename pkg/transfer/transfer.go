@@ -0,0 +1,106 @@
+// Package transfer 封装 ETH 转账与 ERC20 代币转账（对应 05_ETH_transfer、06_token_transfer）。
+package transfer
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	token "github.com/ydh2333/dapp_stu/08_search_token_balance/erc20"
+	"github.com/ydh2333/dapp_stu/pkg/errs"
+	"github.com/ydh2333/dapp_stu/pkg/feeoracle"
+	"github.com/ydh2333/dapp_stu/pkg/guard"
+	"github.com/ydh2333/dapp_stu/pkg/signer"
+	"github.com/ydh2333/dapp_stu/pkg/txbuilder"
+)
+
+// Backend 是发送交易所需的节点能力，*ethclient.Client 和模拟链客户端都满足该接口。
+type Backend interface {
+	txbuilder.Backend
+	ethereum.FeeHistoryReader
+}
+
+// ethTransferGas 是普通 ETH 转账固定消耗的 gas。
+const ethTransferGas = 21000
+
+// SendETH 从 key 对应的账户向 to 转账 value wei，返回已发送的交易。
+func SendETH(ctx context.Context, backend Backend, g *guard.Guard, s signer.Signer, to common.Address, value *big.Int) (*types.Transaction, error) {
+	const op = "transfer.SendETH"
+
+	if value == nil || value.Sign() < 0 {
+		return nil, errs.Invalid(op, "value must be a non-negative amount")
+	}
+	// 燃气价格随市场波动，不宜硬编码，由 txbuilder 使用节点建议的价格
+	signedTx, err := txbuilder.New(backend, g).Send(ctx, s, txbuilder.Spec{
+		To:    &to,
+		Value: value,
+		Mode:  txbuilder.Legacy,
+		Gas:   ethTransferGas,
+	})
+	if err != nil {
+		return nil, errs.Wrap(op, err)
+	}
+	return signedTx, nil
+}
+
+// EncodeTransfer 使用 ERC20 ABI 编码 transfer(address,uint256) 调用数据。
+func EncodeTransfer(to common.Address, amount *big.Int) ([]byte, error) {
+	parsed, err := token.Erc20MetaData.GetAbi()
+	if err != nil {
+		return nil, errs.Wrap("transfer.EncodeTransfer", err)
+	}
+	data, err := parsed.Pack("transfer", to, amount)
+	if err != nil {
+		return nil, errs.Wrap("transfer.EncodeTransfer", err)
+	}
+	return data, nil
+}
+
+// ParseAmount 解析十进制的整数金额字符串（最小单位）。
+func ParseAmount(s string) (*big.Int, error) {
+	amount, ok := new(big.Int).SetString(s, 10)
+	if !ok || amount.Sign() < 0 {
+		return nil, errs.Invalid("transfer.ParseAmount", "invalid amount "+s+" (must be a non-negative integer)")
+	}
+	return amount, nil
+}
+
+// SendToken 以传统 gasPrice 交易调用代币合约的 transfer，向 to 转账 amount 个最小单位的代币。
+func SendToken(ctx context.Context, backend Backend, g *guard.Guard, s signer.Signer, tokenAddress, to common.Address, amount *big.Int) (*types.Transaction, error) {
+	return sendToken(ctx, "transfer.SendToken", backend, g, s, tokenAddress, to, amount, txbuilder.Spec{Mode: txbuilder.Legacy})
+}
+
+// SendTokenDynamicFee 与 SendToken 相同，但构造 EIP-1559 动态手续费交易，
+// 优先费和最大手续费由 feeoracle 按 strategy 估算。
+func SendTokenDynamicFee(ctx context.Context, backend Backend, g *guard.Guard, s signer.Signer, tokenAddress, to common.Address, amount *big.Int, strategy feeoracle.Strategy) (*types.Transaction, error) {
+	const op = "transfer.SendTokenDynamicFee"
+
+	fees, err := feeoracle.New(backend).Suggest(ctx, strategy)
+	if err != nil {
+		return nil, errs.Wrap(op, err)
+	}
+	return sendToken(ctx, op, backend, g, s, tokenAddress, to, amount, txbuilder.Spec{
+		Mode:      txbuilder.DynamicFee,
+		GasTipCap: fees.GasTipCap,
+		GasFeeCap: fees.GasFeeCap,
+	})
+}
+
+// sendToken 编码 transfer 调用数据填入 spec，构造交易（gas 限额由节点估算）并发送。
+func sendToken(ctx context.Context, op string, backend Backend, g *guard.Guard, s signer.Signer, tokenAddress, to common.Address, amount *big.Int, spec txbuilder.Spec) (*types.Transaction, error) {
+	if amount == nil || amount.Sign() < 0 {
+		return nil, errs.Invalid(op, "amount must be non-negative")
+	}
+	data, err := EncodeTransfer(to, amount)
+	if err != nil {
+		return nil, errs.Wrap(op, err)
+	}
+	spec.To, spec.Data = &tokenAddress, data
+	signedTx, err := txbuilder.New(backend, g).Send(ctx, s, spec)
+	if err != nil {
+		return nil, errs.Wrap(op, err)
+	}
+	return signedTx, nil
+}
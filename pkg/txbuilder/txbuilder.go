@@ -0,0 +1,373 @@
+// Package txbuilder 用统一的 Spec 构造任意类型的交易：传统交易、EIP-2930 访问列表交易、
+// EIP-1559 动态手续费交易、EIP-4844 blob 交易和 EIP-7702 代码委托交易。
+// 未填写的 nonce、gas 和费用由节点补全；签名器按链配置和当前区块所处的分叉选择，
+// 签名同样经过 guard.Guard。
+package txbuilder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/misc/eip4844"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/holiman/uint256"
+	"github.com/ydh2333/dapp_stu/pkg/errs"
+	"github.com/ydh2333/dapp_stu/pkg/guard"
+	"github.com/ydh2333/dapp_stu/pkg/nonce"
+	"github.com/ydh2333/dapp_stu/pkg/signer"
+)
+
+// Mode 是交易类型（手续费模式）。
+type Mode int
+
+const (
+	// Auto 按 Spec 内容和链是否支持 EIP-1559 自动选择：带 blob 选 Blob，带授权列表选 SetCode，
+	// 区块有 base fee 时选 DynamicFee，否则按是否带访问列表选 AccessList 或 Legacy。
+	Auto Mode = iota
+	Legacy
+	AccessList
+	DynamicFee
+	Blob
+	SetCode
+)
+
+var modeNames = map[Mode]string{
+	Auto:       "auto",
+	Legacy:     "legacy",
+	AccessList: "access-list",
+	DynamicFee: "dynamic-fee",
+	Blob:       "blob",
+	SetCode:    "set-code",
+}
+
+func (m Mode) String() string {
+	if name, ok := modeNames[m]; ok {
+		return name
+	}
+	return fmt.Sprintf("Mode(%d)", int(m))
+}
+
+// ParseMode 解析 Mode.String 输出的名称。
+func ParseMode(s string) (Mode, error) {
+	for m, name := range modeNames {
+		if name == s {
+			return m, nil
+		}
+	}
+	return Auto, errs.Invalid("txbuilder.ParseMode", "unknown transaction type "+s)
+}
+
+// Spec 描述要构造的交易。除 To/Value/Data/Mode 外的字段都可留空，由 Builder 补全。
+type Spec struct {
+	To         *common.Address  // 接收方，nil 表示部署合约
+	Value      *big.Int         // 转账金额（wei），nil 表示 0
+	Data       []byte           // 调用数据或合约字节码
+	Mode       Mode             // 交易类型
+	AccessList types.AccessList // EIP-2930 访问列表，AccessList 及之后的类型可用
+
+	Nonce *uint64 // nil 时使用 PendingNonceAt
+	Gas   uint64  // 0 时使用 EstimateGas 的结果再加 20% 冗余
+
+	GasPrice   *big.Int // Legacy/AccessList：nil 时使用 SuggestGasPrice
+	GasTipCap  *big.Int // 动态手续费类型：nil 时使用 SuggestGasTipCap
+	GasFeeCap  *big.Int // 动态手续费类型：nil 时取 2*baseFee + GasTipCap
+	BlobFeeCap *big.Int // Blob：nil 时取当前 blob base fee 的 2 倍
+
+	Sidecar  *types.BlobTxSidecar         // Blob：blob、承诺和证明
+	AuthList []types.SetCodeAuthorization // SetCode：EIP-7702 授权列表
+}
+
+// Backend 是构造并发送交易所需的节点能力，*ethclient.Client 和 bind.ContractBackend 都满足该接口。
+type Backend interface {
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	PendingNonceAt(ctx context.Context, account common.Address) (uint64, error)
+	ethereum.GasPricer
+	ethereum.GasPricer1559
+	ethereum.GasEstimator
+	ethereum.TransactionSender
+}
+
+// Builder 按 Spec 构造、签名并发送交易。
+type Builder struct {
+	backend Backend
+	guard   *guard.Guard
+	config  *params.ChainConfig
+}
+
+// New 创建 Builder，链配置由 Guard 确认过的链 ID 决定（见 ChainConfig）。
+func New(backend Backend, g *guard.Guard) *Builder {
+	return &Builder{backend: backend, guard: g, config: ChainConfig(g.ChainID())}
+}
+
+// ChainConfig 返回 chainID 对应的已知公链配置；其他链（本地开发链、模拟链）
+// 视为所有分叉都已激活。
+func ChainConfig(chainID *big.Int) *params.ChainConfig {
+	for _, config := range []*params.ChainConfig{
+		params.MainnetChainConfig,
+		params.SepoliaChainConfig,
+		params.HoleskyChainConfig,
+		params.HoodiChainConfig,
+	} {
+		if config.ChainID.Cmp(chainID) == 0 {
+			return config
+		}
+	}
+	config := *params.AllDevChainProtocolChanges
+	config.ChainID = new(big.Int).Set(chainID)
+	return &config
+}
+
+// Signer 返回 head 所处分叉的签名器，它只接受该分叉已支持的交易类型。
+func (b *Builder) Signer(head *types.Header) types.Signer {
+	return types.MakeSigner(b.config, head.Number, head.Time)
+}
+
+// Build 为 from 构造未签名的交易，并返回该交易应使用的签名器。
+func (b *Builder) Build(ctx context.Context, from common.Address, spec Spec) (*types.Transaction, types.Signer, error) {
+	const op = "txbuilder.Build"
+
+	head, err := b.backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, nil, errs.Wrap(op, err)
+	}
+	mode, err := b.resolveMode(spec, head)
+	if err != nil {
+		return nil, nil, errs.Wrap(op, err)
+	}
+	if spec.To == nil && (mode == Blob || mode == SetCode) {
+		return nil, nil, errs.Invalid(op, mode.String()+" transactions cannot create contracts")
+	}
+	value := spec.Value
+	if value == nil {
+		value = new(big.Int)
+	}
+	if value.Sign() < 0 {
+		return nil, nil, errs.Invalid(op, "value must be non-negative")
+	}
+
+	fees, err := b.fees(ctx, mode, spec, head)
+	if err != nil {
+		return nil, nil, errs.Wrap(op, err)
+	}
+	var blobHashes []common.Hash
+	if spec.Sidecar != nil {
+		blobHashes = spec.Sidecar.BlobHashes()
+	}
+	if mode == Blob && len(blobHashes) == 0 {
+		return nil, nil, errs.Invalid(op, "blob transactions need a sidecar with at least one blob")
+	}
+	if mode == SetCode && len(spec.AuthList) == 0 {
+		return nil, nil, errs.Invalid(op, "set-code transactions need at least one authorization")
+	}
+	gas := spec.Gas
+	if gas == 0 {
+		msg := ethereum.CallMsg{
+			From:              from,
+			To:                spec.To,
+			Value:             value,
+			Data:              spec.Data,
+			AccessList:        spec.AccessList,
+			BlobHashes:        blobHashes,
+			AuthorizationList: spec.AuthList,
+		}
+		if mode == Legacy || mode == AccessList {
+			msg.GasPrice = fees.gasPrice
+		} else {
+			msg.GasTipCap, msg.GasFeeCap = fees.tipCap, fees.feeCap
+			msg.BlobGasFeeCap = fees.blobFeeCap
+		}
+		estimated, err := b.backend.EstimateGas(ctx, msg)
+		if err != nil {
+			return nil, nil, errs.Wrap(op, err)
+		}
+		gas = SafetyGas(estimated)
+	}
+	// blob / set-code 交易的数值字段是 uint256，超出范围的值在取 nonce 之前报错
+	var chainID, tipCap, feeCap, value256, blobFeeCap *uint256.Int
+	if mode == Blob || mode == SetCode {
+		for _, f := range []struct {
+			name string
+			dst  **uint256.Int
+			v    *big.Int
+		}{
+			{"chain ID", &chainID, b.guard.ChainID()},
+			{"max priority fee", &tipCap, fees.tipCap},
+			{"max fee", &feeCap, fees.feeCap},
+			{"value", &value256, value},
+			{"max blob fee", &blobFeeCap, fees.blobFeeCap},
+		} {
+			if *f.dst, err = u256(f.v); err != nil {
+				return nil, nil, errs.Invalid(op, f.name+" "+err.Error())
+			}
+		}
+	}
+	// 最后才取 nonce：使用 nonce 管理器时，前面的步骤失败不会占用 nonce
+	nonce, err := b.nonce(ctx, from, spec.Nonce)
+	if err != nil {
+		return nil, nil, errs.Wrap(op, err)
+	}
+
+	var inner types.TxData
+	switch mode {
+	case Legacy:
+		inner = &types.LegacyTx{Nonce: nonce, GasPrice: fees.gasPrice, Gas: gas, To: spec.To, Value: value, Data: spec.Data}
+	case AccessList:
+		inner = &types.AccessListTx{ChainID: b.guard.ChainID(), Nonce: nonce, GasPrice: fees.gasPrice, Gas: gas,
+			To: spec.To, Value: value, Data: spec.Data, AccessList: spec.AccessList}
+	case DynamicFee:
+		inner = &types.DynamicFeeTx{ChainID: b.guard.ChainID(), Nonce: nonce, GasTipCap: fees.tipCap, GasFeeCap: fees.feeCap,
+			Gas: gas, To: spec.To, Value: value, Data: spec.Data, AccessList: spec.AccessList}
+	case Blob:
+		inner = &types.BlobTx{ChainID: chainID, Nonce: nonce, GasTipCap: tipCap, GasFeeCap: feeCap,
+			Gas: gas, To: *spec.To, Value: value256, Data: spec.Data, AccessList: spec.AccessList,
+			BlobFeeCap: blobFeeCap, BlobHashes: blobHashes, Sidecar: spec.Sidecar}
+	case SetCode:
+		inner = &types.SetCodeTx{ChainID: chainID, Nonce: nonce, GasTipCap: tipCap, GasFeeCap: feeCap,
+			Gas: gas, To: *spec.To, Value: value256, Data: spec.Data, AccessList: spec.AccessList, AuthList: spec.AuthList}
+	}
+	return types.NewTx(inner), b.Signer(head), nil
+}
+
+// Sign 构造交易并经 Guard 用 s 签名。签名或 Guard 检查失败时，归还由 backend 分配的 nonce
+// （见 nonce.Releaser）。
+func (b *Builder) Sign(ctx context.Context, s signer.Signer, spec Spec) (*types.Transaction, error) {
+	tx, txSigner, err := b.Build(ctx, s.Address(), spec)
+	if err != nil {
+		return nil, err
+	}
+	signedTx, err := b.guard.SignTxWith(tx, s, txSigner)
+	if err != nil {
+		if spec.Nonce == nil {
+			err = errors.Join(err, nonce.ReleaseUnsent(ctx, b.backend, s.Address(), tx.Nonce()))
+		}
+		return nil, errs.Wrap("txbuilder.Sign", err)
+	}
+	return signedTx, nil
+}
+
+// Send 构造、签名并发送交易，返回已发送的交易。
+func (b *Builder) Send(ctx context.Context, s signer.Signer, spec Spec) (*types.Transaction, error) {
+	signedTx, err := b.Sign(ctx, s, spec)
+	if err != nil {
+		return nil, err
+	}
+	if err := b.backend.SendTransaction(ctx, signedTx); err != nil {
+		return nil, errs.Wrap("txbuilder.Send", err)
+	}
+	return signedTx, nil
+}
+
+// SafetyGas 在估算的 gas 基础上增加 20% 冗余，避免链上状态变化导致 gas 不足。
+func SafetyGas(estimated uint64) uint64 {
+	return estimated * 120 / 100
+}
+
+// resolveMode 确定交易类型，并检查 head 所处的分叉是否已支持该类型。
+func (b *Builder) resolveMode(spec Spec, head *types.Header) (Mode, error) {
+	mode := spec.Mode
+	if mode == Auto {
+		switch {
+		case spec.Sidecar != nil:
+			mode = Blob
+		case len(spec.AuthList) > 0:
+			mode = SetCode
+		case head.BaseFee != nil:
+			mode = DynamicFee
+		case len(spec.AccessList) > 0:
+			mode = AccessList
+		default:
+			mode = Legacy
+		}
+	}
+	var supported bool
+	switch mode {
+	case Legacy:
+		supported = true
+	case AccessList:
+		supported = b.config.IsBerlin(head.Number)
+	case DynamicFee:
+		supported = b.config.IsLondon(head.Number) && head.BaseFee != nil
+	case Blob:
+		supported = b.config.IsCancun(head.Number, head.Time) && head.ExcessBlobGas != nil
+	case SetCode:
+		supported = b.config.IsPrague(head.Number, head.Time)
+	default:
+		return mode, fmt.Errorf("%w: unknown transaction type %v", errs.ErrInvalidArgument, mode)
+	}
+	if !supported {
+		return mode, fmt.Errorf("%w: %v transactions are not supported at block %v", errs.ErrInvalidArgument, mode, head.Number)
+	}
+	if mode == Legacy && len(spec.AccessList) > 0 {
+		return mode, fmt.Errorf("%w: legacy transactions cannot carry an access list", errs.ErrInvalidArgument)
+	}
+	return mode, nil
+}
+
+func (b *Builder) nonce(ctx context.Context, from common.Address, nonce *uint64) (uint64, error) {
+	if nonce != nil {
+		return *nonce, nil
+	}
+	return b.backend.PendingNonceAt(ctx, from)
+}
+
+// feeParams 是补全后的费用参数。
+type feeParams struct {
+	gasPrice   *big.Int
+	tipCap     *big.Int
+	feeCap     *big.Int
+	blobFeeCap *big.Int
+}
+
+// fees 补全 Spec 中未填写的费用参数。
+func (b *Builder) fees(ctx context.Context, mode Mode, spec Spec, head *types.Header) (*feeParams, error) {
+	f := &feeParams{gasPrice: spec.GasPrice, tipCap: spec.GasTipCap, feeCap: spec.GasFeeCap, blobFeeCap: spec.BlobFeeCap}
+	if mode == Legacy || mode == AccessList {
+		if f.gasPrice == nil {
+			gasPrice, err := b.backend.SuggestGasPrice(ctx)
+			if err != nil {
+				return nil, err
+			}
+			f.gasPrice = gasPrice
+		}
+		return f, nil
+	}
+
+	if f.tipCap == nil {
+		tip, err := b.backend.SuggestGasTipCap(ctx)
+		if err != nil {
+			return nil, err
+		}
+		f.tipCap = tip
+	}
+	if f.feeCap == nil {
+		// 与 abigen 绑定的默认规则相同：留出 base fee 连续上涨的空间
+		f.feeCap = new(big.Int).Add(new(big.Int).Mul(head.BaseFee, big.NewInt(2)), f.tipCap)
+	}
+	if f.feeCap.Cmp(f.tipCap) < 0 {
+		return nil, fmt.Errorf("%w: max fee per gas %v is lower than the tip %v", errs.ErrInvalidArgument, f.feeCap, f.tipCap)
+	}
+	if mode == Blob && f.blobFeeCap == nil {
+		f.blobFeeCap = new(big.Int).Mul(eip4844.CalcBlobFee(b.config, head), big.NewInt(2))
+	}
+	return f, nil
+}
+
+// u256 把 *big.Int 转换为 blob / set-code 交易使用的 *uint256.Int，nil 视为 0。
+func u256(v *big.Int) (*uint256.Int, error) {
+	if v == nil {
+		return new(uint256.Int), nil
+	}
+	if v.Sign() < 0 {
+		return nil, fmt.Errorf("%v is negative", v)
+	}
+	u, overflow := uint256.FromBig(v)
+	if overflow {
+		return nil, fmt.Errorf("%v exceeds 256 bits", v)
+	}
+	return u, nil
+}
@@ -0,0 +1,180 @@
+package txbuilder
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ydh2333/dapp_stu/pkg/errs"
+	"github.com/ydh2333/dapp_stu/pkg/guard"
+	"github.com/ydh2333/dapp_stu/pkg/nonce"
+	"github.com/ydh2333/dapp_stu/pkg/signer"
+)
+
+var to = common.HexToAddress("0x1234567890123456789012345678901234567890")
+
+// failingSigner 的 SignTx 总是失败。
+type failingSigner struct{ signer.Signer }
+
+func (failingSigner) SignTx(*types.Transaction, types.Signer) (*types.Transaction, error) {
+	return nil, errors.New("signer unavailable")
+}
+
+// newBuilder 在模拟链上创建经 nonce 管理器分配 nonce 的 Builder，key 对应的账户预置了余额。
+func newBuilder(t *testing.T) (*simulated.Backend, *nonce.Backend, *Builder, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	balance := new(big.Int).Mul(big.NewInt(100), big.NewInt(1e18))
+	sim := simulated.NewBackend(types.GenesisAlloc{crypto.PubkeyToAddress(key.PublicKey): {Balance: balance}})
+	t.Cleanup(func() { sim.Close() })
+	client := sim.Client()
+	g, err := guard.New(context.Background(), client, big.NewInt(1337), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend := nonce.New(client, "").Wrap(client)
+	return sim, backend, New(backend, g), key
+}
+
+func TestResolveMode(t *testing.T) {
+	b := &Builder{config: ChainConfig(big.NewInt(1337))}
+	london := &types.Header{Number: big.NewInt(1), BaseFee: big.NewInt(1e9), ExcessBlobGas: new(uint64)}
+	noBaseFee := &types.Header{Number: big.NewInt(1)}
+	accessList := types.AccessList{{Address: to}}
+
+	for _, tc := range []struct {
+		name string
+		spec Spec
+		head *types.Header
+		want Mode
+	}{
+		{"blob sidecar", Spec{Sidecar: &types.BlobTxSidecar{}}, london, Blob},
+		{"authorizations", Spec{AuthList: []types.SetCodeAuthorization{{}}}, london, SetCode},
+		{"base fee", Spec{AccessList: accessList}, london, DynamicFee},
+		{"access list without base fee", Spec{AccessList: accessList}, noBaseFee, AccessList},
+		{"plain without base fee", Spec{}, noBaseFee, Legacy},
+		{"explicit legacy", Spec{Mode: Legacy}, london, Legacy},
+	} {
+		got, err := b.resolveMode(tc.spec, tc.head)
+		if err != nil || got != tc.want {
+			t.Errorf("%s: got %v (%v), want %v", tc.name, got, err, tc.want)
+		}
+	}
+
+	for _, tc := range []struct {
+		name string
+		spec Spec
+		head *types.Header
+	}{
+		{"legacy with access list", Spec{Mode: Legacy, AccessList: accessList}, london},
+		{"dynamic fee without base fee", Spec{Mode: DynamicFee}, noBaseFee},
+		{"blob without excess blob gas", Spec{Mode: Blob}, &types.Header{Number: big.NewInt(1), BaseFee: big.NewInt(1e9)}},
+		{"unknown mode", Spec{Mode: Mode(99)}, london},
+	} {
+		if _, err := b.resolveMode(tc.spec, tc.head); !errors.Is(err, errs.ErrInvalidArgument) {
+			t.Errorf("%s: got %v, want ErrInvalidArgument", tc.name, err)
+		}
+	}
+}
+
+func TestResolveModeForkGating(t *testing.T) {
+	b := &Builder{config: params.MainnetChainConfig}
+	preBerlin := &types.Header{Number: big.NewInt(12_000_000)}
+	berlin := &types.Header{Number: big.NewInt(12_500_000)}
+	london := &types.Header{Number: big.NewInt(13_000_000), BaseFee: big.NewInt(1e9), Time: 1_630_000_000}
+
+	for _, tc := range []struct {
+		mode      Mode
+		head      *types.Header
+		supported bool
+	}{
+		{Legacy, preBerlin, true},
+		{AccessList, preBerlin, false},
+		{AccessList, berlin, true},
+		{DynamicFee, berlin, false},
+		{DynamicFee, london, true},
+		{Blob, london, false},
+		{SetCode, london, false},
+	} {
+		_, err := b.resolveMode(Spec{Mode: tc.mode}, tc.head)
+		if tc.supported && err != nil {
+			t.Errorf("%v at block %v: unexpected error %v", tc.mode, tc.head.Number, err)
+		}
+		if !tc.supported && !errors.Is(err, errs.ErrInvalidArgument) {
+			t.Errorf("%v at block %v: got %v, want ErrInvalidArgument", tc.mode, tc.head.Number, err)
+		}
+	}
+
+	// Auto 在 London 之前不会选动态手续费
+	if mode, err := b.resolveMode(Spec{}, berlin); err != nil || mode != Legacy {
+		t.Errorf("auto before London: got %v (%v), want legacy", mode, err)
+	}
+}
+
+func TestSendAuto(t *testing.T) {
+	sim, _, b, key := newBuilder(t)
+	ctx := context.Background()
+
+	tx, err := b.Send(ctx, signer.FromKey(key), Spec{To: &to, Value: big.NewInt(1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.Type() != types.DynamicFeeTxType || tx.Nonce() != 0 {
+		t.Fatalf("got type %d nonce %d, want a dynamic-fee transaction with nonce 0", tx.Type(), tx.Nonce())
+	}
+	sim.Commit()
+	receipt, err := sim.Client().TransactionReceipt(ctx, tx.Hash())
+	if err != nil || receipt.Status != types.ReceiptStatusSuccessful {
+		t.Fatalf("receipt %v (%v)", receipt, err)
+	}
+}
+
+// 数值超出 uint256 时 Build 报错而不 panic，且不占用 nonce。
+func TestBuildUint256Overflow(t *testing.T) {
+	_, backend, b, key := newBuilder(t)
+	ctx := context.Background()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	huge := new(big.Int).Lsh(big.NewInt(1), 256)
+
+	for _, spec := range []Spec{
+		{To: &to, Value: huge},
+		{To: &to, GasTipCap: big.NewInt(1e9), GasFeeCap: huge},
+	} {
+		spec.Mode, spec.Gas = SetCode, 100_000
+		spec.AuthList = []types.SetCodeAuthorization{{}}
+		if _, _, err := b.Build(ctx, from, spec); !errors.Is(err, errs.ErrInvalidArgument) {
+			t.Errorf("value %v, max fee %v: got %v, want ErrInvalidArgument", spec.Value, spec.GasFeeCap, err)
+		}
+	}
+	if n, err := backend.PendingNonceAt(ctx, from); err != nil || n != 0 {
+		t.Fatalf("next nonce %d (%v), want 0", n, err)
+	}
+}
+
+// 签名失败时归还 nonce，下一笔交易复用它。
+func TestSignFailureReleasesNonce(t *testing.T) {
+	_, _, b, key := newBuilder(t)
+	ctx := context.Background()
+	s := signer.FromKey(key)
+
+	if _, err := b.Sign(ctx, failingSigner{s}, Spec{To: &to}); err == nil {
+		t.Fatal("expected the signer error")
+	}
+	tx, err := b.Send(ctx, s, Spec{To: &to})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.Nonce() != 0 {
+		t.Fatalf("got nonce %d, want the released nonce 0", tx.Nonce())
+	}
+}
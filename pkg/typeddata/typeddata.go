@@ -0,0 +1,136 @@
+// Package typeddata 实现 EIP-712 结构化数据的哈希、签名与验签。
+// 输入是钱包 eth_signTypedData_v4 使用的 JSON 文档（types / primaryType / domain / message），
+// 支持嵌套结构体和数组；签名复用交易使用的 signer.Signer 抽象。
+package typeddata
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/ydh2333/dapp_stu/pkg/errs"
+	"github.com/ydh2333/dapp_stu/pkg/msgsign"
+	"github.com/ydh2333/dapp_stu/pkg/signer"
+)
+
+// domainType 是 EIP-712 规定的域类型名。
+const domainType = "EIP712Domain"
+
+// TypedData 是一份已解析的 EIP-712 文档。
+type TypedData = apitypes.TypedData
+
+// Parse 解析 JSON 格式的 EIP-712 文档，并检查 primaryType 与 EIP712Domain 均已定义。
+func Parse(data []byte) (*TypedData, error) {
+	const op = "typeddata.Parse"
+
+	var td TypedData
+	if err := json.Unmarshal(data, &td); err != nil {
+		return nil, errs.Wrap(op, err)
+	}
+	if _, ok := td.Types[domainType]; !ok {
+		return nil, errs.Invalid(op, "types must define "+domainType)
+	}
+	if td.PrimaryType == "" {
+		return nil, errs.Invalid(op, "primaryType is empty")
+	}
+	if _, ok := td.Types[td.PrimaryType]; !ok {
+		return nil, errs.Invalid(op, fmt.Sprintf("primaryType %q is not defined in types", td.PrimaryType))
+	}
+	return &td, nil
+}
+
+// ReadFile 读取并解析 EIP-712 JSON 文件。
+func ReadFile(path string) (*TypedData, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errs.Wrap("typeddata.ReadFile", err)
+	}
+	return Parse(data)
+}
+
+// ChainID 返回域中声明的链 ID，未声明时返回 nil。
+func ChainID(td *TypedData) *big.Int {
+	if td.Domain.ChainId == nil {
+		return nil
+	}
+	return (*big.Int)(td.Domain.ChainId)
+}
+
+// Hashes 是 EIP-712 签名哈希的各个组成部分。
+type Hashes struct {
+	DomainSeparator common.Hash // hashStruct(EIP712Domain)
+	MessageHash     common.Hash // hashStruct(primaryType, message)
+	Digest          common.Hash // keccak256("\x19\x01" || DomainSeparator || MessageHash)
+}
+
+// Hash 计算域分隔符、消息哈希和最终的签名摘要。
+func Hash(td *TypedData) (*Hashes, error) {
+	const op = "typeddata.Hash"
+
+	domain, err := td.HashStruct(domainType, td.Domain.Map())
+	if err != nil {
+		return nil, errs.Wrap(op, fmt.Errorf("domain: %w", err))
+	}
+	message, err := td.HashStruct(td.PrimaryType, td.Message)
+	if err != nil {
+		return nil, errs.Wrap(op, fmt.Errorf("message: %w", err))
+	}
+	h := &Hashes{DomainSeparator: common.BytesToHash(domain), MessageHash: common.BytesToHash(message)}
+	h.Digest = crypto.Keccak256Hash([]byte{0x19, 0x01}, domain, message)
+	return h, nil
+}
+
+// Result 是签名结果。
+type Result struct {
+	Hashes
+	Signature []byte         // 65 字节 [R || S || V]，V 为 27 或 28
+	Signer    common.Address // 从签名恢复出的地址
+}
+
+// Sign 用 s 对 EIP-712 文档签名，并从签名恢复一次签名者以确认结果。
+func Sign(s signer.Signer, td *TypedData) (*Result, error) {
+	const op = "typeddata.Sign"
+
+	h, err := Hash(td)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := msgsign.SignHash(s, h.Digest.Bytes())
+	if err != nil {
+		return nil, errs.Wrap(op, err)
+	}
+	recovered, err := msgsign.RecoverAddress(h.Digest.Bytes(), sig)
+	if err != nil {
+		return nil, errs.Wrap(op, err)
+	}
+	if recovered != s.Address() {
+		return nil, errs.Wrap(op, fmt.Errorf("recovered %s, expected %s", recovered.Hex(), s.Address().Hex()))
+	}
+	return &Result{Hashes: *h, Signature: sig, Signer: recovered}, nil
+}
+
+// Recover 从 EIP-712 文档和签名恢复签名者。
+func Recover(td *TypedData, sig []byte) (*Result, error) {
+	h, err := Hash(td)
+	if err != nil {
+		return nil, err
+	}
+	recovered, err := msgsign.RecoverAddress(h.Digest.Bytes(), sig)
+	if err != nil {
+		return nil, errs.Wrap("typeddata.Recover", err)
+	}
+	return &Result{Hashes: *h, Signature: sig, Signer: recovered}, nil
+}
+
+// Verify 校验 sig 是否为 address 对 EIP-712 文档的签名。
+func Verify(td *TypedData, sig []byte, address common.Address) (bool, error) {
+	h, err := Hash(td)
+	if err != nil {
+		return false, err
+	}
+	return msgsign.VerifyHash(h.Digest.Bytes(), sig, address)
+}
@@ -0,0 +1,248 @@
+// Package vanity 在 04_create_key 的「私钥 → 公钥 → Keccak256 → 地址」流程之上搜索靓号地址：
+// 多个 worker 并行搜索满足前缀 / 后缀 / 正则的地址，可选按 EIP-55 校验和大小写匹配。
+//
+// 每个 worker 从一个随机私钥 k 出发，之后每次把私钥加 1、公钥加上生成元 G，
+// 用一次点加代替一次标量乘法，速度比每次调用 crypto.GenerateKey 快得多。
+package vanity
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ydh2333/dapp_stu/pkg/errs"
+	"github.com/ydh2333/dapp_stu/pkg/keys"
+)
+
+// Pattern 描述要搜索的地址特征，地址均指去掉 0x 后的 40 个十六进制字符。
+type Pattern struct {
+	Prefix string         // 地址前缀
+	Suffix string         // 地址后缀
+	Regex  *regexp.Regexp // 额外的正则条件
+	// CaseSensitive 为 true 时按 EIP-55 校验和地址的大小写匹配（Regex 也作用于校验和地址），
+	// 否则一律按小写匹配。
+	CaseSensitive bool
+}
+
+// Validate 检查前缀、后缀是否为合法的十六进制字符。
+func (p Pattern) Validate() error {
+	const op = "vanity.Pattern"
+
+	if p.Prefix == "" && p.Suffix == "" && p.Regex == nil {
+		return errs.Invalid(op, "empty pattern")
+	}
+	if len(p.Prefix)+len(p.Suffix) > common.AddressLength*2 {
+		return errs.Invalid(op, "prefix and suffix are longer than an address")
+	}
+	for _, s := range []string{p.Prefix, p.Suffix} {
+		if strings.Trim(s, "0123456789abcdefABCDEF") != "" {
+			return errs.Invalid(op, fmt.Sprintf("%q is not hexadecimal", s))
+		}
+	}
+	return nil
+}
+
+// Difficulty 返回每个随机地址满足前缀和后缀的期望尝试次数：每个字符 16 种取值，
+// 区分大小写时每个字母再乘 2。正则条件无法估算，不计入。
+func (p Pattern) Difficulty() float64 {
+	d := 1.0
+	for _, c := range p.Prefix + p.Suffix {
+		d *= 16
+		if p.CaseSensitive && (c < '0' || c > '9') {
+			d *= 2
+		}
+	}
+	return d
+}
+
+// match 判断 40 个小写十六进制字符的地址 lower 是否满足条件，需要时才计算校验和地址。
+func (p Pattern) match(address common.Address, lower string) bool {
+	if !strings.HasPrefix(lower, strings.ToLower(p.Prefix)) || !strings.HasSuffix(lower, strings.ToLower(p.Suffix)) {
+		return false
+	}
+	subject := lower
+	if p.CaseSensitive {
+		subject = address.Hex()[2:]
+		if !strings.HasPrefix(subject, p.Prefix) || !strings.HasSuffix(subject, p.Suffix) {
+			return false
+		}
+	}
+	return p.Regex == nil || p.Regex.MatchString(subject)
+}
+
+// Stats 是搜索进度。
+type Stats struct {
+	Attempts   uint64        // 已尝试的地址数
+	Elapsed    time.Duration // 已用时间
+	Rate       float64       // 每秒尝试次数（keys/sec）
+	Difficulty float64       // 期望尝试次数
+}
+
+// Probability 返回到目前为止至少找到一个匹配地址的概率。
+func (s Stats) Probability() float64 {
+	return 1 - math.Exp(-float64(s.Attempts)/s.Difficulty)
+}
+
+// ETA50 返回按当前速度达到 50% 成功概率还需要的时间，速度未知时返回 0。
+func (s Stats) ETA50() time.Duration {
+	if s.Rate == 0 {
+		return 0
+	}
+	remaining := math.Ln2*s.Difficulty - float64(s.Attempts)
+	if remaining <= 0 {
+		return 0
+	}
+	return time.Duration(remaining / s.Rate * float64(time.Second))
+}
+
+// Options 是搜索选项。
+type Options struct {
+	Workers          int           // 并行 worker 数，默认 runtime.NumCPU()
+	ProgressInterval time.Duration // 进度回调间隔，默认 1 秒
+	Progress         func(Stats)   // 进度回调，可以为 nil
+}
+
+// Result 是找到的靓号地址。
+type Result struct {
+	Key     *ecdsa.PrivateKey
+	Address common.Address
+	Stats   Stats
+}
+
+// batchSize 是 worker 每累计多少次尝试更新一次全局计数。
+const batchSize = 256
+
+// Search 并行搜索满足 p 的地址，直到找到或 ctx 被取消。
+func Search(ctx context.Context, p Pattern, opts Options) (*Result, error) {
+	const op = "vanity.Search"
+
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = runtime.NumCPU()
+	}
+	if opts.ProgressInterval <= 0 {
+		opts.ProgressInterval = time.Second
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		attempts atomic.Uint64
+		start    = time.Now()
+		found    = make(chan *ecdsa.PrivateKey, opts.Workers)
+		errc     = make(chan error, opts.Workers)
+		wg       sync.WaitGroup
+	)
+	for i := 0; i < opts.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			key, err := worker(ctx, p, &attempts)
+			switch {
+			case err != nil:
+				errc <- err
+			case key != nil:
+				found <- key
+			}
+		}()
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	stats := func() Stats {
+		s := Stats{Attempts: attempts.Load(), Elapsed: time.Since(start), Difficulty: p.Difficulty()}
+		if secs := s.Elapsed.Seconds(); secs > 0 {
+			s.Rate = float64(s.Attempts) / secs
+		}
+		return s
+	}
+	ticker := time.NewTicker(opts.ProgressInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case key := <-found:
+			cancel()
+			<-done
+			return &Result{Key: key, Address: keys.Address(&key.PublicKey), Stats: stats()}, nil
+		case err := <-errc:
+			cancel()
+			<-done
+			return nil, errs.Wrap(op, err)
+		case <-done:
+			// 全部 worker 已退出：找到结果或出错的 worker 写完通道后才会退出，
+			// select 可能先选中 done，因此先取走已经写入的结果
+			select {
+			case key := <-found:
+				return &Result{Key: key, Address: keys.Address(&key.PublicKey), Stats: stats()}, nil
+			case err := <-errc:
+				return nil, errs.Wrap(op, err)
+			default:
+			}
+			if err := ctx.Err(); err != nil {
+				return nil, errs.Wrap(op, err)
+			}
+			return nil, errs.Wrap(op, errors.New("all workers stopped without a result"))
+		case <-ticker.C:
+			if opts.Progress != nil {
+				opts.Progress(stats())
+			}
+		}
+	}
+}
+
+// worker 从随机私钥开始逐个递增搜索，找到时返回对应私钥，ctx 取消时返回 nil。
+func worker(ctx context.Context, p Pattern, attempts *atomic.Uint64) (*ecdsa.PrivateKey, error) {
+	curve := crypto.S256()
+	params := curve.Params()
+
+	base, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	pub := &ecdsa.PublicKey{Curve: curve, X: base.X, Y: base.Y}
+	for offset := int64(0); ; offset++ {
+		if offset%batchSize == 0 {
+			if offset > 0 {
+				attempts.Add(batchSize)
+			}
+			if ctx.Err() != nil {
+				return nil, nil
+			}
+		}
+		address := keys.KeccakAddress(pub)
+		if p.match(address, hex.EncodeToString(address[:])) {
+			// 私钥 = 起始私钥 + 偏移量 (mod n)，重新推导一次地址确认无误
+			d := new(big.Int).Add(base.D, big.NewInt(offset))
+			d.Mod(d, params.N)
+			key, err := crypto.ToECDSA(common.LeftPadBytes(d.Bytes(), 32))
+			if err != nil {
+				return nil, err
+			}
+			if keys.Address(&key.PublicKey) != address {
+				return nil, errors.New("derived key does not match the found address")
+			}
+			attempts.Add(uint64(offset % batchSize))
+			return key, nil
+		}
+		x, y := curve.Add(pub.X, pub.Y, params.Gx, params.Gy)
+		pub = &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+	}
+}
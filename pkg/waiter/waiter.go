@@ -0,0 +1,331 @@
+// Package waiter 等待交易上链：支持 context 取消与超时、指数退避轮询、确认数、
+// 识别被丢弃或被同 nonce 交易替换的交易，并可用新区块订阅代替轮询。
+// 结果以 Status 返回，包含交易当前所处的状态、回执和确认数。
+package waiter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ydh2333/dapp_stu/pkg/errs"
+)
+
+var (
+	// ErrDropped 表示交易长时间不在交易池中，也没有被打包（包括 nonce 已被找不到的其他交易占用）。
+	ErrDropped = errors.New("transaction dropped")
+	// ErrReplaced 表示同一 nonce 已被另一笔交易占用（加速 / 取消或其他进程发送）。
+	ErrReplaced = errors.New("transaction replaced")
+)
+
+// State 是交易所处的状态。
+type State int
+
+const (
+	Unknown   State = iota // 节点尚未见到该交易
+	Pending                // 在交易池中等待打包
+	Mined                  // 已打包，确认数不足
+	Confirmed              // 已达到要求的确认数且执行成功
+	Failed                 // 已达到要求的确认数但执行失败（Status 为 0）
+	Dropped                // 被交易池丢弃，或 nonce 被搜索范围之外的未知交易占用
+	Replaced               // 被同 nonce 的另一笔交易替换
+)
+
+var stateNames = [...]string{"unknown", "pending", "mined", "confirmed", "failed", "dropped", "replaced"}
+
+func (s State) String() string {
+	if int(s) < len(stateNames) {
+		return stateNames[s]
+	}
+	return fmt.Sprintf("State(%d)", int(s))
+}
+
+// Status 是一次等待的结果。
+type Status struct {
+	Hash          common.Hash
+	State         State
+	Receipt       *types.Receipt // Mined / Confirmed / Failed 时有值
+	Confirmations uint64         // 包含交易的区块本身算 1 个确认
+	Head          uint64         // 最近一次查询时的最新区块号
+	From          common.Address // 发送方，节点见到过该交易时有值
+	Nonce         uint64
+	ReplacedBy    common.Hash // Replaced 时在最近区块中找到的替换交易
+	Elapsed       time.Duration
+	Polls         int // 查询轮数
+}
+
+func (s *Status) String() string {
+	switch s.State {
+	case Mined, Confirmed, Failed:
+		return fmt.Sprintf("%s %s in block %d (%d confirmations, gas used %d)", s.Hash.Hex(), s.State, s.Receipt.BlockNumber, s.Confirmations, s.Receipt.GasUsed)
+	case Replaced:
+		return fmt.Sprintf("%s replaced by %s (nonce %d)", s.Hash.Hex(), s.ReplacedBy.Hex(), s.Nonce)
+	default:
+		return fmt.Sprintf("%s %s after %s", s.Hash.Hex(), s.State, s.Elapsed.Round(time.Second))
+	}
+}
+
+// Options 是等待选项，零值即可使用。
+type Options struct {
+	Confirmations uint64        // 要求的确认数，默认 1
+	Timeout       time.Duration // 超时时间，0 表示只受 ctx 控制
+	MinInterval   time.Duration // 首次轮询间隔，默认 1 秒，之后每轮翻倍
+	MaxInterval   time.Duration // 轮询间隔上限，默认 15 秒
+	DropAfter     time.Duration // 节点连续多久查不到交易视为被丢弃，默认 2 分钟
+	Subscribe     bool          // 用 eth_subscribe newHeads 触发查询（需要 WebSocket），订阅失败时退回轮询
+	OnUpdate      func(*Status) // 状态变化时回调，可以为 nil
+}
+
+// Backend 是等待交易所需的节点能力，*ethclient.Client 和模拟链客户端都满足该接口。
+type Backend interface {
+	ethereum.ChainReader
+	ethereum.TransactionReader
+	NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error)
+}
+
+// replacementSearchDepth 是查找替换交易时向前扫描的最大区块数。
+const replacementSearchDepth = 64
+
+// Wait 等待 hash 对应的交易达到要求的确认数。返回的 Status 总是有值：
+//   - Confirmed 时 error 为 nil；Failed 时 error 为 errs.ErrTxFailed；
+//   - Dropped / Replaced 时 error 为 ErrDropped / ErrReplaced；
+//   - 超时或 ctx 取消时 error 包装 ctx.Err()，Status 为最后一次观察到的状态。
+//
+// 只有在节点上见到过交易后才能得知发送方和 nonce，从而识别替换；
+// 持有已签名交易时应使用 WaitTx。
+func Wait(ctx context.Context, backend Backend, hash common.Hash, opts Options) (*Status, error) {
+	return wait(ctx, backend, &waiter{status: &Status{Hash: hash}}, opts)
+}
+
+// WaitTx 与 Wait 相同，但从已签名的交易直接得到发送方和 nonce，
+// 即使交易还没被节点见到就被替换也能识别。
+func WaitTx(ctx context.Context, backend Backend, tx *types.Transaction, opts Options) (*Status, error) {
+	w := &waiter{status: &Status{Hash: tx.Hash(), Nonce: tx.Nonce()}}
+	if from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx); err == nil {
+		w.status.From, w.knowsFrom = from, true
+	}
+	return wait(ctx, backend, w, opts)
+}
+
+func wait(ctx context.Context, backend Backend, w *waiter, opts Options) (*Status, error) {
+	const op = "waiter.Wait"
+
+	if opts.Confirmations == 0 {
+		opts.Confirmations = 1
+	}
+	if opts.MinInterval <= 0 {
+		opts.MinInterval = time.Second
+	}
+	if opts.MaxInterval < opts.MinInterval {
+		opts.MaxInterval = max(15*time.Second, opts.MinInterval)
+	}
+	if opts.DropAfter <= 0 {
+		opts.DropAfter = 2 * time.Minute
+	}
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	w.backend, w.opts = backend, opts
+	w.start, w.lastSeen = time.Now(), time.Now()
+
+	var heads chan *types.Header
+	if opts.Subscribe {
+		heads = make(chan *types.Header, 16)
+		sub, err := backend.SubscribeNewHead(ctx, heads)
+		if err != nil {
+			heads = nil // 不支持订阅（如 HTTP 端点），退回轮询
+		} else {
+			defer sub.Unsubscribe()
+		}
+	}
+
+	interval := opts.MinInterval
+	for {
+		done, err := w.poll(ctx)
+		if done || err != nil {
+			w.status.Elapsed = time.Since(w.start)
+			return w.status, errs.Wrap(op, err)
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			w.status.Elapsed = time.Since(w.start)
+			return w.status, errs.Wrap(op, ctx.Err())
+		case <-heads:
+			// 新区块到达后立即查询，间隔重置
+			interval = opts.MinInterval
+		case <-timer.C:
+			interval = min(interval*2, opts.MaxInterval)
+		}
+		timer.Stop()
+	}
+}
+
+// waiter 保存一次等待过程中的状态。
+type waiter struct {
+	backend   Backend
+	opts      Options
+	status    *Status
+	start     time.Time
+	lastSeen  time.Time // 最近一次在节点上查到该交易的时间
+	knowsFrom bool      // 是否已知发送方和 nonce
+
+	searched   bool   // 是否已搜索过替换交易
+	searchedTo uint64 // 已搜索过的最高区块号
+}
+
+// poll 查询一轮。done 为 true 表示等待结束（成功或终态）。
+func (w *waiter) poll(ctx context.Context) (done bool, err error) {
+	s := w.status
+	s.Polls++
+	prev := *s
+
+	head, err := w.backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	s.Head = head.Number.Uint64()
+
+	receipt, err := w.backend.TransactionReceipt(ctx, s.Hash)
+	switch {
+	case err == nil:
+		w.lastSeen = time.Now()
+		// 回执所在区块可能已被重组掉，确认该高度的区块哈希仍一致
+		header, err := w.backend.HeaderByNumber(ctx, receipt.BlockNumber)
+		if err != nil && !errors.Is(err, ethereum.NotFound) {
+			return false, err
+		}
+		if header == nil || header.Hash() != receipt.BlockHash {
+			s.State, s.Receipt, s.Confirmations = Pending, nil, 0
+			break
+		}
+		s.Receipt = receipt
+		// 最新区块号在查询回执之前取得，期间节点可能已经出了包含交易的新区块
+		s.Head = max(s.Head, receipt.BlockNumber.Uint64())
+		s.Confirmations = s.Head - receipt.BlockNumber.Uint64() + 1
+		if s.Confirmations < w.opts.Confirmations {
+			s.State = Mined
+			break
+		}
+		if receipt.Status != types.ReceiptStatusSuccessful {
+			s.State = Failed
+			w.notify(prev)
+			return true, errs.ErrTxFailed
+		}
+		s.State = Confirmed
+		w.notify(prev)
+		return true, nil
+	case !notFound(err):
+		return false, err
+	default:
+		s.Receipt, s.Confirmations = nil, 0
+		if done, err := w.checkPending(ctx); done || err != nil {
+			w.notify(prev)
+			return done, err
+		}
+	}
+	w.notify(prev)
+	return false, nil
+}
+
+// checkPending 在查不到回执时确认交易是否仍在交易池中，或已被替换、丢弃。
+func (w *waiter) checkPending(ctx context.Context) (done bool, err error) {
+	s := w.status
+	tx, _, err := w.backend.TransactionByHash(ctx, s.Hash)
+	switch {
+	case err == nil:
+		w.lastSeen = time.Now()
+		s.State = Pending
+		if !w.knowsFrom {
+			if from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx); err == nil {
+				s.From, s.Nonce, w.knowsFrom = from, tx.Nonce(), true
+			}
+		}
+	case !notFound(err):
+		return false, err
+	}
+	// 同一 nonce 已被上链的其他交易使用：原交易不可能再被打包
+	if w.knowsFrom {
+		latest, err := w.backend.NonceAt(ctx, s.From, nil)
+		if err != nil {
+			return false, err
+		}
+		if latest > s.Nonce {
+			// 只有在区块中找到另一笔交易时才判定为被替换。找到的是本交易时（已打包但回执尚未索引）继续等待回执；
+			// 占用 nonce 的交易不在搜索范围内时，超过 DropAfter 仍没有回执就按丢弃处理
+			by, found, err := w.findReplacement(ctx)
+			if err != nil {
+				return false, err
+			}
+			if found && by != s.Hash {
+				s.State, s.ReplacedBy = Replaced, by
+				return true, ErrReplaced
+			}
+			if found {
+				w.lastSeen = time.Now()
+			}
+		}
+	}
+	if time.Since(w.lastSeen) > w.opts.DropAfter {
+		s.State = Dropped
+		return true, ErrDropped
+	}
+	return false, nil
+}
+
+// findReplacement 查找同一发送方、同一 nonce 的交易（可能就是本交易）：首次从最新区块向前最多搜索
+// replacementSearchDepth 个区块，之后只搜索上次搜索过的区块之后的新区块。found 为 false 表示没有找到。
+func (w *waiter) findReplacement(ctx context.Context) (hash common.Hash, found bool, err error) {
+	s := w.status
+	var low uint64
+	if s.Head >= replacementSearchDepth {
+		low = s.Head - replacementSearchDepth + 1
+	}
+	if w.searched {
+		low = max(low, w.searchedTo+1)
+	}
+	for n := s.Head + 1; n > low; n-- {
+		block, err := w.backend.BlockByNumber(ctx, new(big.Int).SetUint64(n-1))
+		if err != nil {
+			return common.Hash{}, false, err
+		}
+		for _, tx := range block.Transactions() {
+			if tx.Nonce() != s.Nonce {
+				continue
+			}
+			if from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx); err == nil && from == s.From {
+				return tx.Hash(), true, nil
+			}
+		}
+	}
+	w.searched, w.searchedTo = true, max(w.searchedTo, s.Head)
+	return common.Hash{}, false, nil
+}
+
+// notFound 判断查询结果是否表示“暂时查不到”。节点的交易索引尚未建完时
+// 会返回 "transaction indexing is in progress"，同样按查不到处理、继续等待。
+func notFound(err error) bool {
+	return errors.Is(err, ethereum.NotFound) || strings.Contains(err.Error(), "transaction indexing is in progress")
+}
+
+// notify 在状态或确认数变化时调用 OnUpdate。
+func (w *waiter) notify(prev Status) {
+	if w.opts.OnUpdate == nil {
+		return
+	}
+	if w.status.State != prev.State || w.status.Confirmations != prev.Confirmations {
+		w.status.Elapsed = time.Since(w.start)
+		w.opts.OnUpdate(w.status)
+	}
+}